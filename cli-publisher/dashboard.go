@@ -1,9 +1,10 @@
 package cli_publisher
 
 import (
-	"fmt"
 	"github.com/AaronFeledy/tyk-ops/pkg/clients/dashboard"
 	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/rest"
+	"github.com/AaronFeledy/tyk-ops/pkg/output"
 )
 
 type DashboardPublisher struct {
@@ -15,14 +16,27 @@ type DashboardPublisher struct {
 		// InsecureSkipVerify is a flag that specifies if we should validate the
 		// server's TLS certificate.
 		InsecureSkipVerify bool
+		// CAFile, CertFile and KeyFile set the CA bundle and the client certificate used to
+		// connect to the target
+		CAFile   string
+		CertFile string
+		KeyFile  string
 		// Skip creating APIs if they already exist
 		SkipExisting bool
+		// Owner restricts syncs to the objects stamped with this owner
+		Owner string
+		// Parallel is the number of objects written to the target at once
+		Parallel int
+		// HTTP sets the timeouts, retries and rate limit of requests to the target
+		HTTP rest.Options
+		// Proxy is the URL of the proxy requests to the target are sent through
+		Proxy string
 	}
 }
 
 func (p *DashboardPublisher) enforceOrgID(apiDefs *[]objects.DBApiDefinition) {
 	if p.OrgOverride != "" {
-		fmt.Println("org override detected, setting.")
+		output.Println("org override detected, setting.")
 
 		for i := range *apiDefs {
 			(*apiDefs)[i].OrgID = p.OrgOverride
@@ -32,7 +46,7 @@ func (p *DashboardPublisher) enforceOrgID(apiDefs *[]objects.DBApiDefinition) {
 
 func (p *DashboardPublisher) enforceOrgIDForPolicies(pols *[]objects.Policy) {
 	if p.OrgOverride != "" {
-		fmt.Println("org override detected, setting.")
+		output.Println("org override detected, setting.")
 
 		for i := range *pols {
 			(*pols)[i].OrgID = p.OrgOverride
@@ -41,8 +55,9 @@ func (p *DashboardPublisher) enforceOrgIDForPolicies(pols *[]objects.Policy) {
 }
 
 func (p *DashboardPublisher) CreateAPIs(apiDefs *[]objects.DBApiDefinition) error {
-	c, err := dashboard.NewDashboardClient(p.Hostname, p.Secret, p.OrgOverride)
-	c.InsecureSkipVerify = p.ClientOptions.InsecureSkipVerify
+	c, err := dashboard.NewDashboardClientProxy(p.Hostname, p.Secret, p.OrgOverride, p.tls(), p.ClientOptions.Proxy)
+	c.HTTP = p.ClientOptions.HTTP
+	c.Parallel = p.ClientOptions.Parallel
 	c.SkipExisting = p.ClientOptions.SkipExisting
 	if err != nil {
 		return err
@@ -56,8 +71,9 @@ func (p *DashboardPublisher) CreateAPIs(apiDefs *[]objects.DBApiDefinition) erro
 }
 
 func (p *DashboardPublisher) UpdateAPIs(apiDefs *[]objects.DBApiDefinition) error {
-	c, err := dashboard.NewDashboardClient(p.Hostname, p.Secret, p.OrgOverride)
-	c.InsecureSkipVerify = p.ClientOptions.InsecureSkipVerify
+	c, err := dashboard.NewDashboardClientProxy(p.Hostname, p.Secret, p.OrgOverride, p.tls(), p.ClientOptions.Proxy)
+	c.HTTP = p.ClientOptions.HTTP
+	c.Parallel = p.ClientOptions.Parallel
 	if err != nil {
 		return err
 	}
@@ -70,8 +86,10 @@ func (p *DashboardPublisher) UpdateAPIs(apiDefs *[]objects.DBApiDefinition) erro
 }
 
 func (p *DashboardPublisher) SyncAPIs(apiDefs []objects.DBApiDefinition) error {
-	c, err := dashboard.NewDashboardClient(p.Hostname, p.Secret, p.OrgOverride)
-	c.InsecureSkipVerify = p.ClientOptions.InsecureSkipVerify
+	c, err := dashboard.NewDashboardClientProxy(p.Hostname, p.Secret, p.OrgOverride, p.tls(), p.ClientOptions.Proxy)
+	c.HTTP = p.ClientOptions.HTTP
+	c.Parallel = p.ClientOptions.Parallel
+	c.Owner = p.ClientOptions.Owner
 	if err != nil {
 		return err
 	}
@@ -94,8 +112,65 @@ func (p *DashboardPublisher) SyncAPIs(apiDefs []objects.DBApiDefinition) error {
 	return c.SyncAPIs(apiDefs)
 }
 
+func (p *DashboardPublisher) FetchAPIs() ([]objects.DBApiDefinition, error) {
+	c, err := dashboard.NewDashboardClientProxy(p.Hostname, p.Secret, p.OrgOverride, p.tls(), p.ClientOptions.Proxy)
+	c.HTTP = p.ClientOptions.HTTP
+	if err != nil {
+		return nil, err
+	}
+
+	return c.FetchAPIs()
+}
+
+// FetchPolicies returns the policies currently on the dashboard. Each policy is fetched on its own,
+// as the access rights in the policy list don't always decode.
+func (p *DashboardPublisher) FetchPolicies() ([]objects.Policy, error) {
+	c, err := dashboard.NewDashboardClientProxy(p.Hostname, p.Secret, p.OrgOverride, p.tls(), p.ClientOptions.Proxy)
+	c.HTTP = p.ClientOptions.HTTP
+	if err != nil {
+		return nil, err
+	}
+
+	pols, err := c.FetchPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, pol := range pols {
+		full, err := c.FetchPolicy(pol.MID.Hex())
+		if err != nil {
+			return nil, err
+		}
+		pols[i] = *full
+	}
+
+	return pols, nil
+}
+
+// FetchCertificates returns the metadata of the certificates currently on the dashboard.
+func (p *DashboardPublisher) FetchCertificates() ([]objects.CertificateMeta, error) {
+	c, err := dashboard.NewDashboardClientProxy(p.Hostname, p.Secret, p.OrgOverride, p.tls(), p.ClientOptions.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	c.HTTP = p.ClientOptions.HTTP
+
+	return c.FetchCertificates()
+}
+
+// CreateCertificate uploads a PEM certificate to the dashboard, returning its ID.
+func (p *DashboardPublisher) CreateCertificate(cert []byte) (string, error) {
+	c, err := dashboard.NewDashboardClientProxy(p.Hostname, p.Secret, p.OrgOverride, p.tls(), p.ClientOptions.Proxy)
+	if err != nil {
+		return "", err
+	}
+	c.HTTP = p.ClientOptions.HTTP
+
+	return c.CreateCertificate(cert)
+}
+
 func (p *DashboardPublisher) Reload() error {
-	fmt.Println("Dashboard does not require explicit reload. Skipping Reload.")
+	output.Println("Dashboard does not require explicit reload. Skipping Reload.")
 	return nil
 }
 
@@ -104,8 +179,9 @@ func (p *DashboardPublisher) Name() string {
 }
 
 func (p *DashboardPublisher) CreatePolicies(pols *[]objects.Policy) error {
-	c, err := dashboard.NewDashboardClient(p.Hostname, p.Secret, p.OrgOverride)
-	c.InsecureSkipVerify = p.ClientOptions.InsecureSkipVerify
+	c, err := dashboard.NewDashboardClientProxy(p.Hostname, p.Secret, p.OrgOverride, p.tls(), p.ClientOptions.Proxy)
+	c.HTTP = p.ClientOptions.HTTP
+	c.Parallel = p.ClientOptions.Parallel
 	c.SkipExisting = p.ClientOptions.SkipExisting
 	if err != nil {
 		return err
@@ -119,8 +195,9 @@ func (p *DashboardPublisher) CreatePolicies(pols *[]objects.Policy) error {
 }
 
 func (p *DashboardPublisher) UpdatePolicies(pols *[]objects.Policy) error {
-	c, err := dashboard.NewDashboardClient(p.Hostname, p.Secret, p.OrgOverride)
-	c.InsecureSkipVerify = p.ClientOptions.InsecureSkipVerify
+	c, err := dashboard.NewDashboardClientProxy(p.Hostname, p.Secret, p.OrgOverride, p.tls(), p.ClientOptions.Proxy)
+	c.HTTP = p.ClientOptions.HTTP
+	c.Parallel = p.ClientOptions.Parallel
 	if err != nil {
 		return err
 	}
@@ -133,8 +210,10 @@ func (p *DashboardPublisher) UpdatePolicies(pols *[]objects.Policy) error {
 }
 
 func (p *DashboardPublisher) SyncPolicies(pols []objects.Policy) error {
-	c, err := dashboard.NewDashboardClient(p.Hostname, p.Secret, p.OrgOverride)
-	c.InsecureSkipVerify = p.ClientOptions.InsecureSkipVerify
+	c, err := dashboard.NewDashboardClientProxy(p.Hostname, p.Secret, p.OrgOverride, p.tls(), p.ClientOptions.Proxy)
+	c.HTTP = p.ClientOptions.HTTP
+	c.Parallel = p.ClientOptions.Parallel
+	c.Owner = p.ClientOptions.Owner
 	if err != nil {
 		return err
 	}
@@ -155,3 +234,69 @@ func (p *DashboardPublisher) SyncPolicies(pols []objects.Policy) error {
 
 	return c.SyncPolicies(pols)
 }
+
+func (p *DashboardPublisher) Plan(apiDefs []objects.DBApiDefinition, pols []objects.Policy) (*objects.SyncPlan, error) {
+	c, err := dashboard.NewDashboardClientProxy(p.Hostname, p.Secret, p.OrgOverride, p.tls(), p.ClientOptions.Proxy)
+	c.HTTP = p.ClientOptions.HTTP
+	c.Owner = p.ClientOptions.Owner
+	if err != nil {
+		return nil, err
+	}
+	if p.OrgOverride == "" {
+		p.OrgOverride = c.OrgID
+	}
+
+	fixedDefs := make([]objects.DBApiDefinition, len(apiDefs))
+	copy(fixedDefs, apiDefs)
+	p.enforceOrgID(&fixedDefs)
+
+	fixedPols := make([]objects.Policy, len(pols))
+	copy(fixedPols, pols)
+	p.enforceOrgIDForPolicies(&fixedPols)
+
+	return c.Plan(fixedDefs, fixedPols)
+}
+
+func (p *DashboardPublisher) PlanUsers(plan *objects.SyncPlan, users []objects.User, groups []objects.UserGroup) error {
+	if len(users) == 0 && len(groups) == 0 {
+		return nil
+	}
+
+	c, err := dashboard.NewDashboardClientProxy(p.Hostname, p.Secret, p.OrgOverride, p.tls(), p.ClientOptions.Proxy)
+	c.HTTP = p.ClientOptions.HTTP
+	c.Owner = p.ClientOptions.Owner
+	if err != nil {
+		return err
+	}
+
+	return c.PlanUsers(plan, users, groups)
+}
+
+func (p *DashboardPublisher) Apply(plan *objects.SyncPlan) error {
+	c, err := dashboard.NewDashboardClientProxy(p.Hostname, p.Secret, p.OrgOverride, p.tls(), p.ClientOptions.Proxy)
+	c.HTTP = p.ClientOptions.HTTP
+	c.Parallel = p.ClientOptions.Parallel
+	if err != nil {
+		return err
+	}
+
+	return c.ApplyPlan(plan)
+}
+
+// SetTLS sets the TLS settings of the client options.
+func (p *DashboardPublisher) SetTLS(tls rest.TLS) {
+	p.ClientOptions.InsecureSkipVerify = tls.InsecureSkipVerify
+	p.ClientOptions.CAFile = tls.CAFile
+	p.ClientOptions.CertFile = tls.CertFile
+	p.ClientOptions.KeyFile = tls.KeyFile
+}
+
+// tls returns the TLS settings of the client options.
+func (p *DashboardPublisher) tls() rest.TLS {
+	return rest.TLS{
+		InsecureSkipVerify: p.ClientOptions.InsecureSkipVerify,
+		CAFile:             p.ClientOptions.CAFile,
+		CertFile:           p.ClientOptions.CertFile,
+		KeyFile:            p.ClientOptions.KeyFile,
+	}
+}
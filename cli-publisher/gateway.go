@@ -2,8 +2,10 @@ package cli_publisher
 
 import (
 	"errors"
+
 	"github.com/AaronFeledy/tyk-ops/pkg/clients/gateway"
 	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/rest"
 )
 
 type GatewayPublisher struct {
@@ -14,15 +16,33 @@ type GatewayPublisher struct {
 		// InsecureSkipVerify is a flag that specifies if we should validate the
 		// server's TLS certificate.
 		InsecureSkipVerify bool
+		// CAFile, CertFile and KeyFile set the CA bundle and the client certificate used to
+		// connect to the target
+		CAFile   string
+		CertFile string
+		KeyFile  string
 		// Skip creating APIs if they already exist
 		SkipExisting bool
+		// Owner restricts syncs to the APIs stamped with this owner
+		Owner string
+		// Parallel is the number of objects written to the target at once
+		Parallel int
+		// HTTP sets the timeouts, retries and rate limit of requests to the target
+		HTTP rest.Options
+		// Proxy is the URL of the proxy requests to the target are sent through
+		Proxy string
+		// PoliciesFile is written instead of using the gateway's policy API when it is set
+		PoliciesFile string
 	}
 }
 
 func (p *GatewayPublisher) CreateAPIs(apiDefs *[]objects.DBApiDefinition) error {
 	c, err := gateway.NewGatewayClient(p.Hostname, p.Secret)
 	c.SkipExisting = p.ClientOptions.SkipExisting
-	c.InsecureSkipVerify = p.ClientOptions.InsecureSkipVerify
+	c.SetTLS(p.tls())
+	c.HTTP = p.ClientOptions.HTTP
+	c.Proxy = p.ClientOptions.Proxy
+	c.Parallel = p.ClientOptions.Parallel
 	if err != nil {
 		return err
 	}
@@ -32,7 +52,10 @@ func (p *GatewayPublisher) CreateAPIs(apiDefs *[]objects.DBApiDefinition) error
 
 func (p *GatewayPublisher) UpdateAPIs(apiDefs *[]objects.DBApiDefinition) error {
 	c, err := gateway.NewGatewayClient(p.Hostname, p.Secret)
-	c.InsecureSkipVerify = p.ClientOptions.InsecureSkipVerify
+	c.SetTLS(p.tls())
+	c.HTTP = p.ClientOptions.HTTP
+	c.Proxy = p.ClientOptions.Proxy
+	c.Parallel = p.ClientOptions.Parallel
 	if err != nil {
 		return err
 	}
@@ -46,7 +69,9 @@ func (p *GatewayPublisher) Name() string {
 
 func (p *GatewayPublisher) Reload() error {
 	c, err := gateway.NewGatewayClient(p.Hostname, p.Secret)
-	c.InsecureSkipVerify = p.ClientOptions.InsecureSkipVerify
+	c.SetTLS(p.tls())
+	c.HTTP = p.ClientOptions.HTTP
+	c.Proxy = p.ClientOptions.Proxy
 	if err != nil {
 		return err
 	}
@@ -56,7 +81,11 @@ func (p *GatewayPublisher) Reload() error {
 
 func (p *GatewayPublisher) SyncAPIs(apiDefs []objects.DBApiDefinition) error {
 	c, err := gateway.NewGatewayClient(p.Hostname, p.Secret)
-	c.InsecureSkipVerify = p.ClientOptions.InsecureSkipVerify
+	c.SetTLS(p.tls())
+	c.HTTP = p.ClientOptions.HTTP
+	c.Proxy = p.ClientOptions.Proxy
+	c.Parallel = p.ClientOptions.Parallel
+	c.Owner = p.ClientOptions.Owner
 	if err != nil {
 		return err
 	}
@@ -64,14 +93,148 @@ func (p *GatewayPublisher) SyncAPIs(apiDefs []objects.DBApiDefinition) error {
 	return c.SyncAPIs(apiDefs)
 }
 
+func (p *GatewayPublisher) FetchAPIs() ([]objects.DBApiDefinition, error) {
+	c, err := gateway.NewGatewayClient(p.Hostname, p.Secret)
+	c.SetTLS(p.tls())
+	c.HTTP = p.ClientOptions.HTTP
+	c.Proxy = p.ClientOptions.Proxy
+	if err != nil {
+		return nil, err
+	}
+
+	return c.FetchAPIs()
+}
+
+func (p *GatewayPublisher) Plan(apiDefs []objects.DBApiDefinition, pols []objects.Policy) (*objects.SyncPlan, error) {
+	c, err := gateway.NewGatewayClient(p.Hostname, p.Secret)
+	c.SetTLS(p.tls())
+	c.HTTP = p.ClientOptions.HTTP
+	c.Proxy = p.ClientOptions.Proxy
+	c.Owner = p.ClientOptions.Owner
+	c.PoliciesFile = p.ClientOptions.PoliciesFile
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Plan(apiDefs, pols)
+}
+
+// PlanUsers fails if there are users or user groups to sync, as gateways have none.
+func (p *GatewayPublisher) PlanUsers(plan *objects.SyncPlan, users []objects.User, groups []objects.UserGroup) error {
+	if len(users) > 0 || len(groups) > 0 {
+		return errors.New("users and user groups can only be synced to a dashboard")
+	}
+	return nil
+}
+
+func (p *GatewayPublisher) Apply(plan *objects.SyncPlan) error {
+	c, err := gateway.NewGatewayClient(p.Hostname, p.Secret)
+	c.SetTLS(p.tls())
+	c.HTTP = p.ClientOptions.HTTP
+	c.Proxy = p.ClientOptions.Proxy
+	c.Parallel = p.ClientOptions.Parallel
+	c.PoliciesFile = p.ClientOptions.PoliciesFile
+	if err != nil {
+		return err
+	}
+
+	return c.ApplyPlan(plan)
+}
+
+// FetchCertificates returns the metadata of the certificates currently on the gateway.
+func (p *GatewayPublisher) FetchCertificates() ([]objects.CertificateMeta, error) {
+	c, err := gateway.NewGatewayClient(p.Hostname, p.Secret)
+	if err != nil {
+		return nil, err
+	}
+	c.SetTLS(p.tls())
+	c.HTTP = p.ClientOptions.HTTP
+	c.Proxy = p.ClientOptions.Proxy
+
+	return c.FetchCertificates()
+}
+
+// CreateCertificate uploads a PEM certificate to the gateway, returning its ID.
+func (p *GatewayPublisher) CreateCertificate(cert []byte) (string, error) {
+	c, err := gateway.NewGatewayClient(p.Hostname, p.Secret)
+	if err != nil {
+		return "", err
+	}
+	c.SetTLS(p.tls())
+	c.HTTP = p.ClientOptions.HTTP
+	c.Proxy = p.ClientOptions.Proxy
+
+	return c.CreateCertificate(cert)
+}
+
+// FetchPolicies returns the policies currently on the gateway, or in the policies file when it is set.
+func (p *GatewayPublisher) FetchPolicies() ([]objects.Policy, error) {
+	c, err := p.policiesClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.FetchPolicies()
+}
+
 func (p *GatewayPublisher) CreatePolicies(pols *[]objects.Policy) error {
-	return errors.New("Policy handling not supported by Gateway publisher")
+	c, err := p.policiesClient()
+	if err != nil {
+		return err
+	}
+	c.SkipExisting = p.ClientOptions.SkipExisting
+
+	return c.CreatePolicies(pols)
 }
 
 func (p *GatewayPublisher) UpdatePolicies(pols *[]objects.Policy) error {
-	return errors.New("Policy handling not supported by Gateway publisher")
+	c, err := p.policiesClient()
+	if err != nil {
+		return err
+	}
+
+	return c.UpdatePolicies(pols)
 }
 
 func (p *GatewayPublisher) SyncPolicies(pols []objects.Policy) error {
-	return errors.New("Policy handling not supported by Gateway publisher")
+	c, err := p.policiesClient()
+	if err != nil {
+		return err
+	}
+	c.Owner = p.ClientOptions.Owner
+
+	return c.SyncPolicies(pols)
+}
+
+// policiesClient returns a gateway client for handling policies.
+func (p *GatewayPublisher) policiesClient() (*gateway.Client, error) {
+	c, err := gateway.NewGatewayClient(p.Hostname, p.Secret)
+	if err != nil {
+		return nil, err
+	}
+	c.SetTLS(p.tls())
+	c.HTTP = p.ClientOptions.HTTP
+	c.Proxy = p.ClientOptions.Proxy
+	c.Parallel = p.ClientOptions.Parallel
+	c.PoliciesFile = p.ClientOptions.PoliciesFile
+
+	return c, nil
+}
+
+// SetTLS sets the TLS settings of the client options.
+func (p *GatewayPublisher) SetTLS(tls rest.TLS) {
+	p.ClientOptions.InsecureSkipVerify = tls.InsecureSkipVerify
+	p.ClientOptions.CAFile = tls.CAFile
+	p.ClientOptions.CertFile = tls.CertFile
+	p.ClientOptions.KeyFile = tls.KeyFile
+}
+
+// tls returns the TLS settings of the client options.
+func (p *GatewayPublisher) tls() rest.TLS {
+	return rest.TLS{
+		InsecureSkipVerify: p.ClientOptions.InsecureSkipVerify,
+		CAFile:             p.ClientOptions.CAFile,
+		CertFile:           p.ClientOptions.CertFile,
+		KeyFile:            p.ClientOptions.KeyFile,
+	}
 }
@@ -1,15 +1,15 @@
 package cli_publisher
 
 import (
-	"fmt"
 	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/output"
 )
 
 type MockPublisher struct{}
 
 func (mp MockPublisher) CreateAPIs(apiDefs *[]objects.DBApiDefinition) error {
 	for _, apiDef := range *apiDefs {
-		fmt.Printf("Creating API ID: %v (on: %v to: %v)\n",
+		output.Emit(objects.APIEvent(&apiDef, objects.ActionCreate, nil), "Creating API ID: %v (on: %v to: %v)\n",
 			"mock",
 			apiDef.Proxy.ListenPath,
 			apiDef.Proxy.TargetURL)
@@ -20,7 +20,7 @@ func (mp MockPublisher) CreateAPIs(apiDefs *[]objects.DBApiDefinition) error {
 
 func (mp MockPublisher) UpdateAPIs(apiDefs *[]objects.DBApiDefinition) error {
 	for _, apiDef := range *apiDefs {
-		fmt.Printf("Updating API ID: %v (on: %v to: %v)\n",
+		output.Emit(objects.APIEvent(&apiDef, objects.ActionUpdate, nil), "Updating API ID: %v (on: %v to: %v)\n",
 			apiDef.APIID,
 			apiDef.Proxy.ListenPath,
 			apiDef.Proxy.TargetURL)
@@ -45,6 +45,72 @@ func (mp MockPublisher) SyncPolicies(pols []objects.Policy) error {
 	return nil
 }
 
+// FetchAPIs returns no APIs, as the mock publisher has no target.
+func (mp MockPublisher) FetchAPIs() ([]objects.DBApiDefinition, error) {
+	return nil, nil
+}
+
+// FetchPolicies returns no policies, as the mock publisher has no target.
+func (mp MockPublisher) FetchPolicies() ([]objects.Policy, error) {
+	return nil, nil
+}
+
+// FetchCertificates returns no certificates, as the mock publisher has no target.
+func (mp MockPublisher) FetchCertificates() ([]objects.CertificateMeta, error) {
+	return nil, nil
+}
+
+// CreateCertificate uploads nothing, as the mock publisher has no target.
+func (mp MockPublisher) CreateCertificate(cert []byte) (string, error) {
+	return "", nil
+}
+
+// Plan treats every API and policy as new, as the mock publisher has no target to compare with.
+func (mp MockPublisher) Plan(apiDefs []objects.DBApiDefinition, pols []objects.Policy) (*objects.SyncPlan, error) {
+	plan := &objects.SyncPlan{Version: objects.PlanVersion}
+	for i := range apiDefs {
+		plan.APIs = append(plan.APIs, objects.APIChange{Action: objects.ActionCreate, Local: &apiDefs[i]})
+	}
+	for i := range pols {
+		plan.Policies = append(plan.Policies, objects.PolicyChange{Action: objects.ActionCreate, Local: &pols[i]})
+	}
+
+	return plan, nil
+}
+
+// PlanUsers treats every user and user group as new, in the same way as Plan.
+func (mp MockPublisher) PlanUsers(plan *objects.SyncPlan, users []objects.User, groups []objects.UserGroup) error {
+	for i := range groups {
+		plan.UserGroups = append(plan.UserGroups, objects.UserGroupChange{Action: objects.ActionCreate, Local: &groups[i]})
+	}
+	for i := range users {
+		plan.Users = append(plan.Users, objects.UserChange{Action: objects.ActionCreate, Local: &users[i]})
+	}
+
+	return nil
+}
+
+func (mp MockPublisher) Apply(plan *objects.SyncPlan) error {
+	for _, change := range plan.APIs {
+		apiDef := change.Definition()
+		output.Emit(objects.APIEvent(apiDef, change.Action, nil), "Applying %v to API ID: %v (on: %v to: %v)\n",
+			change.Action,
+			apiDef.APIID,
+			apiDef.Proxy.ListenPath,
+			apiDef.Proxy.TargetURL)
+	}
+	for _, change := range plan.UserGroups {
+		group := change.UserGroup()
+		output.Emit(objects.UserGroupEvent(group, change.Action, nil), "Applying %v to user group: %v\n", change.Action, group.Name)
+	}
+	for _, change := range plan.Users {
+		user := change.User()
+		output.Emit(objects.UserEvent(user, change.Action, nil), "Applying %v to user: %v\n", change.Action, user.EmailAddress)
+	}
+
+	return nil
+}
+
 func (mp MockPublisher) Name() string {
 	return "Mock Publisher"
 }
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"github.com/AaronFeledy/tyk-ops/pkg/cli_util"
+	"os"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/gateway"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/spf13/cobra"
+)
+
+// adoptCmd represents the adopt command
+var adoptCmd = &cobra.Command{
+	Use:   "adopt",
+	Short: "Stamp unmanaged APIs and policies on a gateway or dashboard with an owner",
+	Long: `When an owner is set, sync only updates and deletes the objects stamped with that owner, so that
+	several repos can be synced into one dashboard. Adopt stamps existing objects that have no owner
+	yet, so that the next sync with the same owner takes them over. Select the objects with --apis and
+	--policies, or use --all to adopt every unmanaged object. Objects managed by another owner are
+	never adopted.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		applyTargetEnv(cmd)
+		verificationError := verifyArguments(cmd)
+		if verificationError != nil {
+			fmt.Println(verificationError)
+			os.Exit(1)
+		}
+
+		if err := processAdopt(cmd, args); err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func processAdopt(cmd *cobra.Command, args []string) error {
+	owner := ownerName(cmd)
+	if owner == "" {
+		return errors.New("adopt requires an owner, set --owner or owner in the config file")
+	}
+
+	all, _ := cmd.Flags().GetBool("all")
+	wantedAPIs, _ := cmd.Flags().GetStringSlice("apis")
+	wantedPolicies, _ := cmd.Flags().GetStringSlice("policies")
+	if !all && len(wantedAPIs) == 0 && len(wantedPolicies) == 0 {
+		return errors.New("adopt requires --apis, --policies or --all to be set")
+	}
+
+	publisher, err := getPublisher(cmd, args)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Using publisher: %v\n", publisher.Name())
+
+	apis, err := publisher.FetchAPIs()
+	if err != nil {
+		return err
+	}
+
+	adoptAPIs := []objects.DBApiDefinition{}
+	for _, api := range apis {
+		if !all && !contains(wantedAPIs, api.APIID) {
+			continue
+		}
+		if adoptable(fmt.Sprintf("API %v", api.APIID), api.Owner(), owner) {
+			api.SetOwner(owner)
+			adoptAPIs = append(adoptAPIs, api)
+		}
+	}
+
+	fmt.Printf("> Adopting %v APIs\n", len(adoptAPIs))
+	if len(adoptAPIs) > 0 {
+		if err := publisher.UpdateAPIs(&adoptAPIs); err != nil {
+			return err
+		}
+	}
+
+	pols, err := publisher.FetchPolicies()
+	if err == gateway.PolicyAPIUnsupportedError && len(wantedPolicies) == 0 {
+		// Gateways without the policy API can still have their APIs adopted
+		fmt.Println("The gateway doesn't support the policy API, skipping policies")
+		return publisher.Reload()
+	}
+	if err != nil {
+		return err
+	}
+
+	adoptPolicies := []objects.Policy{}
+	for _, pol := range pols {
+		id := pol.ID
+		if id == "" {
+			id = pol.MID.Hex()
+		}
+		if !all && !contains(wantedPolicies, id) && !contains(wantedPolicies, pol.MID.Hex()) {
+			continue
+		}
+		if adoptable(fmt.Sprintf("policy %v", id), pol.Owner(), owner) {
+			pol.SetOwner(owner)
+			adoptPolicies = append(adoptPolicies, pol)
+		}
+	}
+
+	fmt.Printf("> Adopting %v policies\n", len(adoptPolicies))
+	if len(adoptPolicies) > 0 {
+		if err := publisher.UpdatePolicies(&adoptPolicies); err != nil {
+			return err
+		}
+	}
+
+	if isGateway {
+		if err := publisher.Reload(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("Done")
+	return nil
+}
+
+// adoptable reports whether an object with the given owner can be adopted, explaining why not.
+func adoptable(object, current, owner string) bool {
+	switch current {
+	case "":
+		return true
+	case owner:
+		fmt.Printf("--> %v is already managed by %v\n", object, owner)
+	default:
+		fmt.Printf("--> [WARNING] %v is managed by %v, skipping\n", object, current)
+	}
+	return false
+}
+
+func contains(list []string, val string) bool {
+	for _, v := range list {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	rootCmd.AddCommand(adoptCmd)
+
+	adoptCmd.Flags().StringP("gateway", "g", "", "Fully qualified gateway target URL")
+	adoptCmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
+	adoptCmd.Flags().StringP("secret", "s", "", "Your API secret")
+	adoptCmd.Flags().String("owner", "", "Owner to stamp the objects with (defaults to owner in the config file)")
+	adoptCmd.Flags().StringSlice("apis", []string{}, "Specific Apis ids to adopt")
+	adoptCmd.Flags().StringSlice("policies", []string{}, "Specific Policies ids to adopt")
+	adoptCmd.Flags().Bool("all", false, "Adopt every object that has no owner")
+	adoptCmd.Flags().BoolP("insecure", "", false, "Override TLS certificate validation")
+	cli_util.AddTLSFlags(adoptCmd)
+	adoptCmd.Flags().String("policies-file", "", "Write gateway policies to this file instead of using the gateway's policy API")
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/AaronFeledy/tyk-ops/pkg/cli_util"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// applyCmd represents the apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply plan_file",
+	Short: "Apply a plan saved by sync --out to a gateway or dashboard",
+	Long: `Apply will make exactly the changes recorded in a plan file created with sync --out. The
+	plan records the state of the target it was created against, and apply will refuse to make
+	any changes if the target's APIs or policies have changed since then.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		applyTargetEnv(cmd)
+		verificationError := verifyArguments(cmd)
+		if verificationError != nil {
+			fmt.Println(verificationError)
+			os.Exit(1)
+		}
+
+		err := processApply(cmd, args)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func processApply(cmd *cobra.Command, args []string) error {
+	plan, err := readPlan(args[0])
+	if err != nil {
+		return err
+	}
+
+	publisher, err := getPublisher(cmd, args)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Using publisher: %v\n", publisher.Name())
+
+	if err := printPlan(plan); err != nil {
+		return err
+	}
+
+	fmt.Println("Processing changes...")
+	return applyPlan(publisher, plan)
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+
+	applyCmd.Flags().StringP("gateway", "g", "", "Fully qualified gateway target URL")
+	applyCmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
+	applyCmd.Flags().StringP("secret", "s", "", "Your API secret")
+	applyCmd.Flags().StringP("org", "o", "", "org ID override")
+	applyCmd.Flags().Bool("test", false, "Use test publisher, output results to stdio")
+	applyCmd.Flags().BoolP("insecure", "", false, "Override TLS certificate validation")
+	cli_util.AddTLSFlags(applyCmd)
+	applyCmd.Flags().String("policies-file", "", "Write gateway policies to this file instead of using the gateway's policy API")
+	applyCmd.Flags().Int("parallel", 1, "Number of APIs or policies to write to the target at once")
+}
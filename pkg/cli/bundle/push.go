@@ -2,6 +2,7 @@ package bundle
 
 import (
 	"fmt"
+	"github.com/AaronFeledy/tyk-ops/pkg/cli_util"
 	"github.com/AaronFeledy/tyk-ops/pkg/clients/mserv"
 	out "github.com/AaronFeledy/tyk-ops/pkg/output"
 	"github.com/schollz/progressbar/v3"
@@ -34,6 +35,7 @@ func init() {
 	pushCmd.PersistentFlags().StringP("endpoint", "e", "", "Mserv endpoint")
 	pushCmd.PersistentFlags().StringP("dashboard", "d", "", "The dashboard proxying to mserv")
 	pushCmd.PersistentFlags().BoolP("insecure-tls", "k", false, "allow insecure TLS for mserv client")
+	cli_util.AddTLSFlags(pushCmd)
 
 	_ = viper.BindPFlag("mserv-url", pushCmd.PersistentFlags().Lookup("endpoint"))
 	_ = viper.BindPFlag("mserv-secret", pushCmd.PersistentFlags().Lookup("token"))
@@ -85,6 +87,8 @@ func pushBundle(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to init mserv client: %s", err.Error())
 	}
+	client.SetTLS(cli_util.TLSOptions(cmd, "insecure-tls", cfg.TargetServer("mserv")))
+	client.Proxy = cfg.TargetServer("mserv").Proxy
 
 	// Errors beyond this point are unlikely to be tykops syntax so don't display help/usage on error.
 	cmd.SilenceUsage = true
@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/cli_util"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/interfaces"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/spf13/cobra"
+)
+
+// certCmd represents the cert command
+var certCmd = &cobra.Command{
+	Use:   "cert",
+	Short: "Manage the certificates stored on a gateway or dashboard",
+	Long: `Certificates stored on a gateway or dashboard are used for mutual TLS with clients and
+	upstreams, and are referred to by ID from API definitions. These commands list, show, upload,
+	delete and export them.`,
+}
+
+var certListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the certificates with their fingerprint, subject, SANs and expiry",
+	Args:  cobra.NoArgs,
+	Run:   certRun(processCertList),
+}
+
+var certShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show the details of a certificate",
+	Args:  cobra.ExactArgs(1),
+	Run:   certRun(processCertShow),
+}
+
+var certUploadCmd = &cobra.Command{
+	Use:   "upload <file>...",
+	Short: "Upload PEM certificates, with their private key when the file has one",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   certRun(processCertUpload),
+}
+
+var certDeleteCmd = &cobra.Command{
+	Use:   "delete <id>...",
+	Short: "Delete certificates",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   certRun(processCertDelete),
+}
+
+var certExportCmd = &cobra.Command{
+	Use:   "export [id]...",
+	Short: "Export the metadata of certificates to JSON files",
+	Long: `Export writes the metadata of the given certificates, or of every certificate when none are
+	given, to a <id>.json file each. The gateway and dashboard APIs never return a certificate's PEM
+	data or private key, so the files can't be uploaded again: keep the PEM files themselves safe.`,
+	Run: certRun(processCertExport),
+}
+
+// certRun returns the Run function of a cert command, which connects to the target and calls process.
+func certRun(process func(cmd *cobra.Command, client interfaces.CertificateManagementClient, args []string) error) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		applyTargetEnv(cmd)
+		verificationError := verifyArguments(cmd)
+		if verificationError != nil {
+			fmt.Println(verificationError)
+			os.Exit(1)
+		}
+
+		client, err := newTargetClient(cmd)
+		if err == nil {
+			err = process(cmd, client, args)
+		}
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func processCertList(cmd *cobra.Command, client interfaces.CertificateManagementClient, args []string) error {
+	certs, err := client.FetchCertificates()
+	if err != nil {
+		return err
+	}
+
+	if len(certs) == 0 {
+		fmt.Println("no certificates found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 1, 1, 4, ' ', 0)
+	fmt.Fprintln(w, "ID\tFINGERPRINT\tSUBJECT\tSANS\tEXPIRES")
+	for _, cert := range certs {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", cert.ID, cert.Fingerprint, cert.Subject, strings.Join(cert.DNSNames, ","), expiry(cert))
+	}
+	return w.Flush()
+}
+
+func processCertShow(cmd *cobra.Command, client interfaces.CertificateManagementClient, args []string) error {
+	cert, err := client.FetchCertificate(args[0])
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 1, 1, 2, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%v\n", cert.ID)
+	fmt.Fprintf(w, "Fingerprint:\t%v\n", cert.Fingerprint)
+	fmt.Fprintf(w, "Subject:\t%v\n", cert.Subject)
+	fmt.Fprintf(w, "Issuer:\t%v\n", cert.Issuer)
+	fmt.Fprintf(w, "SANs:\t%v\n", strings.Join(cert.DNSNames, ", "))
+	fmt.Fprintf(w, "Not before:\t%v\n", cert.NotBefore.Format(time.RFC3339))
+	fmt.Fprintf(w, "Not after:\t%v\n", expiry(*cert))
+	fmt.Fprintf(w, "CA:\t%v\n", cert.IsCA)
+	fmt.Fprintf(w, "Private key:\t%v\n", cert.HasPrivate)
+	return w.Flush()
+}
+
+func processCertUpload(cmd *cobra.Command, client interfaces.CertificateManagementClient, args []string) error {
+	for _, file := range args {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		id, err := client.CreateCertificate(data)
+		if err != nil {
+			return fmt.Errorf("uploading %v: %v", file, err)
+		}
+		fmt.Printf("Uploaded %v: %v\n", file, id)
+	}
+
+	return nil
+}
+
+func processCertDelete(cmd *cobra.Command, client interfaces.CertificateManagementClient, args []string) error {
+	for _, id := range args {
+		if err := client.DeleteCertificate(id); err != nil {
+			return fmt.Errorf("deleting certificate %v: %v", id, err)
+		}
+		fmt.Printf("Deleted certificate: %v\n", id)
+	}
+
+	return nil
+}
+
+func processCertExport(cmd *cobra.Command, client interfaces.CertificateManagementClient, args []string) error {
+	certs := []objects.CertificateMeta{}
+	if len(args) == 0 {
+		all, err := client.FetchCertificates()
+		if err != nil {
+			return err
+		}
+		certs = all
+	}
+	for _, id := range args {
+		cert, err := client.FetchCertificate(id)
+		if err != nil {
+			return fmt.Errorf("fetching certificate %v: %v", id, err)
+		}
+		certs = append(certs, *cert)
+	}
+
+	dir, _ := cmd.Flags().GetString("out")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, cert := range certs {
+		data, err := json.MarshalIndent(cert, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		file := filepath.Join(dir, cert.ID+".json")
+		if err := ioutil.WriteFile(file, data, 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Exported certificate %v to %v\n", cert.ID, file)
+	}
+
+	fmt.Println("--> [WARNING] Only the metadata of the certificates was exported, their PEM data can't be fetched from the target")
+	return nil
+}
+
+// expiry returns when a certificate expires, marking those that already have.
+func expiry(cert objects.CertificateMeta) string {
+	expires := cert.NotAfter.Format(time.RFC3339)
+	if cert.NotAfter.Before(time.Now()) {
+		expires += " (expired)"
+	}
+	return expires
+}
+
+func init() {
+	rootCmd.AddCommand(certCmd)
+	for _, cmd := range []*cobra.Command{certListCmd, certShowCmd, certUploadCmd, certDeleteCmd, certExportCmd} {
+		certCmd.AddCommand(cmd)
+		cmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
+		cmd.Flags().StringP("gateway", "g", "", "Fully qualified gateway target URL")
+		cmd.Flags().StringP("secret", "s", "", "Your API secret")
+		cmd.Flags().BoolP("insecure", "", false, "Override TLS certificate validation")
+		cli_util.AddTLSFlags(cmd)
+	}
+
+	certExportCmd.Flags().String("out", ".", "Directory to write the exported metadata to")
+}
@@ -0,0 +1,184 @@
+package cli
+
+import (
+	"fmt"
+	"github.com/AaronFeledy/tyk-ops/pkg/cli_util"
+	"os"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/diff"
+	"github.com/spf13/cobra"
+)
+
+// driftExitCode is the exit code used when the target has drifted from the repo, so that it can
+// be told apart from errors, which exit with 1.
+const driftExitCode = 2
+
+// driftCmd represents the drift command
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Compare a github repo or file system with the APIs and policies on a gateway or dashboard",
+	Long: `Drift will compare the contents of a Github repository or directory with a gateway or dashboard
+	and report objects that only exist on the target, only exist in the repo, or differ between the two.
+	Fields that are managed by the server, such as _id, last_updated and date_created, are ignored.
+	Drift exits with 0 when the target is in sync, 2 when it has drifted and 1 on errors.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		applyTargetEnv(cmd)
+		verificationError := verifyArguments(cmd)
+		if verificationError != nil {
+			fmt.Println(verificationError)
+			os.Exit(1)
+		}
+
+		drifted, err := processDrift(cmd, args)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+
+		if drifted {
+			os.Exit(driftExitCode)
+		}
+	},
+}
+
+func processDrift(cmd *cobra.Command, args []string) (bool, error) {
+	data, err := doGetData(cmd, args)
+	if err != nil {
+		return false, err
+	}
+
+	publisher, err := getPublisher(cmd, args)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := resolveCertificates(publisher, data.APIs, data.Certificates, true); err != nil {
+		return false, err
+	}
+
+	plan, err := publisher.Plan(data.APIs, data.Policies)
+	if err != nil {
+		return false, err
+	}
+
+	if err := publisher.PlanUsers(plan, data.Users, data.UserGroups); err != nil {
+		return false, err
+	}
+
+	return printDrift(plan)
+}
+
+// driftEntry is a single object that differs between the repo and the target
+type driftEntry struct {
+	label   string
+	changes []diff.Change
+}
+
+// printDrift reports the differences between the repo and the target found in a sync plan, and
+// whether there were any.
+func printDrift(plan *objects.SyncPlan) (bool, error) {
+	var remoteOnly, repoOnly, differs []driftEntry
+
+	add := func(action objects.ChangeAction, label string, changes []diff.Change) {
+		switch action {
+		case objects.ActionDelete:
+			remoteOnly = append(remoteOnly, driftEntry{label: label})
+		case objects.ActionCreate:
+			repoOnly = append(repoOnly, driftEntry{label: label})
+		case objects.ActionUpdate:
+			if len(changes) > 0 {
+				differs = append(differs, driftEntry{label: label, changes: changes})
+			}
+		}
+	}
+
+	for _, change := range plan.Policies {
+		pol := change.Policy()
+		id := pol.ID
+		if id == "" {
+			id = pol.MID.Hex()
+		}
+
+		changes, err := change.Diff()
+		if err != nil {
+			return false, err
+		}
+		add(change.Action, fmt.Sprintf("policy %q (id: %v)", pol.Name, id), changes)
+	}
+
+	for _, change := range plan.APIs {
+		def := change.Definition()
+
+		changes, err := change.Diff()
+		if err != nil {
+			return false, err
+		}
+		add(change.Action, fmt.Sprintf("api %q (api_id: %v)", def.Name, def.APIID), changes)
+	}
+
+	for _, change := range plan.UserGroups {
+		changes, err := change.Diff()
+		if err != nil {
+			return false, err
+		}
+		add(change.Action, fmt.Sprintf("user group %q", change.UserGroup().Name), changes)
+	}
+
+	for _, change := range plan.Users {
+		changes, err := change.Diff()
+		if err != nil {
+			return false, err
+		}
+		add(change.Action, fmt.Sprintf("user %q", change.User().EmailAddress), changes)
+	}
+
+	sections := []struct {
+		title   string
+		entries []driftEntry
+	}{
+		{"Only on target:", remoteOnly},
+		{"Only in repo:", repoOnly},
+		{"Differs:", differs},
+	}
+	for _, section := range sections {
+		if len(section.entries) == 0 {
+			continue
+		}
+		fmt.Println(section.title)
+		for _, entry := range section.entries {
+			fmt.Printf("  %s\n", entry.label)
+			for _, c := range entry.changes {
+				fmt.Printf("      %s\n", c)
+			}
+		}
+	}
+
+	drifted := len(remoteOnly)+len(repoOnly)+len(differs) > 0
+	if drifted {
+		fmt.Printf("\nDrift detected: %v only on target, %v only in repo, %v differ.\n",
+			len(remoteOnly), len(repoOnly), len(differs))
+	} else {
+		fmt.Println("No drift detected, target is in sync.")
+	}
+
+	return drifted, nil
+}
+
+func init() {
+	rootCmd.AddCommand(driftCmd)
+
+	driftCmd.Flags().StringP("gateway", "g", "", "Fully qualified gateway target URL")
+	driftCmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
+	driftCmd.Flags().StringP("key", "k", "", "Key file location for auth (optional)")
+	driftCmd.Flags().StringP("branch", "b", "refs/heads/master", "Branch to use (defaults to refs/heads/master)")
+	driftCmd.Flags().StringP("secret", "s", "", "Your API secret")
+	driftCmd.Flags().StringP("org", "o", "", "org ID override")
+	driftCmd.Flags().StringP("path", "p", "", "Source directory for definition files (optional)")
+	driftCmd.Flags().StringSlice("policies", []string{}, "Specific Policies ids to compare")
+	driftCmd.Flags().StringSlice("apis", []string{}, "Specific Apis ids to compare")
+	driftCmd.Flags().BoolP("insecure", "", false, "Override TLS certificate validation")
+	cli_util.AddTLSFlags(driftCmd)
+	driftCmd.Flags().String("policies-file", "", "Read gateway policies from this file instead of using the gateway's policy API")
+	driftCmd.Flags().String("owner", "", "Only manage the objects stamped with this owner, see the adopt command (defaults to owner in the config file)")
+}
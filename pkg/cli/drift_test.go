@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintDrift(t *testing.T) {
+	remote := objects.Policy{ID: "pol", Name: "Policy", Rate: 10, DateCreated: time.Now(), LastUpdated: "1"}
+	local := objects.Policy{ID: "pol", Name: "Policy", Rate: 10}
+
+	t.Run("server managed fields are not drift", func(t *testing.T) {
+		plan := &objects.SyncPlan{Policies: []objects.PolicyChange{
+			{Action: objects.ActionUpdate, Local: &local, Remote: &remote},
+		}}
+		drifted, err := printDrift(plan)
+		assert.NoError(t, err)
+		assert.False(t, drifted)
+	})
+	t.Run("changed fields are drift", func(t *testing.T) {
+		changed := local
+		changed.Rate = 20
+		plan := &objects.SyncPlan{Policies: []objects.PolicyChange{
+			{Action: objects.ActionUpdate, Local: &changed, Remote: &remote},
+		}}
+		drifted, err := printDrift(plan)
+		assert.NoError(t, err)
+		assert.True(t, drifted)
+	})
+	t.Run("objects only on the target are drift", func(t *testing.T) {
+		plan := &objects.SyncPlan{Policies: []objects.PolicyChange{
+			{Action: objects.ActionDelete, Remote: &remote},
+		}}
+		drifted, err := printDrift(plan)
+		assert.NoError(t, err)
+		assert.True(t, drifted)
+	})
+}
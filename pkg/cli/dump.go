@@ -1,9 +1,12 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"github.com/AaronFeledy/tyk-ops/pkg/cli_util"
 	"github.com/AaronFeledy/tyk-ops/pkg/clients/dashboard"
 	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	out "github.com/AaronFeledy/tyk-ops/pkg/output"
 
 	"gopkg.in/mgo.v2/bson"
 
@@ -16,6 +19,9 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// actionDump is the action reported for each object written to a file by dump.
+const actionDump objects.ChangeAction = "dump"
+
 // dumpCmd represents the dump command
 var dumpCmd = &cobra.Command{
 	Use:   "dump",
@@ -24,239 +30,261 @@ var dumpCmd = &cobra.Command{
 	place them in a directory of your choosing. It will also generate a spec file
 	that can be used for sync.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		dbString, _ := cmd.Flags().GetString("dashboard")
-
-		if dbString == "" {
-			fmt.Println("Dump requires a dashboard URL to be set")
-			return
+		setOutputFormat(cmd)
+		err := processDump(cmd)
+		out.Finish(cmd.Name(), err)
+		if err != nil {
+			out.Println(err)
+			os.Exit(1)
 		}
+	},
+}
 
-		flagVal, _ := cmd.Flags().GetString("secret")
-
-		sec := os.Getenv("TYKGIT_DB_SECRET")
-		if sec == "" && flagVal == "" {
-			fmt.Println("Please set TYKGIT_DB_SECRET, or set the --secret flag, to your dashboard user secret")
-			return
+// processDump writes the APIs and policies on the dashboard to the target directory, along with a
+// spec file for them.
+func processDump(cmd *cobra.Command) error {
+	dbString, _ := cmd.Flags().GetString("dashboard")
+
+	if dbString == "" {
+		return errors.New("dump requires a dashboard URL to be set")
+	}
+
+	flagVal, _ := cmd.Flags().GetString("secret")
+
+	sec := os.Getenv("TYKGIT_DB_SECRET")
+	if sec == "" && flagVal == "" {
+		return errors.New("please set TYKGIT_DB_SECRET, or set the --secret flag, to your dashboard user secret")
+	}
+
+	secret := ""
+	if sec != "" {
+		secret = sec
+	}
+
+	if flagVal != "" {
+		secret = flagVal
+	}
+
+	out.Printf("Extracting APIs and Policies from %v\n", dbString)
+
+	c, err := dashboard.NewDashboardClientProxy(dbString, secret, "", cli_util.TLSOptions(cmd, "insecure", cfg.TargetServer("dashboard")), cfg.TargetServer("dashboard").Proxy)
+	if err != nil {
+		return err
+	}
+	c.HTTP = httpOptions()
+
+	out.Println("> Fetching policies")
+	wantedPolicies, _ := cmd.Flags().GetStringSlice("policies")
+	wantedAPIs, _ := cmd.Flags().GetStringSlice("apis")
+
+	policies := []objects.Policy{}
+	apis := []objects.DBApiDefinition{}
+	var errPoliciesFetch error
+	var errApisFetch error
+
+	//building the api def objs from wantedAPIs
+	for _, APIID := range wantedAPIs {
+		api := objects.DBApiDefinition{APIDefinition: &objects.APIDefinition{}}
+		api.APIID = APIID
+		apis = append(apis, api)
+	}
+
+	//building the policies obj from wantedAPIs
+	for _, wantedPolicy := range wantedPolicies {
+		if !bson.IsObjectIdHex(wantedPolicy) {
+			return fmt.Errorf("invalid selected policy ID: %s", wantedPolicy)
 		}
-
-		secret := ""
-		if sec != "" {
-			secret = sec
+		pol := objects.Policy{
+			ID:  wantedPolicy,
+			MID: bson.ObjectIdHex(wantedPolicy),
 		}
+		policies = append(policies, pol)
+	}
 
-		if flagVal != "" {
-			secret = flagVal
-		}
+	if len(wantedAPIs) == 0 && len(wantedPolicies) == 0 {
+		out.Println("> Fetching policies ")
 
-		fmt.Printf("Extracting APIs and Policies from %v\n", dbString)
+		policies, errPoliciesFetch = c.FetchPolicies()
+		if errPoliciesFetch != nil {
+			return errPoliciesFetch
+		}
+		out.Println("> Fetching APIs")
 
-		c, err := dashboard.NewDashboardClient(dbString, secret, "")
+		apis, errApisFetch = c.FetchAPIs()
+		if errApisFetch != nil {
+			return errApisFetch
+		}
+	}
+
+	out.Printf("--> Identified %v policies\n", len(policies))
+	if len(wantedPolicies) > 0 {
+		out.Println("--> Fetching and cleaning policy objects")
+	} else {
+		out.Println("--> Cleaning policy objects")
+	}
+	// A bug exists which causes decoding of the access rights to break,
+	// so we should fetch individually
+	cleanPolicyObjects := make([]*objects.Policy, len(policies))
+	for i, p := range policies {
+		cp, err := c.FetchPolicy(p.MID.Hex())
 		if err != nil {
-			fmt.Println(err)
+			return err
 		}
 
-		fmt.Println("> Fetching policies")
-		wantedPolicies, _ := cmd.Flags().GetStringSlice("policies")
-		wantedAPIs, _ := cmd.Flags().GetStringSlice("apis")
-
-		policies := []objects.Policy{}
-		apis := []objects.DBApiDefinition{}
-		var errPoliciesFetch error
-		var errApisFetch error
-
-		//building the api def objs from wantedAPIs
-		for _, APIID := range wantedAPIs {
-			api := objects.DBApiDefinition{APIDefinition: &objects.APIDefinition{}}
-			api.APIID = APIID
-			apis = append(apis, api)
+		// Make sure we retain IDs
+		if cp.ID == "" {
+			cp.ID = cp.MID.Hex()
 		}
 
-		//building the policies obj from wantedAPIs
-		for _, wantedPolicy := range wantedPolicies {
-			if !bson.IsObjectIdHex(wantedPolicy) {
-				fmt.Printf("Invalid selected policy ID: %s.\n", wantedPolicy)
-				return
-			}
-			pol := objects.Policy{
-				ID:  wantedPolicy,
-				MID: bson.ObjectIdHex(wantedPolicy),
-			}
-			policies = append(policies, pol)
-		}
-
-		if len(wantedAPIs) == 0 && len(wantedPolicies) == 0 {
-			fmt.Println("> Fetching policies ")
+		cleanPolicyObjects[i] = cp
+	}
+	out.Printf("--> Fetched %v Policies\n", len(cleanPolicyObjects))
 
-			policies, errPoliciesFetch = c.FetchPolicies()
-			if errPoliciesFetch != nil {
-				fmt.Println(errPoliciesFetch)
-				return
-			}
-			fmt.Println("> Fetching APIs")
+	if len(wantedAPIs) > 0 {
+		out.Printf("--> Identified %v APIs\n", len(apis))
+		out.Println("--> Fetching and cleaning APIs objects")
 
-			apis, errApisFetch = c.FetchAPIs()
+		for i, api := range apis {
+			fullAPI, err := c.FetchAPI(api.APIID)
 			if err != nil {
-				fmt.Println(errApisFetch)
-				return
+				return err
 			}
+			apis[i] = fullAPI
 		}
+	}
 
-		fmt.Printf("--> Identified %v policies\n", len(policies))
-		if len(wantedPolicies) > 0 {
-			fmt.Println("--> Fetching and cleaning policy objects")
-		} else {
-			fmt.Println("--> Cleaning policy objects")
-		}
-		// A bug exists which causes decoding of the access rights to break,
-		// so we should fetch individually
-		cleanPolicyObjects := make([]*objects.Policy, len(policies))
-		for i, p := range policies {
-			cp, err := c.FetchPolicy(p.MID.Hex())
-			if err != nil {
-				fmt.Println(err)
-				return
-			}
+	out.Printf("--> Fetched %v APIs\n", len(apis))
 
-			// Make sure we retain IDs
-			if cp.ID == "" {
-				cp.ID = cp.MID.Hex()
-			}
+	dir, _ := cmd.Flags().GetString("target")
+	apiFiles := make([]string, len(apis))
+	for i, api := range apis {
 
-			cleanPolicyObjects[i] = cp
-		}
-		fmt.Printf("--> Fetched %v Policies\n", len(cleanPolicyObjects))
-
-		if len(wantedAPIs) > 0 {
-			fmt.Printf("--> Identified %v APIs\n", len(apis))
-			fmt.Println("--> Fetching and cleaning APIs objects")
-
-			for i, api := range apis {
-				fullAPI, err := c.FetchAPI(api.APIID)
-				if err != nil {
-					fmt.Println(err)
-					return
-				}
-				apis[i] = fullAPI
-			}
+		j, jerr := json.MarshalIndent(api, "", "  ")
+		if jerr != nil {
+			return fmt.Errorf("JSON Encoding error: %v", jerr.Error())
 		}
 
-		fmt.Printf("--> Fetched %v APIs\n", len(apis))
-
-		dir, _ := cmd.Flags().GetString("target")
-		apiFiles := make([]string, len(apis))
-		for i, api := range apis {
-
-			j, jerr := json.MarshalIndent(api, "", "  ")
-			if jerr != nil {
-				fmt.Printf("JSON Encoding error: %v\n", jerr.Error())
-				return
-			}
-
-			fname := fmt.Sprintf("api-%v.json", api.APIID)
-			p := path.Join(dir, fname)
-			err := ioutil.WriteFile(p, j, 0644)
-			if err != nil {
-				fmt.Printf("Error writing file: %v\n", err)
-				return
-			}
-			apiFiles[i] = fname
+		fname := fmt.Sprintf("api-%v.json", api.APIID)
+		p := path.Join(dir, fname)
+		err := ioutil.WriteFile(p, j, 0644)
+		out.Emit(objects.APIEvent(&apis[i], actionDump, err), "")
+		if err != nil {
+			return fmt.Errorf("error writing file: %v", err)
 		}
-
-		// If we have selected Policies specified we're going to check if we're importing all the necessary APIs
-		if len(wantedPolicies) > 0 {
-			for _, policy := range cleanPolicyObjects {
-				for _, accesRights := range policy.AccessRights {
-					found := false
-					for _, api := range apis {
-						if api.APIID == accesRights.APIID {
-							found = true
-						}
-					}
-					if !found {
-						fmt.Println("--> [WARNING] Policy ", policy.ID, " has access rights over API ID ", accesRights.APIID, " and that API it's not imported. It might cause some problems in the future.")
-					}
-				}
-			}
+		apiFiles[i] = fname
+	}
+
+	// Warn about references to objects that aren't being dumped, which is likely when only
+	// some APIs or policies have been selected
+	dumpedPolicies := make([]objects.Policy, len(cleanPolicyObjects))
+	for i, pol := range cleanPolicyObjects {
+		dumpedPolicies[i] = *pol
+	}
+	for _, issue := range tyk_vcs.CheckIntegrity(apis, dumpedPolicies) {
+		out.Printf("--> [WARNING] %v\n", issue)
+	}
+
+	if err := dumpCertificates(c, apis, dir); err != nil {
+		return err
+	}
+
+	policyFiles := make([]string, len(cleanPolicyObjects))
+	for i, pol := range cleanPolicyObjects {
+		if pol.ID == "" {
+			pol.ID = pol.MID.Hex()
 		}
-		// If we have selected APIs specified we're going to check if we're importing all the necessary policies
-		if len(wantedAPIs) > 0 {
-			//checking selected APIs  -  Policies integrity
-			for _, api := range apis {
-				for _, provider := range api.OpenIDOptions.Providers {
-					for _, id := range provider.ClientIDs {
-						found := false
-						for _, policy := range cleanPolicyObjects {
-							if policy.ID == id {
-								found = true
-								break
-							}
-						}
-						if !found {
-							fmt.Println("--> [WARNING] Api ", api.APIID, " has the Policy ", id, " as an OIDC issuer policy and that policy is not imported. It might cause some problems in the future.")
-						}
-					}
-				}
-			}
+
+		j, jerr := json.MarshalIndent(pol, "", "  ")
+		if jerr != nil {
+			return fmt.Errorf("JSON Encoding error: %v", jerr.Error())
 		}
 
-		policyFiles := make([]string, len(cleanPolicyObjects))
-		for i, pol := range cleanPolicyObjects {
-			if pol.ID == "" {
-				pol.ID = pol.MID.Hex()
-			}
+		fname := fmt.Sprintf("policy-%v.json", pol.ID)
+		p := path.Join(dir, fname)
+		err := ioutil.WriteFile(p, j, 0644)
+		out.Emit(objects.PolicyEvent(pol, actionDump, err), "")
+		if err != nil {
+			return fmt.Errorf("error writing file: %v", err)
+		}
 
-			j, jerr := json.MarshalIndent(pol, "", "  ")
-			if jerr != nil {
-				fmt.Printf("JSON Encoding error: %v\n", jerr.Error())
-				return
-			}
+		policyFiles[i] = fname
+	}
 
-			fname := fmt.Sprintf("policy-%v.json", pol.ID)
-			p := path.Join(dir, fname)
-			err := ioutil.WriteFile(p, j, 0644)
-			if err != nil {
-				fmt.Printf("Error writing file: %v\n", err)
-				return
-			}
+	// Create a spec file
+	gitSpec := tyk_vcs.TykSourceSpec{
+		Type:     tyk_vcs.TYPE_APIDEF,
+		Files:    make([]tyk_vcs.APIInfo, len(apiFiles)),
+		Policies: make([]tyk_vcs.PolicyInfo, len(policyFiles)),
+	}
 
-			policyFiles[i] = fname
+	for i, apiFile := range apiFiles {
+		asInfo := tyk_vcs.APIInfo{
+			File: apiFile,
 		}
+		gitSpec.Files[i] = asInfo
+	}
 
-		// Create a spec file
-		gitSpec := tyk_vcs.TykSourceSpec{
-			Type:     tyk_vcs.TYPE_APIDEF,
-			Files:    make([]tyk_vcs.APIInfo, len(apiFiles)),
-			Policies: make([]tyk_vcs.PolicyInfo, len(policyFiles)),
+	for i, polFile := range policyFiles {
+		asInfo := tyk_vcs.PolicyInfo{
+			File: polFile,
 		}
+		gitSpec.Policies[i] = asInfo
+	}
+
+	fname := ".tyk.json"
+	p := path.Join(dir, fname)
+	out.Printf("> Creating spec file in: %v\n", p)
+	j, jerr := json.MarshalIndent(gitSpec, "", "  ")
+	if jerr != nil {
+		return fmt.Errorf("JSON Encoding error: %v", jerr.Error())
+	}
+	if err := ioutil.WriteFile(p, j, 0644); err != nil {
+		return fmt.Errorf("error writing file: %v", err)
+	}
+	out.Println("Done.")
+	return nil
+}
 
-		for i, apiFile := range apiFiles {
-			asInfo := tyk_vcs.APIInfo{
-				File: apiFile,
+// dumpCertificates writes the metadata of the certificates the APIs refer to into cert-<id>.json
+// files. The dashboard never returns the PEM data of a certificate, so the public certificates have
+// to be added to the spec's certificates by hand for them to be uploaded to other targets.
+func dumpCertificates(c *dashboard.Client, apis []objects.DBApiDefinition, dir string) error {
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, api := range apis {
+		for _, id := range api.CertificateIDs() {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
 			}
-			gitSpec.Files[i] = asInfo
 		}
-
-		for i, polFile := range policyFiles {
-			asInfo := tyk_vcs.PolicyInfo{
-				File: polFile,
-			}
-			gitSpec.Policies[i] = asInfo
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	out.Printf("--> Identified %v referenced certificates\n", len(ids))
+	for _, id := range ids {
+		cert, err := c.FetchCertificate(id)
+		if err != nil {
+			out.Printf("--> [WARNING] Certificate %v is referenced but couldn't be fetched: %v\n", id, err)
+			continue
 		}
 
-		fname := ".tyk.json"
-		p := path.Join(dir, fname)
-		fmt.Printf("> Creating spec file in: %v\n", p)
-		j, jerr := json.MarshalIndent(gitSpec, "", "  ")
+		j, jerr := json.MarshalIndent(cert, "", "  ")
 		if jerr != nil {
-			fmt.Printf("JSON Encoding error: %v\n", jerr.Error())
-			return
+			return fmt.Errorf("JSON Encoding error: %v", jerr.Error())
 		}
+
+		p := path.Join(dir, fmt.Sprintf("cert-%v.json", cert.ID))
 		if err := ioutil.WriteFile(p, j, 0644); err != nil {
-			fmt.Printf("Error writing file: %v\n", err)
-			return
+			return fmt.Errorf("error writing file: %v", err)
 		}
-		fmt.Println("Done.")
-	},
+		out.Printf("--> Wrote certificate metadata: %v\n", p)
+	}
+
+	out.Println("--> [WARNING] The dashboard doesn't return certificates' PEM data, add the PEM files of the certificates above to the spec's certificates so they can be uploaded to other targets")
+	return nil
 }
 
 func init() {
@@ -269,4 +297,7 @@ func init() {
 	dumpCmd.Flags().StringP("target", "t", "", "Target directory for files")
 	dumpCmd.Flags().StringSlice("policies", []string{}, "Specific Policies ids to dump")
 	dumpCmd.Flags().StringSlice("apis", []string{}, "Specific Apis ids to dump")
+	dumpCmd.Flags().BoolP("insecure", "", false, "Override TLS certificate validation")
+	cli_util.AddTLSFlags(dumpCmd)
+	dumpCmd.Flags().String("output", "text", "Output format: text, json or ndjson")
 }
@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/dashboard"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	out "github.com/AaronFeledy/tyk-ops/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// groupCmd represents the group command
+var groupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Manage the user groups of a dashboard",
+	Long: `Create, update, delete and list the user groups of a dashboard, whose permissions apply to
+	every user in them. User groups are given by their ID or name. User groups can also be declared
+	in the repo's spec, so that sync manages them.`,
+}
+
+var groupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the user groups",
+	Args:  cobra.NoArgs,
+	Run:   dashboardRun(processGroupList),
+}
+
+var groupCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a user group, printing its ID to stdout",
+	Args:  cobra.ExactArgs(1),
+	Run:   dashboardRun(processGroupCreate),
+}
+
+var groupUpdateCmd = &cobra.Command{
+	Use:   "update <group>",
+	Short: "Update the name, description or permissions of a user group",
+	Long: `Update a user group, changing only the settings whose flags are set. --permission replaces
+	the group's permissions.`,
+	Args: cobra.ExactArgs(1),
+	Run:  dashboardRun(processGroupUpdate),
+}
+
+var groupDeleteCmd = &cobra.Command{
+	Use:   "delete <group>...",
+	Short: "Delete user groups",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   dashboardRun(processGroupDelete),
+}
+
+func processGroupList(cmd *cobra.Command, c *dashboard.Client, args []string) error {
+	groups, err := c.FetchUserGroups()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 1, 1, 4, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tDESCRIPTION")
+	for _, group := range groups {
+		fmt.Fprintf(w, "%v\t%v\t%v\n", group.ID, group.Name, group.Description)
+	}
+	return w.Flush()
+}
+
+func processGroupCreate(cmd *cobra.Command, c *dashboard.Client, args []string) error {
+	groups, err := c.FetchUserGroups()
+	if err != nil {
+		return err
+	}
+	if objects.FindUserGroup(groups, args[0]) != nil {
+		return fmt.Errorf("user group %v already exists, use update", args[0])
+	}
+
+	group := &objects.UserGroup{Name: args[0], OrgID: c.OrgID}
+	if err := applyGroupFlags(cmd, group); err != nil {
+		return err
+	}
+
+	id, err := c.CreateUserGroup(group)
+	if err != nil {
+		return err
+	}
+
+	out.DataWithFlair(id).Pre("Created user group: ").Post("\n").Print()
+	return nil
+}
+
+func processGroupUpdate(cmd *cobra.Command, c *dashboard.Client, args []string) error {
+	group, err := fetchUserGroup(c, args[0])
+	if err != nil {
+		return err
+	}
+
+	if cmd.Flags().Changed("name") {
+		group.Name, _ = cmd.Flags().GetString("name")
+	}
+	if err := applyGroupFlags(cmd, group); err != nil {
+		return err
+	}
+
+	if err := c.UpdateUserGroup(group); err != nil {
+		return err
+	}
+
+	out.User.Printf("Updated user group: %v\n", group.Name)
+	return nil
+}
+
+func processGroupDelete(cmd *cobra.Command, c *dashboard.Client, args []string) error {
+	for _, ref := range args {
+		group, err := fetchUserGroup(c, ref)
+		if err != nil {
+			return err
+		}
+
+		if err := c.DeleteUserGroup(group.ID); err != nil {
+			return fmt.Errorf("deleting user group %v: %v", group.Name, err)
+		}
+		out.User.Printf("Deleted user group: %v\n", group.Name)
+	}
+
+	return nil
+}
+
+// fetchUserGroup returns the user group with the given ID or name.
+func fetchUserGroup(c *dashboard.Client, ref string) (*objects.UserGroup, error) {
+	groups, err := c.FetchUserGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	group := objects.FindUserGroup(groups, ref)
+	if group == nil {
+		return nil, fmt.Errorf("user group %v was not found", ref)
+	}
+	return group, nil
+}
+
+// applyGroupFlags sets the description and permissions of a user group from the flags that have
+// been set.
+func applyGroupFlags(cmd *cobra.Command, group *objects.UserGroup) error {
+	if cmd.Flags().Changed("description") {
+		group.Description, _ = cmd.Flags().GetString("description")
+	}
+
+	if cmd.Flags().Changed("permission") {
+		perms, _ := cmd.Flags().GetStringArray("permission")
+		permissions, err := parsePermissions(perms)
+		if err != nil {
+			return err
+		}
+		group.UserPermissions = permissions
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(groupCmd)
+	for _, cmd := range []*cobra.Command{groupListCmd, groupCreateCmd, groupUpdateCmd, groupDeleteCmd} {
+		groupCmd.AddCommand(cmd)
+		addDashboardFlags(cmd)
+	}
+
+	for _, cmd := range []*cobra.Command{groupCreateCmd, groupUpdateCmd} {
+		cmd.Flags().String("description", "", "Description of the user group")
+		cmd.Flags().StringArray("permission", []string{}, "Permission of the group's users, as <object>=<access> such as apis=write, may be repeated")
+	}
+	groupUpdateCmd.Flags().String("name", "", "New name of the user group")
+}
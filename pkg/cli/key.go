@@ -0,0 +1,338 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/cli_util"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	out "github.com/AaronFeledy/tyk-ops/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// keyCmd represents the key command
+var keyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "Manage the API keys on a gateway or dashboard",
+	Long: `Create, show, update, delete and list the API keys on a gateway or dashboard. Keys can be
+	given the policies synced from a repo, by the policy's ID, or access to APIs directly.`,
+}
+
+var keyCreateCmd = &cobra.Command{
+	Use:   "create [key]",
+	Short: "Create a key, printing it to stdout",
+	Long: `Create a key with the given policies and access rights. The target generates the key unless
+	one is given. Only the key is written to stdout, so it can be captured by scripts.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  keyRun(processKeyCreate),
+}
+
+var keyGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the session of a key as JSON",
+	Args:  cobra.ExactArgs(1),
+	Run:   keyRun(processKeyGet),
+}
+
+var keyUpdateCmd = &cobra.Command{
+	Use:   "update <key>",
+	Short: "Update the policies, access rights, limits or expiry of a key",
+	Long: `Update a key, changing only the settings whose flags are set. --policy and --api replace the
+	key's policies and access rights.`,
+	Args: cobra.ExactArgs(1),
+	Run:  keyRun(processKeyUpdate),
+}
+
+var keyDeleteCmd = &cobra.Command{
+	Use:   "delete <key>...",
+	Short: "Delete keys",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   keyRun(processKeyDelete),
+}
+
+var keyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the keys, one per line on stdout",
+	Args:  cobra.NoArgs,
+	Run:   keyRun(processKeyList),
+}
+
+// keyRun returns the Run function of a key command, which connects to the target and calls process.
+// Errors are written to stderr, so that stdout only ever holds the data.
+func keyRun(process func(cmd *cobra.Command, client targetClient, args []string) error) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		applyTargetEnv(cmd)
+		verificationError := verifyArguments(cmd)
+		if verificationError != nil {
+			out.User.Errorf("%v", verificationError)
+			os.Exit(1)
+		}
+
+		client, err := newTargetClient(cmd)
+		if err == nil {
+			err = process(cmd, client, args)
+		}
+		if err != nil {
+			out.User.Errorf("%v", err)
+			os.Exit(1)
+		}
+	}
+}
+
+func processKeyCreate(cmd *cobra.Command, client targetClient, args []string) error {
+	session := &objects.Session{
+		AccessRights: map[string]objects.AccessDefinition{},
+		MetaData:     map[string]interface{}{},
+		Tags:         []string{},
+	}
+	session.ApplyPolicies = []string{}
+
+	if err := applyKeyFlags(cmd, client, session, true); err != nil {
+		return err
+	}
+
+	key := ""
+	if len(args) > 0 {
+		key = args[0]
+	}
+
+	created, err := client.CreateKey(key, session)
+	if err != nil {
+		return err
+	}
+
+	out.DataWithFlair(created).Pre("Created key: ").Post("\n").Print()
+	return nil
+}
+
+func processKeyGet(cmd *cobra.Command, client targetClient, args []string) error {
+	session, err := client.FetchKey(args[0])
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+
+	out.PrettyString(string(data))
+	return nil
+}
+
+func processKeyUpdate(cmd *cobra.Command, client targetClient, args []string) error {
+	session, err := client.FetchKey(args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := applyKeyFlags(cmd, client, session, false); err != nil {
+		return err
+	}
+
+	if err := client.UpdateKey(args[0], session); err != nil {
+		return err
+	}
+
+	out.User.Printf("Updated key: %v\n", args[0])
+	return nil
+}
+
+func processKeyDelete(cmd *cobra.Command, client targetClient, args []string) error {
+	for _, key := range args {
+		if err := client.DeleteKey(key); err != nil {
+			return fmt.Errorf("deleting key %v: %v", key, err)
+		}
+		out.User.Printf("Deleted key: %v\n", key)
+	}
+
+	return nil
+}
+
+func processKeyList(cmd *cobra.Command, client targetClient, args []string) error {
+	keys, err := client.FetchKeys()
+	if err != nil {
+		return err
+	}
+
+	out.User.Printf("Found %v keys\n", len(keys))
+	for _, key := range keys {
+		out.Data.Printf("%v\n", key)
+	}
+
+	return nil
+}
+
+// applyKeyFlags sets the settings of a session from the flags of a key command. Only the flags that
+// have been set are applied, unless all is set.
+func applyKeyFlags(cmd *cobra.Command, client targetClient, session *objects.Session, all bool) error {
+	flags := cmd.Flags()
+	set := func(name string) bool {
+		return all || flags.Changed(name)
+	}
+
+	if set("rate") {
+		session.Rate, _ = flags.GetFloat64("rate")
+	}
+	if set("per") {
+		session.Per, _ = flags.GetFloat64("per")
+	}
+	if set("quota") {
+		session.QuotaMax, _ = flags.GetInt64("quota")
+		session.QuotaRemaining = session.QuotaMax
+	}
+	if set("alias") {
+		session.Alias, _ = flags.GetString("alias")
+	}
+	if set("inactive") {
+		session.IsInactive, _ = flags.GetBool("inactive")
+	}
+	if flags.Changed("org") {
+		session.OrgID, _ = flags.GetString("org")
+	}
+
+	if set("expires") {
+		expires, _ := flags.GetString("expires")
+		expiry, err := parseExpiry(expires, time.Now())
+		if err != nil {
+			return err
+		}
+		session.Expires = expiry
+	}
+
+	if flags.Changed("api") {
+		apis, _ := flags.GetStringArray("api")
+		rights, err := parseAccessRights(apis)
+		if err != nil {
+			return err
+		}
+		session.AccessRights = rights
+	}
+
+	if flags.Changed("policy") {
+		ids, _ := flags.GetStringSlice("policy")
+		policies, orgID, err := resolvePolicies(client, ids)
+		if err != nil {
+			return err
+		}
+		session.ApplyPolicies = policies
+		if session.OrgID == "" {
+			session.OrgID = orgID
+		}
+	}
+
+	if len(session.ApplyPolicies) == 0 && len(session.AccessRights) == 0 {
+		return errors.New("a key needs a policy or access to an API, set --policy or --api")
+	}
+
+	return nil
+}
+
+// parseExpiry returns the expiry time of a key as a Unix timestamp, from a duration after now or
+// an RFC 3339 date. Keys that never expire have an expiry of 0.
+func parseExpiry(expires string, now time.Time) (int64, error) {
+	if expires == "" || expires == "never" {
+		return 0, nil
+	}
+
+	if d, err := time.ParseDuration(expires); err == nil {
+		if d <= 0 {
+			return 0, fmt.Errorf("invalid expiry %v, the duration must be positive", expires)
+		}
+		return now.Add(d).Unix(), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, expires)
+	if err != nil {
+		return 0, fmt.Errorf("invalid expiry %v, use a duration such as 720h, an RFC 3339 date or never", expires)
+	}
+	return t.Unix(), nil
+}
+
+// parseAccessRights returns the access rights given by --api flags, each an API ID optionally
+// followed by a colon and the comma-separated versions the key can access.
+func parseAccessRights(apis []string) (map[string]objects.AccessDefinition, error) {
+	rights := map[string]objects.AccessDefinition{}
+	for _, api := range apis {
+		id, versions := api, "Default"
+		if i := strings.Index(api, ":"); i >= 0 {
+			id, versions = api[:i], api[i+1:]
+		}
+		if id == "" || versions == "" {
+			return nil, fmt.Errorf("invalid API access %q, use <api id>[:<version>,...]", api)
+		}
+
+		rights[id] = objects.AccessDefinition{
+			APIID:    id,
+			Versions: strings.Split(versions, ","),
+		}
+	}
+
+	return rights, nil
+}
+
+// resolvePolicies looks up the policies with the given IDs on the target, matching the IDs the
+// policies are synced with as well as the target's own IDs. It returns the IDs the target applies
+// the policies with and the organisation they belong to.
+func resolvePolicies(client targetClient, ids []string) ([]string, string, error) {
+	pols, err := client.FetchPolicies()
+	if err != nil {
+		return nil, "", err
+	}
+
+	resolved := []string{}
+	orgID := ""
+	for _, id := range ids {
+		found := false
+		for _, pol := range pols {
+			if id != pol.ID && id != pol.MID.Hex() {
+				continue
+			}
+
+			found = true
+			if pol.ID != "" {
+				resolved = append(resolved, pol.ID)
+			} else {
+				resolved = append(resolved, pol.MID.Hex())
+			}
+			if orgID == "" {
+				orgID = pol.OrgID
+			}
+			break
+		}
+
+		if !found {
+			return nil, "", fmt.Errorf("policy %v was not found on the target, sync it first", id)
+		}
+	}
+
+	return resolved, orgID, nil
+}
+
+func init() {
+	rootCmd.AddCommand(keyCmd)
+	for _, cmd := range []*cobra.Command{keyCreateCmd, keyGetCmd, keyUpdateCmd, keyDeleteCmd, keyListCmd, keyMigrateCmd} {
+		keyCmd.AddCommand(cmd)
+		cmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
+		cmd.Flags().StringP("gateway", "g", "", "Fully qualified gateway target URL")
+		cmd.Flags().StringP("secret", "s", "", "Your API secret")
+		cmd.Flags().BoolP("insecure", "", false, "Override TLS certificate validation")
+		cli_util.AddTLSFlags(cmd)
+	}
+
+	for _, cmd := range []*cobra.Command{keyCreateCmd, keyUpdateCmd} {
+		cmd.Flags().StringSlice("policy", []string{}, "IDs of the policies to apply to the key, as synced from the repo")
+		cmd.Flags().StringArray("api", []string{}, "API the key can access, as <api id>[:<version>,...], may be repeated")
+		cmd.Flags().String("expires", "", "When the key expires, as a duration such as 720h, an RFC 3339 date or never")
+		cmd.Flags().String("alias", "", "Alias of the key, shown in analytics")
+		cmd.Flags().Bool("inactive", false, "Disable the key")
+		cmd.Flags().StringP("org", "o", "", "Org ID of the key (defaults to the org of its policies)")
+		cmd.Flags().Float64("rate", 1000, "Number of requests allowed per --per seconds")
+		cmd.Flags().Float64("per", 60, "Period of the rate limit in seconds")
+		cmd.Flags().Int64("quota", -1, "Maximum number of requests per quota period, -1 for no quota")
+	}
+}
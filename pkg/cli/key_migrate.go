@@ -0,0 +1,300 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/ops"
+	out "github.com/AaronFeledy/tyk-ops/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+var keyMigrateCmd = &cobra.Command{
+	Use:   "migrate [key]...",
+	Short: "Copy keys from the target environment to another environment",
+	Long: `Copy keys from the target environment to the environment set with --to, keeping the keys
+	themselves so that developers don't need new ones. The policies of a key are matched on the
+	destination by the explicit IDs that sync relies on, and the APIs by their API IDs, so both
+	have to be synced to the destination first. Keys that already exist on the destination are
+	updated.
+
+	Targets that hash keys only list the keys' hashes. A key that is only known by its hash can't
+	be recreated, so it is reported as not migrated, unless --regenerate-hashed is set to give it a
+	new key on the destination.
+
+	A report of the keys that were and weren't migrated is written to stdout.`,
+	Run: keyRun(processKeyMigrate),
+}
+
+// keyMigration holds what is needed to migrate keys between two targets.
+type keyMigration struct {
+	source, dest   targetClient
+	sourcePolicies []objects.Policy
+	destPolicies   []objects.Policy
+	destAPIs       map[string]*objects.APIDefinition
+}
+
+// keyMigrationResult is the outcome of migrating a key, as listed in the report.
+type keyMigrationResult struct {
+	Key    string
+	Status string
+	Detail string
+}
+
+const keyMigrationFailed = "not migrated"
+
+func processKeyMigrate(cmd *cobra.Command, source targetClient, args []string) error {
+	toName, _ := cmd.Flags().GetString("to")
+	all, _ := cmd.Flags().GetBool("all")
+	regenerate, _ := cmd.Flags().GetBool("regenerate-hashed")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	if all == (len(args) > 0) {
+		return errors.New("set either the keys to migrate or --all")
+	}
+
+	env, err := migrationEnvironment(toName)
+	if err != nil {
+		return err
+	}
+
+	serverType, _ := cmd.Flags().GetString("to-server")
+	if serverType == "" {
+		serverType = "dashboard"
+		if gw, _ := cmd.Flags().GetString("gateway"); gw != "" {
+			serverType = "gateway"
+		}
+	}
+	var server ops.Server
+	switch serverType {
+	case "dashboard":
+		server = env.Dashboard
+	case "gateway":
+		server = env.Gateway
+	default:
+		return fmt.Errorf("invalid --to-server %v, use dashboard or gateway", serverType)
+	}
+	dest, err := newServerClient(serverType, server, env.HTTP)
+	if err != nil {
+		return fmt.Errorf("environment %v: %v", toName, err)
+	}
+
+	m, err := newKeyMigration(source, dest)
+	if err != nil {
+		return err
+	}
+
+	keys := args
+	if all {
+		if keys, err = source.FetchKeys(); err != nil {
+			return err
+		}
+	}
+	out.User.Printf("Migrating %v keys to %v\n", len(keys), toName)
+
+	results := make([]keyMigrationResult, 0, len(keys))
+	failed := 0
+	for _, key := range keys {
+		result := m.migrate(key, regenerate, dryRun)
+		if result.Status == keyMigrationFailed {
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 1, 1, 4, ' ', 0)
+	fmt.Fprintln(w, "KEY\tSTATUS\tDETAIL")
+	for _, result := range results {
+		fmt.Fprintf(w, "%v\t%v\t%v\n", result.Key, result.Status, result.Detail)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%v of %v keys could not be migrated", failed, len(keys))
+	}
+	return nil
+}
+
+// migrationEnvironment returns the environment with the given name from the config file.
+func migrationEnvironment(name string) (*ops.Environment, error) {
+	if name == "" {
+		return nil, errors.New("set --to to the environment to migrate the keys to")
+	}
+	if name == cfg.Target {
+		return nil, fmt.Errorf("the keys are already in environment %v", name)
+	}
+	if cfg.Environments != nil {
+		if env, ok := (*cfg.Environments)[name]; ok && env != nil {
+			return env, nil
+		}
+	}
+	return nil, fmt.Errorf("environment %v was not found in the config file", name)
+}
+
+// newKeyMigration fetches the policies of both targets and the APIs of the destination, which the
+// keys are remapped with.
+func newKeyMigration(source, dest targetClient) (*keyMigration, error) {
+	m := &keyMigration{source: source, dest: dest, destAPIs: map[string]*objects.APIDefinition{}}
+
+	var err error
+	if m.sourcePolicies, err = source.FetchPolicies(); err != nil {
+		return nil, fmt.Errorf("fetching the source's policies: %v", err)
+	}
+	if m.destPolicies, err = dest.FetchPolicies(); err != nil {
+		return nil, fmt.Errorf("fetching the destination's policies: %v", err)
+	}
+
+	apis, err := dest.FetchAPIs()
+	if err != nil {
+		return nil, fmt.Errorf("fetching the destination's APIs: %v", err)
+	}
+	for _, api := range apis {
+		if api.APIDefinition != nil {
+			m.destAPIs[api.APIID] = api.APIDefinition
+		}
+	}
+
+	return m, nil
+}
+
+// migrate copies a key to the destination. A key that is only known by its hash is given a new key
+// when regenerate is set. Nothing is changed when dryRun is set.
+func (m *keyMigration) migrate(key string, regenerate, dryRun bool) keyMigrationResult {
+	failed := func(format string, a ...interface{}) keyMigrationResult {
+		return keyMigrationResult{Key: key, Status: keyMigrationFailed, Detail: fmt.Sprintf(format, a...)}
+	}
+
+	session, hashed, err := m.fetchSourceKey(key)
+	if err != nil {
+		return failed("not found on the source: %v", err)
+	}
+	if hashed && !regenerate {
+		return failed("only the key's hash is known, so it can't be recreated; set --regenerate-hashed to give it a new key")
+	}
+
+	if err := m.remap(session); err != nil {
+		return failed("%v", err)
+	}
+
+	if hashed {
+		if dryRun {
+			return keyMigrationResult{Key: key, Status: "would get a new key"}
+		}
+		created, err := m.dest.CreateKey("", session)
+		if err != nil {
+			return failed("creating the new key: %v", err)
+		}
+		return keyMigrationResult{Key: key, Status: "new key", Detail: created}
+	}
+
+	_, exists := m.dest.FetchKey(key)
+	switch {
+	case dryRun && exists == nil:
+		return keyMigrationResult{Key: key, Status: "would be updated"}
+	case dryRun:
+		return keyMigrationResult{Key: key, Status: "would be migrated"}
+	case exists == nil:
+		if err := m.dest.UpdateKey(key, session); err != nil {
+			return failed("updating the key: %v", err)
+		}
+		return keyMigrationResult{Key: key, Status: "updated"}
+	}
+
+	if _, err := m.dest.CreateKey(key, session); err != nil {
+		return failed("creating the key: %v", err)
+	}
+	return keyMigrationResult{Key: key, Status: "migrated"}
+}
+
+// fetchSourceKey returns the session of a key on the source, and whether the key is a hash, as
+// listed by targets that hash keys.
+func (m *keyMigration) fetchSourceKey(key string) (*objects.Session, bool, error) {
+	session, err := m.source.FetchKey(key)
+	if err == nil {
+		return session, false, nil
+	}
+
+	if session, hashErr := m.source.FetchKeyHash(key); hashErr == nil {
+		return session, true, nil
+	}
+	return nil, false, err
+}
+
+// remap rewrites the policies and access rights of a session from the source's IDs to the
+// destination's. Policies are matched by the explicit IDs that sync relies on, and APIs by their
+// API IDs.
+func (m *keyMigration) remap(session *objects.Session) error {
+	orgID := ""
+
+	policies := make([]string, 0, len(session.ApplyPolicies))
+	for _, id := range session.ApplyPolicies {
+		var source *objects.Policy
+		for i, pol := range m.sourcePolicies {
+			if id == pol.ID || id == pol.MID.Hex() {
+				source = &m.sourcePolicies[i]
+				break
+			}
+		}
+		if source == nil {
+			return fmt.Errorf("policy %v was not found on the source", id)
+		}
+		if source.ID == "" {
+			return fmt.Errorf("policy %v has no explicit ID to match it on the destination, set its id in the repo and sync it", id)
+		}
+
+		var dest *objects.Policy
+		for i, pol := range m.destPolicies {
+			if pol.ID == source.ID {
+				dest = &m.destPolicies[i]
+				break
+			}
+		}
+		if dest == nil {
+			return fmt.Errorf("policy %v was not found on the destination, sync it first", source.ID)
+		}
+
+		if dest.ID != "" {
+			policies = append(policies, dest.ID)
+		} else {
+			policies = append(policies, dest.MID.Hex())
+		}
+		if orgID == "" {
+			orgID = dest.OrgID
+		}
+	}
+
+	rights := make(map[string]objects.AccessDefinition, len(session.AccessRights))
+	for apiID, right := range session.AccessRights {
+		api, ok := m.destAPIs[apiID]
+		if !ok {
+			return fmt.Errorf("API %v was not found on the destination, sync it first", apiID)
+		}
+
+		right.APIID = api.APIID
+		right.APIName = api.Name
+		rights[api.APIID] = right
+		if orgID == "" {
+			orgID = api.OrgID
+		}
+	}
+
+	session.ApplyPolicies = policies
+	session.AccessRights = rights
+	if orgID != "" {
+		session.OrgID = orgID
+	}
+	return nil
+}
+
+func init() {
+	keyMigrateCmd.Flags().String("to", "", "Name of the environment in the config file to migrate the keys to")
+	keyMigrateCmd.Flags().String("to-server", "", "Server of the destination to migrate the keys to, dashboard or gateway (defaults to the source's)")
+	keyMigrateCmd.Flags().Bool("all", false, "Migrate all the keys listed by the source")
+	keyMigrateCmd.Flags().Bool("regenerate-hashed", false, "Give keys only known by their hash a new key on the destination")
+	keyMigrateCmd.Flags().Bool("dry-run", false, "Report what would be migrated without changing the destination")
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestParseExpiry(t *testing.T) {
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for expires, want := range map[string]int64{
+		"":                     0,
+		"never":                0,
+		"24h":                  now.Add(24 * time.Hour).Unix(),
+		"2031-01-01T00:00:00Z": time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC).Unix(),
+	} {
+		got, err := parseExpiry(expires, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, want, got, expires)
+	}
+
+	for _, expires := range []string{"-1h", "tomorrow"} {
+		_, err := parseExpiry(expires, now)
+		assert.Error(t, err, expires)
+	}
+}
+
+func TestParseAccessRights(t *testing.T) {
+	rights, err := parseAccessRights([]string{"orders", "billing:v1,v2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, map[string]objects.AccessDefinition{
+		"orders":  {APIID: "orders", Versions: []string{"Default"}},
+		"billing": {APIID: "billing", Versions: []string{"v1", "v2"}},
+	}, rights)
+
+	_, err = parseAccessRights([]string{"orders:"})
+	assert.Error(t, err)
+}
+
+func TestKeyMigration_Remap(t *testing.T) {
+	sourceMID := bson.NewObjectId()
+	destMID := bson.NewObjectId()
+	m := &keyMigration{
+		sourcePolicies: []objects.Policy{
+			{MID: sourceMID, ID: "gold"},
+			{MID: bson.NewObjectId(), ID: ""},
+		},
+		destPolicies: []objects.Policy{
+			{MID: destMID, ID: "gold", OrgID: "prod-org"},
+		},
+		destAPIs: map[string]*objects.APIDefinition{
+			"orders": {APIDefinition: apidef.APIDefinition{APIID: "orders", Name: "Orders", OrgID: "prod-org"}},
+		},
+	}
+
+	session := &objects.Session{
+		OrgID:         "staging-org",
+		ApplyPolicies: []string{sourceMID.Hex()},
+		AccessRights: map[string]objects.AccessDefinition{
+			"orders": {APIID: "orders", APIName: "Old name", Versions: []string{"Default"}},
+		},
+	}
+	if err := m.remap(session); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, []string{"gold"}, session.ApplyPolicies)
+	assert.Equal(t, "Orders", session.AccessRights["orders"].APIName)
+	assert.Equal(t, "prod-org", session.OrgID)
+
+	for _, session := range []*objects.Session{
+		{ApplyPolicies: []string{m.sourcePolicies[1].MID.Hex()}},
+		{ApplyPolicies: []string{"unknown"}},
+		{AccessRights: map[string]objects.AccessDefinition{"billing": {APIID: "billing"}}},
+	} {
+		assert.Error(t, m.remap(session))
+	}
+}
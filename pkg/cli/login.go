@@ -2,12 +2,12 @@ package cli
 
 import (
 	"fmt"
+	"github.com/AaronFeledy/tyk-ops/pkg/cli_util"
 	"github.com/AaronFeledy/tyk-ops/pkg/ops"
 	out "github.com/AaronFeledy/tyk-ops/pkg/output"
 	"github.com/containerd/console"
 	"github.com/eiannone/keyboard"
 	"github.com/fatih/color"
-	"github.com/go-resty/resty/v2"
 	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -32,6 +32,7 @@ var loginCmd = &cobra.Command{
 // loginOpt defines the flags for the `tykops login` CLI command
 func loginOpt() {
 	loginCmd.Flags().BoolP("insecure", "k", false, "Override TLS certificate validation")
+	cli_util.AddTLSFlags(loginCmd)
 	loginCmd.Flags().StringP("org", "o", "", "The ID of the organization to log in to")
 	loginCmd.Flags().StringP("user", "u", "", "The email address of the user to log in as")
 	loginCmd.Flags().StringP("secret", "s", "", "The dashboard admin auth token to use")
@@ -53,22 +54,20 @@ func cmdLogin(cmd *cobra.Command, args []string) error {
 		cfg.TargetEnv.Dashboard.Secret = secret
 	}
 
-	allowInsecure := false
-	if allowInsecure, _ = cmd.Flags().GetBool("insecure"); !allowInsecure {
-		allowInsecure = cfg.TargetEnv.Dashboard.AllowInsecure
+	server := ops.Server{
+		Type:   "dashboard",
+		Url:    cfg.TargetEnv.Dashboard.Url,
+		Secret: cfg.TargetEnv.Dashboard.Secret,
+		Proxy:  cfg.TargetEnv.Dashboard.Proxy,
 	}
+	server.SetTLS(cli_util.TLSOptions(cmd, "insecure", cfg.TargetEnv.Dashboard))
 
 	// Errors beyond this point are unlikely to be tykops syntax so don't display help/usage on error.
 	cmd.SilenceUsage = true
 
 	dashAdmin := ops.DashboardAdmin{
-		Server: ops.Server{
-			Type:          "dashboard",
-			Url:           cfg.TargetEnv.Dashboard.Url,
-			Secret:        cfg.TargetEnv.Dashboard.Secret,
-			AllowInsecure: allowInsecure,
-		},
-		Client: resty.New(),
+		Server: server,
+		HTTP:   httpOptions(),
 	}
 
 	orgId := ""
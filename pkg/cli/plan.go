@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/diff"
+	out "github.com/AaronFeledy/tyk-ops/pkg/output"
+	"github.com/fatih/color"
+)
+
+var (
+	createColor = color.New(color.FgGreen).SprintFunc()
+	updateColor = color.New(color.FgYellow).SprintFunc()
+	deleteColor = color.New(color.FgRed).SprintFunc()
+)
+
+// planCounts tallies the operations in a list of changes
+type planCounts struct {
+	create, update, unchanged, delete int
+}
+
+// printPlan renders a sync plan as a list of operations with a field level diff for every update.
+func printPlan(plan *objects.SyncPlan) error {
+	var counts planCounts
+
+	if len(plan.Policies) > 0 {
+		out.Println("Policies:")
+	}
+	for _, change := range plan.Policies {
+		pol := change.Policy()
+		id := pol.ID
+		if id == "" {
+			id = pol.MID.Hex()
+		}
+
+		changes, err := change.Diff()
+		if err != nil {
+			return err
+		}
+		printChange(&counts, change.Action, fmt.Sprintf("policy %q (id: %v)", pol.Name, id), changes)
+		emitPlanned(objects.PolicyEvent(pol, change.Action, nil), changes)
+	}
+
+	if len(plan.APIs) > 0 {
+		out.Println("APIs:")
+	}
+	for _, change := range plan.APIs {
+		def := change.Definition()
+
+		changes, err := change.Diff()
+		if err != nil {
+			return err
+		}
+		printChange(&counts, change.Action, fmt.Sprintf("api %q (api_id: %v)", def.Name, def.APIID), changes)
+		emitPlanned(objects.APIEvent(def, change.Action, nil), changes)
+	}
+
+	if len(plan.UserGroups) > 0 {
+		out.Println("User groups:")
+	}
+	for _, change := range plan.UserGroups {
+		group := change.UserGroup()
+
+		changes, err := change.Diff()
+		if err != nil {
+			return err
+		}
+		printChange(&counts, change.Action, fmt.Sprintf("user group %q", group.Name), changes)
+		emitPlanned(objects.UserGroupEvent(group, change.Action, nil), changes)
+	}
+
+	if len(plan.Users) > 0 {
+		out.Println("Users:")
+	}
+	for _, change := range plan.Users {
+		user := change.User()
+
+		changes, err := change.Diff()
+		if err != nil {
+			return err
+		}
+		label := fmt.Sprintf("user %q", user.EmailAddress)
+		if change.Action == objects.ActionDelete {
+			label += " (can't be rolled back)"
+		}
+		printChange(&counts, change.Action, label, changes)
+		emitPlanned(objects.UserEvent(user, change.Action, nil), changes)
+	}
+
+	out.Printf("\nPlan: %v to create, %v to update, %v to delete, %v unchanged.\n",
+		counts.create, counts.update, counts.delete, counts.unchanged)
+	return nil
+}
+
+func printChange(counts *planCounts, action objects.ChangeAction, label string, changes []diff.Change) {
+	switch action {
+	case objects.ActionCreate:
+		counts.create++
+		out.Printf("  %s %s\n", createColor("+ create"), label)
+	case objects.ActionDelete:
+		counts.delete++
+		out.Printf("  %s %s\n", deleteColor("- delete"), label)
+	case objects.ActionUpdate:
+		// Updates are always sent, but there is nothing to show for objects that already match
+		if len(changes) == 0 {
+			counts.unchanged++
+			return
+		}
+		counts.update++
+		out.Printf("  %s %s\n", updateColor("~ update"), label)
+		for _, c := range changes {
+			out.Printf("      %s\n", c)
+		}
+	}
+}
+
+// emitPlanned reports a change in a plan as an event that has not been applied yet. Updates that
+// change nothing are left out, as they are in the printed plan.
+func emitPlanned(e out.Event, changes []diff.Change) {
+	if e.Action == string(objects.ActionUpdate) && len(changes) == 0 {
+		return
+	}
+	e.Result = out.ResultPlanned
+	out.Emit(e, "")
+}
+
+// writePlan saves a sync plan to a file so that it can be applied later.
+func writePlan(plan *objects.SyncPlan, file string) error {
+	j, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(file, j, 0644); err != nil {
+		return err
+	}
+
+	out.Printf("Plan saved to %v, use 'apply %v' to make these changes.\n", file, file)
+	return nil
+}
+
+// readPlan loads a sync plan saved with writePlan.
+func readPlan(file string) (*objects.SyncPlan, error) {
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := objects.SyncPlan{}
+	if err := json.Unmarshal(raw, &plan); err != nil {
+		return nil, err
+	}
+
+	if plan.Version != objects.PlanVersion {
+		return nil, objects.UnsupportedPlanError
+	}
+
+	return &plan, nil
+}
@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/ops"
+	out "github.com/AaronFeledy/tyk-ops/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// defaultMaxDeletePercent is used when the config file doesn't set max_delete_percent.
+const defaultMaxDeletePercent = 50
+
+// protectPlan drops the deletes of protected objects from a sync plan.
+func protectPlan(plan *objects.SyncPlan, prune ops.Prune) *objects.SyncPlan {
+	protected := *plan
+
+	protected.APIs = []objects.APIChange{}
+	for _, change := range plan.APIs {
+		if change.Action == objects.ActionDelete && isProtected(prune.Protected, []string{change.Remote.APIID}, change.Remote.Tags, change.Remote.Name) {
+			out.Printf("--> API %v is protected and will not be deleted\n", change.Remote.APIID)
+			continue
+		}
+		protected.APIs = append(protected.APIs, change)
+	}
+
+	protected.Policies = []objects.PolicyChange{}
+	for _, change := range plan.Policies {
+		if change.Action == objects.ActionDelete && isProtected(prune.Protected, []string{change.Remote.ID, change.Remote.MID.Hex()}, change.Remote.Tags, change.Remote.Name) {
+			out.Printf("--> Policy %v is protected and will not be deleted\n", change.Remote.Name)
+			continue
+		}
+		protected.Policies = append(protected.Policies, change)
+	}
+
+	protected.UserGroups = []objects.UserGroupChange{}
+	for _, change := range plan.UserGroups {
+		if change.Action == objects.ActionDelete && isProtected(prune.Protected, []string{change.Remote.ID}, nil, change.Remote.Name) {
+			out.Printf("--> User group %v is protected and will not be deleted\n", change.Remote.Name)
+			continue
+		}
+		protected.UserGroups = append(protected.UserGroups, change)
+	}
+
+	protected.Users = []objects.UserChange{}
+	for _, change := range plan.Users {
+		if change.Action == objects.ActionDelete && isProtected(prune.Protected, []string{change.Remote.ID}, nil, change.Remote.EmailAddress) {
+			out.Printf("--> User %v is protected and will not be deleted\n", change.Remote.EmailAddress)
+			continue
+		}
+		protected.Users = append(protected.Users, change)
+	}
+
+	return &protected
+}
+
+func isProtected(protected ops.Protected, ids []string, tags []string, name string) bool {
+	for _, id := range ids {
+		if id != "" && contains(protected.IDs, id) {
+			return true
+		}
+	}
+
+	for _, tag := range tags {
+		if contains(protected.Tags, tag) {
+			return true
+		}
+	}
+
+	for _, pattern := range protected.Names {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkDeletes refuses a sync plan that deletes more APIs or policies than the config file allows,
+// unless --allow-mass-delete is set. The share of deletes is worked out from the remote objects the
+// plan manages, that is the ones it updates or deletes.
+func checkDeletes(cmd *cobra.Command, plan *objects.SyncPlan, prune ops.Prune) error {
+	if allow, _ := cmd.Flags().GetBool("allow-mass-delete"); allow {
+		return nil
+	}
+
+	var apiDeletes, apiRemotes int
+	for _, change := range plan.APIs {
+		if change.Action == objects.ActionDelete {
+			apiDeletes++
+		}
+		if change.Remote != nil {
+			apiRemotes++
+		}
+	}
+	if err := checkDeleteLimits("APIs", apiDeletes, apiRemotes, prune); err != nil {
+		return err
+	}
+
+	var policyDeletes, policyRemotes int
+	for _, change := range plan.Policies {
+		if change.Action == objects.ActionDelete {
+			policyDeletes++
+		}
+		if change.Remote != nil {
+			policyRemotes++
+		}
+	}
+	if err := checkDeleteLimits("policies", policyDeletes, policyRemotes, prune); err != nil {
+		return err
+	}
+
+	var groupDeletes, groupRemotes int
+	for _, change := range plan.UserGroups {
+		if change.Action == objects.ActionDelete {
+			groupDeletes++
+		}
+		if change.Remote != nil {
+			groupRemotes++
+		}
+	}
+	if err := checkDeleteLimits("user groups", groupDeletes, groupRemotes, prune); err != nil {
+		return err
+	}
+
+	var userDeletes, userRemotes int
+	for _, change := range plan.Users {
+		if change.Action == objects.ActionDelete {
+			userDeletes++
+		}
+		if change.Remote != nil {
+			userRemotes++
+		}
+	}
+	return checkDeleteLimits("users", userDeletes, userRemotes, prune)
+}
+
+func checkDeleteLimits(kind string, deletes, remotes int, prune ops.Prune) error {
+	if deletes == 0 {
+		return nil
+	}
+
+	if prune.MaxDeletes > 0 && deletes > prune.MaxDeletes {
+		return fmt.Errorf("sync would delete %v %v, more than the limit of %v, set --allow-mass-delete if this is intended", deletes, kind, prune.MaxDeletes)
+	}
+
+	maxPercent := prune.MaxDeletePercent
+	if maxPercent == 0 {
+		maxPercent = defaultMaxDeletePercent
+	}
+	percent := float64(deletes) / float64(remotes) * 100
+	if percent > maxPercent {
+		return fmt.Errorf("sync would delete %v of %v %v (%.0f%%), more than the limit of %v%%, set --allow-mass-delete if this is intended", deletes, remotes, kind, percent, maxPercent)
+	}
+
+	return nil
+}
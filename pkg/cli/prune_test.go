@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/ops"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProtectPlan(t *testing.T) {
+	api := func(id, name string, tags ...string) *objects.DBApiDefinition {
+		def := &objects.DBApiDefinition{APIDefinition: &objects.APIDefinition{}}
+		def.APIID = id
+		def.Name = name
+		def.Tags = tags
+		return def
+	}
+
+	plan := &objects.SyncPlan{
+		APIs: []objects.APIChange{
+			{Action: objects.ActionDelete, Remote: api("by-id", "One")},
+			{Action: objects.ActionDelete, Remote: api("by-tag", "Two", "keep")},
+			{Action: objects.ActionDelete, Remote: api("by-name", "Legacy Orders")},
+			{Action: objects.ActionDelete, Remote: api("gone", "Three")},
+			{Action: objects.ActionUpdate, Local: api("by-id", "One"), Remote: api("by-id", "One")},
+		},
+		Policies: []objects.PolicyChange{
+			{Action: objects.ActionDelete, Remote: &objects.Policy{ID: "by-id"}},
+			{Action: objects.ActionDelete, Remote: &objects.Policy{ID: "gone"}},
+		},
+	}
+
+	protected := protectPlan(plan, ops.Prune{Protected: ops.Protected{
+		IDs:   []string{"by-id"},
+		Tags:  []string{"keep"},
+		Names: []string{"Legacy *"},
+	}})
+
+	if assert.Len(t, protected.APIs, 2) {
+		assert.Equal(t, "gone", protected.APIs[0].Remote.APIID)
+		assert.Equal(t, objects.ActionUpdate, protected.APIs[1].Action, "updates of protected objects are kept")
+	}
+	if assert.Len(t, protected.Policies, 1) {
+		assert.Equal(t, "gone", protected.Policies[0].Remote.ID)
+	}
+	assert.Len(t, plan.APIs, 5, "the original plan should not change")
+}
+
+func TestCheckDeletes(t *testing.T) {
+	plan := func(deletes, updates int) *objects.SyncPlan {
+		p := &objects.SyncPlan{}
+		for i := 0; i < deletes; i++ {
+			p.APIs = append(p.APIs, objects.APIChange{Action: objects.ActionDelete, Remote: &objects.DBApiDefinition{}})
+		}
+		for i := 0; i < updates; i++ {
+			p.APIs = append(p.APIs, objects.APIChange{Action: objects.ActionUpdate, Local: &objects.DBApiDefinition{}, Remote: &objects.DBApiDefinition{}})
+		}
+		return p
+	}
+
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("allow-mass-delete", false, "")
+
+	assert.NoError(t, checkDeletes(cmd, plan(5, 5), ops.Prune{}), "half of the APIs may be deleted by default")
+	assert.Error(t, checkDeletes(cmd, plan(6, 4), ops.Prune{}))
+	assert.Error(t, checkDeletes(cmd, plan(3, 0), ops.Prune{}), "a sync from an empty repo is refused")
+	assert.NoError(t, checkDeletes(cmd, plan(3, 0), ops.Prune{MaxDeletePercent: 100}))
+	assert.Error(t, checkDeletes(cmd, plan(3, 97), ops.Prune{MaxDeletes: 2}))
+
+	_ = cmd.Flags().Set("allow-mass-delete", "true")
+	assert.NoError(t, checkDeletes(cmd, plan(3, 0), ops.Prune{}))
+}
@@ -1,9 +1,9 @@
 package cli
 
 import (
-	"fmt"
+	"github.com/AaronFeledy/tyk-ops/pkg/cli_util"
+	out "github.com/AaronFeledy/tyk-ops/pkg/output"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"os"
 )
 
@@ -14,32 +14,19 @@ var publishCmd = &cobra.Command{
 	Long: `Publish API definitions from a Git repo to a gateway or dashboard, this
 	will not update existing APIs, and if it detects a collision, will stop.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if cfg.TargetEnv != nil {
-			url := cfg.TargetEnv.Dashboard.Url
-			secret := cfg.TargetEnv.Dashboard.Secret
-			urlFlag := "dashboard"
-			serverType := viper.GetString("target-server.type")
-			if serverType == "gateway" {
-				url = cfg.TargetEnv.Gateway.Url
-				secret = cfg.TargetEnv.Gateway.Secret
-				urlFlag = "gateway"
-			}
-			if val, _ := cmd.Flags().GetString(urlFlag); val == "" {
-				cmd.Flags().Lookup(urlFlag).Value.Set(url)
-			}
-			if val, _ := cmd.Flags().GetString("secret"); val == "" {
-				cmd.Flags().Lookup("secret").Value.Set(secret)
-			}
-		}
+		setOutputFormat(cmd)
+		applyTargetEnv(cmd)
 		verificationError := verifyArguments(cmd)
 		if verificationError != nil {
-			fmt.Println(verificationError)
+			out.Println(verificationError)
+			out.Finish(cmd.Name(), verificationError)
 			os.Exit(1)
 		}
 
 		err := processPublish(cmd, args)
+		out.Finish(cmd.Name(), err)
 		if err != nil {
-			fmt.Println("Error: ", err)
+			out.Println("Error: ", err)
 			os.Exit(1)
 		}
 	},
@@ -60,4 +47,9 @@ func init() {
 	publishCmd.Flags().StringSlice("apis", []string{}, "Specific Apis ids to publish")
 	publishCmd.Flags().BoolP("skip-existing", "n", false, "Skip creating APIs if they already exist")
 	publishCmd.Flags().BoolP("insecure", "", false, "Override TLS certificate validation")
+	cli_util.AddTLSFlags(publishCmd)
+	publishCmd.Flags().String("policies-file", "", "Write gateway policies to this file instead of using the gateway's policy API")
+	publishCmd.Flags().Bool("strict", false, "Refuse to deploy if the APIs and policies refer to missing objects or share listen paths")
+	publishCmd.Flags().String("output", "text", "Output format: text, json or ndjson")
+	publishCmd.Flags().Int("parallel", 1, "Number of APIs or policies to write to the target at once")
 }
@@ -1,10 +1,10 @@
 package rest
 
 import (
-	"crypto/tls"
 	"fmt"
 	"github.com/AaronFeledy/tyk-ops/pkg/cli_util"
 	rest_client "github.com/AaronFeledy/tyk-ops/pkg/clients/rest"
+	"github.com/AaronFeledy/tyk-ops/pkg/ops"
 	"github.com/AaronFeledy/tyk-ops/pkg/output"
 	"github.com/go-resty/resty/v2"
 	"github.com/json-iterator/go"
@@ -34,6 +34,7 @@ var RestCmd = &cobra.Command{
 func restOpt() {
 	// Flags that apply to this command
 	RestCmd.Flags().BoolP("insecure", "k", false, "override TLS certificate validation")
+	cli_util.AddTLSFlags(RestCmd)
 	RestCmd.Flags().IntP("truncate", "t", 0, "truncate output to specified length")
 	RestCmd.Flags().Lookup("truncate").NoOptDefVal = "1000"
 	RestCmd.Flags().StringSliceP("headers", "H", make([]string, 0), "add headers to the request")
@@ -77,15 +78,18 @@ func runRest(cmd *cobra.Command, args []string) error {
 		reqHeaders[parts[0]] = parts[1]
 	}
 
-	client := resty.New()
-
-	allowInsecure, _ := cmd.Flags().GetBool("insecure")
-	if allowInsecure {
-		client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	// A user agent given with the headers replaces ours
+	clientConfig := rest_client.Config{}
+	for key, value := range reqHeaders {
+		if strings.EqualFold(key, "User-Agent") {
+			clientConfig.UserAgent = value
+		}
 	}
+	clientConfig.TLS = cli_util.TLSOptions(cmd, "insecure", targetServer(url))
+	clientConfig.Proxy = targetServer(url).Proxy
+	client := resty.NewWithClient(rest_client.New(clientConfig))
 
 	req := client.R().
-		SetHeader("User-Agent", rest_client.UserAgent).
 		SetHeaders(reqHeaders)
 	body, _ := cmd.Flags().GetString("body")
 	if body != "" {
@@ -169,6 +173,20 @@ func runRest(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// targetServer returns the server of the target environment that url is on, if any, so that the
+// request is made with its TLS settings.
+func targetServer(url string) ops.Server {
+	if cfg.TargetEnv == nil {
+		return ops.Server{}
+	}
+	for _, server := range []ops.Server{cfg.TargetEnv.Dashboard, cfg.TargetEnv.Gateway, cfg.TargetEnv.Mserv} {
+		if server.Url != "" && strings.HasPrefix(url, server.Url) {
+			return server
+		}
+	}
+	return ops.Server{}
+}
+
 // prepareResponse takes the response from the request and parses it into a map[string]interface{}
 func prepareResponse(cmd *cobra.Command, resp *resty.Response, outputObj *map[string]interface{}) *map[string]interface{} {
 	response := *outputObj
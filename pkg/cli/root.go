@@ -157,6 +157,7 @@ func initConfig() {
 		}
 		// Add shorthand for the target environment
 		if targetEnv {
+			cfg.Target = target
 			cfg.TargetEnv = ops.Environments[target]
 		}
 	}
@@ -3,14 +3,22 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"github.com/AaronFeledy/tyk-ops/pkg/cli_util"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/dashboard"
 	"github.com/AaronFeledy/tyk-ops/pkg/clients/examplesrepo"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/gateway"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/interfaces"
 	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	rest_client "github.com/AaronFeledy/tyk-ops/pkg/clients/rest"
+	"github.com/AaronFeledy/tyk-ops/pkg/ops"
+	out "github.com/AaronFeledy/tyk-ops/pkg/output"
 	"io/ioutil"
 	"os"
 	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	"github.com/AaronFeledy/tyk-ops/cli-publisher"
 	"github.com/AaronFeledy/tyk-ops/tyk-vcs"
@@ -18,28 +26,133 @@ import (
 
 var isGateway bool
 
-func doGitFetchCycle(getter tyk_vcs.Getter) ([]objects.DBApiDefinition, []objects.Policy, error) {
+// setOutputFormat applies the --output flag of a command, exiting if the format is unknown.
+func setOutputFormat(cmd *cobra.Command) {
+	format, _ := cmd.Flags().GetString("output")
+	if err := out.SetFormat(format); err != nil {
+		out.Println(err)
+		os.Exit(1)
+	}
+}
+
+// applyTargetEnv fills in the target URL and secret flags from the target environment in the
+// config file, unless they have been set on the command line.
+func applyTargetEnv(cmd *cobra.Command) {
+	if cfg.TargetEnv == nil {
+		return
+	}
+
+	url := cfg.TargetEnv.Dashboard.Url
+	secret := cfg.TargetEnv.Dashboard.Secret
+	urlFlag := "dashboard"
+	serverType := viper.GetString("target-server.type")
+	if serverType == "gateway" {
+		url = cfg.TargetEnv.Gateway.Url
+		secret = cfg.TargetEnv.Gateway.Secret
+		urlFlag = "gateway"
+	}
+	// Not every command can target both server types
+	if cmd.Flags().Lookup(urlFlag) == nil {
+		return
+	}
+	if val, _ := cmd.Flags().GetString(urlFlag); val == "" {
+		cmd.Flags().Lookup(urlFlag).Value.Set(url)
+	}
+	if val, _ := cmd.Flags().GetString("secret"); val == "" {
+		cmd.Flags().Lookup("secret").Value.Set(secret)
+	}
+}
+
+// httpOptions returns the HTTP settings of the target environment, which are all defaults when
+// there is no target environment.
+func httpOptions() rest_client.Options {
+	if cfg.TargetEnv == nil {
+		return rest_client.Options{}
+	}
+	return cfg.TargetEnv.HTTP
+}
+
+// targetName returns the name of the target environment selected with --target, if any.
+func targetName() string {
+	target := cfg.Target
+	if target == "" {
+		target = viper.GetString("target")
+	}
+	if target == "default" {
+		return ""
+	}
+	return target
+}
+
+// fetchSpec fetches the repo and loads its spec, set up for the given environment.
+func fetchSpec(getter tyk_vcs.Getter, environment string) (*tyk_vcs.TykSourceSpec, error) {
 	err := getter.FetchRepo()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	ts, err := getter.FetchTykSpec()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	ads, err := getter.FetchAPIDef(ts)
-	if err != nil {
-		return nil, nil, err
+	ts.Environment = environment
+	if cfg.TargetEnv != nil {
+		ts.EnvironmentVars = cfg.TargetEnv.Vars
+	}
+	if _, ok := ts.Overlays[environment]; ok {
+		out.Printf("Applying overlay: %v\n", environment)
 	}
 
-	pols, err := getter.FetchPolicies(ts)
+	return ts, nil
+}
+
+// repoData holds the objects read from a repo.
+type repoData struct {
+	APIs         []objects.DBApiDefinition
+	Policies     []objects.Policy
+	Certificates []tyk_vcs.Certificate
+	Users        []objects.User
+	UserGroups   []objects.UserGroup
+}
+
+func doGitFetchCycle(getter tyk_vcs.Getter, environment string) (*repoData, error) {
+	ts, err := fetchSpec(getter, environment)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+
+	data := &repoData{}
+	if data.APIs, err = getter.FetchAPIDef(ts); err != nil {
+		return nil, err
+	}
+
+	if data.Policies, err = getter.FetchPolicies(ts); err != nil {
+		return nil, err
+	}
+
+	if data.Certificates, err = getter.FetchCertificates(ts); err != nil {
+		return nil, err
+	}
+
+	if data.UserGroups, err = getter.FetchUserGroups(ts); err != nil {
+		return nil, err
 	}
 
-	return ads, pols, nil
+	if data.Users, err = getter.FetchUsers(ts); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// ownerName returns the owner that syncs are restricted to, from the --owner flag or the owner
+// setting in the config file.
+func ownerName(cmd *cobra.Command) string {
+	if owner, _ := cmd.Flags().GetString("owner"); owner != "" {
+		return owner
+	}
+	return viper.GetString("owner")
 }
 
 func getPublisher(cmd *cobra.Command, args []string) (tyk_vcs.Publisher, error) {
@@ -73,8 +186,12 @@ func getPublisher(cmd *cobra.Command, args []string) (tyk_vcs.Publisher, error)
 			Hostname:    dbString,
 			OrgOverride: orgOverride,
 		}
-		newDashPublisher.ClientOptions.InsecureSkipVerify, _ = cmd.Flags().GetBool("insecure")
+		newDashPublisher.SetTLS(cli_util.TLSOptions(cmd, "insecure", cfg.TargetServer("dashboard")))
 		newDashPublisher.ClientOptions.SkipExisting, _ = cmd.Flags().GetBool("skip-existing")
+		newDashPublisher.ClientOptions.Owner = ownerName(cmd)
+		newDashPublisher.ClientOptions.Parallel, _ = cmd.Flags().GetInt("parallel")
+		newDashPublisher.ClientOptions.HTTP = httpOptions()
+		newDashPublisher.ClientOptions.Proxy = cfg.TargetServer("dashboard").Proxy
 
 		return newDashPublisher, nil
 	}
@@ -99,8 +216,16 @@ func getPublisher(cmd *cobra.Command, args []string) (tyk_vcs.Publisher, error)
 			Secret:   secret,
 			Hostname: gwString,
 		}
-		newGWPublisher.ClientOptions.InsecureSkipVerify, _ = cmd.Flags().GetBool("insecure")
+		newGWPublisher.SetTLS(cli_util.TLSOptions(cmd, "insecure", cfg.TargetServer("gateway")))
 		newGWPublisher.ClientOptions.SkipExisting, _ = cmd.Flags().GetBool("skip-existing")
+		newGWPublisher.ClientOptions.Owner = ownerName(cmd)
+		newGWPublisher.ClientOptions.Parallel, _ = cmd.Flags().GetInt("parallel")
+		newGWPublisher.ClientOptions.HTTP = httpOptions()
+		newGWPublisher.ClientOptions.Proxy = cfg.TargetServer("gateway").Proxy
+		newGWPublisher.ClientOptions.PoliciesFile = cfg.TargetServer("gateway").PoliciesFile
+		if cmd.Flags().Changed("policies-file") {
+			newGWPublisher.ClientOptions.PoliciesFile, _ = cmd.Flags().GetString("policies-file")
+		}
 
 		isGateway = true
 		return newGWPublisher, nil
@@ -115,7 +240,7 @@ func getAuthAndBranch(cmd *cobra.Command, args []string) ([]byte, string) {
 	if keyFile != "" {
 		sshKey, errSsh := ioutil.ReadFile(keyFile)
 		if errSsh != nil {
-			fmt.Println("Error reading ", keyFile, " for github key:", errSsh)
+			out.Println("Error reading ", keyFile, " for github key:", errSsh)
 		}
 		auth = []byte(sshKey)
 	}
@@ -138,22 +263,31 @@ func NewGetter(cmd *cobra.Command, args []string) (tyk_vcs.Getter, error) {
 	return tyk_vcs.NewGGetter(args[0], branch, auth, subdirectoryPath)
 }
 
-func doGetData(cmd *cobra.Command, args []string) ([]objects.DBApiDefinition, []objects.Policy, error) {
+func doGetData(cmd *cobra.Command, args []string) (*repoData, error) {
 	getter, err := NewGetter(cmd, args)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	defs, pols, err := doGitFetchCycle(getter)
+	data, err := doGitFetchCycle(getter, targetName())
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	defs, pols := data.APIs, data.Policies
+
+	// Only the commands that deploy check integrity, and they check everything in the repo as the
+	// objects left out by the filters below may already be deployed
+	if cmd.Flags().Lookup("strict") != nil {
+		if err := checkIntegrity(cmd, defs, pols); err != nil {
+			return nil, err
+		}
 	}
 
 	wantedPolicies, _ := cmd.Flags().GetStringSlice("policies")
 	wantedAPIs, _ := cmd.Flags().GetStringSlice("apis")
 
 	if len(wantedAPIs) == 0 && len(wantedPolicies) == 0 {
-		return defs, pols, nil
+		return data, nil
 	}
 	filteredAPIS := []objects.DBApiDefinition{}
 	filteredPolicies := []objects.Policy{}
@@ -188,30 +322,228 @@ func doGetData(cmd *cobra.Command, args []string) ([]objects.DBApiDefinition, []
 		filteredPolicies = filteredPolicies[:newL]
 	}
 
-	return filteredAPIS, filteredPolicies, nil
+	// Users and user groups are left alone when only some APIs or policies are wanted
+	return &repoData{APIs: filteredAPIS, Policies: filteredPolicies, Certificates: data.Certificates}, nil
+}
+
+// checkIntegrity warns about references between APIs and policies that won't hold once they are
+// deployed, and fails if there are any when --strict is set.
+func checkIntegrity(cmd *cobra.Command, defs []objects.DBApiDefinition, pols []objects.Policy) error {
+	issues := tyk_vcs.CheckIntegrity(defs, pols)
+	for _, issue := range issues {
+		out.Printf("--> [WARNING] %v\n", issue)
+	}
+
+	strict, _ := cmd.Flags().GetBool("strict")
+	if strict && len(issues) > 0 {
+		return fmt.Errorf("found %v integrity issues, nothing has been deployed as --strict is set", len(issues))
+	}
+
+	return nil
+}
+
+// targetClient is a client for the dashboard or gateway that a command acts on directly.
+type targetClient interface {
+	interfaces.CertificateManagementClient
+	interfaces.KeyManagementClient
+	FetchAPIs() ([]objects.DBApiDefinition, error)
+}
+
+// newTargetClient returns a client for the dashboard or gateway set with the --dashboard or
+// --gateway flag.
+func newTargetClient(cmd *cobra.Command) (targetClient, error) {
+	if dbString, _ := cmd.Flags().GetString("dashboard"); dbString != "" {
+		secret, err := dashboardSecret(cmd)
+		if err != nil {
+			return nil, err
+		}
+
+		server := ops.Server{Url: dbString, Secret: secret, Proxy: cfg.TargetServer("dashboard").Proxy}
+		server.SetTLS(cli_util.TLSOptions(cmd, "insecure", cfg.TargetServer("dashboard")))
+		return newServerClient("dashboard", server, httpOptions())
+	}
+
+	gwString, _ := cmd.Flags().GetString("gateway")
+	secret, _ := cmd.Flags().GetString("secret")
+	if secret == "" {
+		secret = os.Getenv("TYKGIT_GW_SECRET")
+	}
+	if secret == "" {
+		return nil, errors.New("Please set TYKGIT_GW_SECRET, or set the --secret flag, to your gateway secret")
+	}
+
+	server := ops.Server{
+		Url:          gwString,
+		Secret:       secret,
+		Proxy:        cfg.TargetServer("gateway").Proxy,
+		PoliciesFile: cfg.TargetServer("gateway").PoliciesFile,
+	}
+	server.SetTLS(cli_util.TLSOptions(cmd, "insecure", cfg.TargetServer("gateway")))
+	return newServerClient("gateway", server, httpOptions())
+}
+
+// newServerClient returns a client for a dashboard or gateway, as set in an environment.
+func newServerClient(serverType string, server ops.Server, http rest_client.Options) (targetClient, error) {
+	if server.Url == "" {
+		return nil, fmt.Errorf("no %v URL is set", serverType)
+	}
+
+	if serverType == "dashboard" {
+		c, err := dashboard.NewDashboardClientProxy(server.Url, server.Secret, "", server.TLS(), server.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		c.HTTP = http
+		return c, nil
+	}
+
+	c, err := gateway.NewGatewayClient(server.Url, server.Secret)
+	if err != nil {
+		return nil, err
+	}
+	c.SetTLS(server.TLS())
+	c.HTTP = http
+	c.Proxy = server.Proxy
+	c.PoliciesFile = server.PoliciesFile
+	return c, nil
+}
+
+// resolveCertificates uploads the spec's certificates that the target doesn't have, and rewrites the
+// certificate IDs in the APIs to the target's. When dryRun is set nothing is uploaded, and the
+// certificates that would be are returned.
+func resolveCertificates(publisher tyk_vcs.Publisher, defs []objects.DBApiDefinition, certs []tyk_vcs.Certificate, dryRun bool) ([]tyk_vcs.Certificate, error) {
+	warnings, pending, err := tyk_vcs.ResolveCertificates(defs, certs, publisher, dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, warning := range warnings {
+		out.Printf("--> [WARNING] %v\n", warning)
+	}
+
+	return pending, nil
+}
+
+// uploadCertificates uploads the certificates a plan is waiting for, and points the plan's APIs at
+// them. It runs once the plan has passed its checks, so that nothing reaches the target before.
+func uploadCertificates(publisher tyk_vcs.Publisher, plan *objects.SyncPlan, certs []tyk_vcs.Certificate) error {
+	if len(certs) == 0 {
+		return nil
+	}
+
+	defs := []objects.DBApiDefinition{}
+	for _, change := range plan.APIs {
+		if change.Local != nil {
+			defs = append(defs, *change.Local)
+		}
+	}
+
+	// Warnings were given when the plan was made
+	_, _, err := tyk_vcs.ResolveCertificates(defs, certs, publisher, false)
+	return err
+}
+
+// checkRoutes looks for collisions between the listen paths of the APIs being deployed and those
+// of the APIs that will remain on the target. With a plan, remote APIs are matched to the repo's by
+// the plan, otherwise in the way publish and update match them. Collisions are reported as
+// warnings, and stop the deployment when --strict is set.
+func checkRoutes(cmd *cobra.Command, publisher tyk_vcs.Publisher, defs []objects.DBApiDefinition, plan *objects.SyncPlan) error {
+	remote, err := publisher.FetchAPIs()
+	if err != nil {
+		return err
+	}
+
+	var routes []tyk_vcs.Route
+	if plan != nil {
+		routes = tyk_vcs.PlanRoutingTable(plan, remote)
+	} else {
+		routes = tyk_vcs.PublishRoutingTable(defs, remote)
+	}
+
+	collisions := 0
+	for _, conflict := range tyk_vcs.CheckRoutes(routes) {
+		out.Printf("--> [WARNING] %v\n", conflict)
+		if !conflict.Shadowed {
+			collisions++
+		}
+	}
+
+	strict, _ := cmd.Flags().GetBool("strict")
+	if strict && collisions > 0 {
+		return fmt.Errorf("found %v listen path collisions, nothing has been deployed as --strict is set", collisions)
+	}
+
+	return nil
 }
 
 func processSync(cmd *cobra.Command, args []string) error {
-	defs, pols, err := doGetData(cmd, args)
+	data, err := doGetData(cmd, args)
 	if err != nil {
 		return err
 	}
+	defs, pols := data.APIs, data.Policies
 
 	publisher, err := getPublisher(cmd, args)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Using publisher: %v\n", publisher.Name())
+	out.Printf("Using publisher: %v\n", publisher.Name())
 
-	if len(pols) > 0 && !isGateway {
-		fmt.Println("Processing Policies...")
-		if err := publisher.SyncPolicies(pols); err != nil {
+	planOnly, _ := cmd.Flags().GetBool("plan")
+	planFile, _ := cmd.Flags().GetString("out")
+	pending, err := resolveCertificates(publisher, defs, data.Certificates, true)
+	if err != nil {
+		return err
+	}
+
+	// A saved plan can't carry the certificates, as they may hold private keys
+	if planFile != "" && len(pending) > 0 {
+		files := []string{}
+		for _, cert := range pending {
+			files = append(files, cert.File)
+		}
+		return fmt.Errorf("the target is missing certificates %v, upload them with cert upload before saving a plan", strings.Join(files, ", "))
+	}
+
+	plan, err := publisher.Plan(defs, pols)
+	if err != nil {
+		return err
+	}
+
+	if err := publisher.PlanUsers(plan, data.Users, data.UserGroups); err != nil {
+		return err
+	}
+
+	plan = protectPlan(plan, cfg.Prune)
+	if err := checkDeletes(cmd, plan, cfg.Prune); err != nil {
+		return err
+	}
+
+	if err := checkRoutes(cmd, publisher, defs, plan); err != nil {
+		return err
+	}
+
+	if planOnly || planFile != "" {
+		if err := printPlan(plan); err != nil {
 			return err
 		}
+		if planFile != "" {
+			return writePlan(plan, planFile)
+		}
+		return nil
 	}
 
-	fmt.Println("Processing APIs...")
-	if err := publisher.SyncAPIs(defs); err != nil {
+	if err := uploadCertificates(publisher, plan, pending); err != nil {
+		return err
+	}
+
+	out.Println("Processing changes...")
+	return applyPlan(publisher, plan)
+}
+
+// applyPlan applies a sync plan with the given publisher, reloading the gateway afterwards.
+func applyPlan(publisher tyk_vcs.Publisher, plan *objects.SyncPlan) error {
+	if err := publisher.Apply(plan); err != nil {
 		return err
 	}
 
@@ -225,16 +557,25 @@ func processSync(cmd *cobra.Command, args []string) error {
 }
 
 func processPublish(cmd *cobra.Command, args []string) error {
-	defs, pols, err := doGetData(cmd, args)
+	data, err := doGetData(cmd, args)
 	if err != nil {
 		return err
 	}
+	defs, pols := data.APIs, data.Policies
 
 	publisher, err := getPublisher(cmd, args)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Using publisher: %v\n", publisher.Name())
+	out.Printf("Using publisher: %v\n", publisher.Name())
+
+	if err := checkRoutes(cmd, publisher, defs, nil); err != nil {
+		return err
+	}
+
+	if _, err := resolveCertificates(publisher, defs, data.Certificates, false); err != nil {
+		return err
+	}
 
 	if "publish" == cmd.Use {
 		err = publisher.CreateAPIs(&defs)
@@ -243,28 +584,34 @@ func processPublish(cmd *cobra.Command, args []string) error {
 	}
 
 	if err != nil {
-		fmt.Printf("--> Status: FAIL, Error:%v\n", err)
-		fmt.Println("Failed to publish APIs")
+		out.Printf("--> Status: FAIL, Error:%v\n", err)
+		out.Println("Failed to publish APIs")
 		return err
 	}
 
-	if !isGateway {
+	// Policies are left alone when there are none, so that a gateway without the policy API can
+	// still have APIs published to it
+	if len(pols) > 0 {
 		if "publish" == cmd.Use {
 			err = publisher.CreatePolicies(&pols)
 		} else if "update" == cmd.Use {
 			err = publisher.UpdatePolicies(&pols)
 		}
-	} else {
-		err = publisher.Reload()
 	}
 
 	if err != nil {
-		fmt.Printf("--> Status: FAIL, Error:%v\n", err)
-		fmt.Println("Failed to publish Policies")
+		out.Printf("--> Status: FAIL, Error:%v\n", err)
+		out.Println("Failed to publish Policies")
 		return err
 	}
 
-	fmt.Println("Done")
+	if isGateway {
+		if err := publisher.Reload(); err != nil {
+			return err
+		}
+	}
+
+	out.Println("Done")
 	return nil
 }
 
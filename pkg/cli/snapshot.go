@@ -0,0 +1,313 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/AaronFeledy/tyk-ops/pkg/cli_util"
+	"os"
+	"path"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/dashboard"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/rollback"
+	"github.com/AaronFeledy/tyk-ops/pkg/ops"
+	"github.com/AaronFeledy/tyk-ops/pkg/snapshot"
+	"github.com/spf13/cobra"
+)
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot [command]",
+	Short: "Create and restore snapshots of a dashboard organisation",
+	Long: `Snapshot captures every API and policy of a dashboard organisation, the certificate IDs they
+	refer to and, when an admin secret is given, the organisation's metadata into a versioned archive
+	that can be restored later.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		_ = cmd.Help()
+	},
+}
+
+// snapshotCreateCmd represents the snapshot create command
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Save the APIs and policies of a dashboard organisation to a snapshot archive",
+	Long: `Create will fetch every API and policy from a dashboard and save them to a timestamped archive.
+	The certificates the APIs refer to are listed in the archive, but are not part of it. Set
+	--admin-secret to also capture the organisation's metadata.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		applyTargetEnv(cmd)
+		if err := processSnapshotCreate(cmd); err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// snapshotRestoreCmd represents the snapshot restore command
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore archive",
+	Short: "Restore the APIs and policies in a snapshot archive to a dashboard",
+	Long: `Restore will create the APIs and policies in a snapshot archive that are missing from a
+	dashboard, and update those that already exist. Nothing is deleted from the dashboard. Set
+	--admin-secret to also restore the organisation's metadata, if it was captured.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		applyTargetEnv(cmd)
+		if err := processSnapshotRestore(cmd, args); err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// dashboardSecret returns the dashboard secret from the --secret flag or the TYKGIT_DB_SECRET
+// environment variable, preferring the flag.
+func dashboardSecret(cmd *cobra.Command) (string, error) {
+	if secret, _ := cmd.Flags().GetString("secret"); secret != "" {
+		return secret, nil
+	}
+
+	if secret := os.Getenv("TYKGIT_DB_SECRET"); secret != "" {
+		return secret, nil
+	}
+
+	return "", errors.New("Please set TYKGIT_DB_SECRET, or set the --secret flag, to your dashboard user secret")
+}
+
+// snapshotAdmin returns a dashboard admin client when an admin secret has been given.
+func snapshotAdmin(cmd *cobra.Command, dbString string) *ops.DashboardAdmin {
+	adminSecret, _ := cmd.Flags().GetString("admin-secret")
+	if adminSecret == "" {
+		return nil
+	}
+
+	server := ops.Server{
+		Type:   "dashboard",
+		Url:    dbString,
+		Secret: adminSecret,
+		Proxy:  cfg.TargetServer("dashboard").Proxy,
+	}
+	server.SetTLS(cli_util.TLSOptions(cmd, "insecure", cfg.TargetServer("dashboard")))
+	return &ops.DashboardAdmin{
+		Server: server,
+		HTTP:   httpOptions(),
+	}
+}
+
+func processSnapshotCreate(cmd *cobra.Command) error {
+	dbString, _ := cmd.Flags().GetString("dashboard")
+	if dbString == "" {
+		return errors.New("snapshot requires a dashboard URL to be set")
+	}
+
+	secret, err := dashboardSecret(cmd)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Creating snapshot of %v\n", dbString)
+
+	c, err := dashboard.NewDashboardClientProxy(dbString, secret, "", cli_util.TLSOptions(cmd, "insecure", cfg.TargetServer("dashboard")), cfg.TargetServer("dashboard").Proxy)
+	if err != nil {
+		return err
+	}
+	c.HTTP = httpOptions()
+
+	fmt.Println("> Fetching APIs")
+	apis, err := c.FetchAPIs()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("--> Fetched %v APIs\n", len(apis))
+
+	fmt.Println("> Fetching policies")
+	policies, err := c.FetchPolicies()
+	if err != nil {
+		return err
+	}
+
+	// A bug exists which causes decoding of the access rights to break,
+	// so we should fetch individually
+	cleanPolicies := make([]objects.Policy, len(policies))
+	for i, p := range policies {
+		cp, err := c.FetchPolicy(p.MID.Hex())
+		if err != nil {
+			return err
+		}
+
+		// Make sure we retain IDs
+		if cp.ID == "" {
+			cp.ID = cp.MID.Hex()
+		}
+
+		cleanPolicies[i] = *cp
+	}
+	fmt.Printf("--> Fetched %v policies\n", len(cleanPolicies))
+
+	var org json.RawMessage
+	if admin := snapshotAdmin(cmd, dbString); admin != nil {
+		fmt.Println("> Fetching organisation")
+		if org, err = admin.GetOrganization(c.OrgID); err != nil {
+			return err
+		}
+	} else {
+		fmt.Println("> No admin secret set, organisation metadata will not be captured")
+	}
+
+	snap := snapshot.New(dbString, c.OrgID, apis, cleanPolicies, org)
+	if len(snap.Manifest.CertificateIDs) > 0 {
+		fmt.Printf("--> APIs refer to %v certificates, these are recorded but not captured\n", len(snap.Manifest.CertificateIDs))
+	}
+
+	file, _ := cmd.Flags().GetString("out")
+	if file == "" {
+		file = snap.FileName()
+	} else if info, err := os.Stat(file); err == nil && info.IsDir() {
+		file = path.Join(file, snap.FileName())
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	if err := snap.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Snapshot saved to %v\n", file)
+	return nil
+}
+
+func processSnapshotRestore(cmd *cobra.Command, args []string) error {
+	dbString, _ := cmd.Flags().GetString("dashboard")
+	if dbString == "" {
+		return errors.New("snapshot requires a dashboard URL to be set")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	snap, err := snapshot.Read(f)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restoring snapshot of %v taken at %v\n", snap.Manifest.Source, snap.Manifest.CreatedAt)
+
+	publisher, err := getPublisher(cmd, args)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Using publisher: %v\n", publisher.Name())
+
+	plan, err := publisher.Plan(snap.APIs, snap.Policies)
+	if err != nil {
+		return err
+	}
+	plan = withoutDeletes(plan)
+
+	if len(snap.Manifest.CertificateIDs) > 0 {
+		fmt.Println("The restored APIs refer to these certificates, which must exist on the dashboard:")
+		for _, id := range snap.Manifest.CertificateIDs {
+			fmt.Printf("  %v\n", id)
+		}
+	}
+
+	if err := printPlan(plan); err != nil {
+		return err
+	}
+
+	var admin *ops.DashboardAdmin
+	if snap.Manifest.HasOrg {
+		if admin = snapshotAdmin(cmd, dbString); admin != nil {
+			fmt.Printf("Organisation %v will be restored\n", snap.Manifest.OrgID)
+		} else {
+			fmt.Println("No admin secret set, organisation metadata will not be restored")
+		}
+	}
+
+	if planOnly, _ := cmd.Flags().GetBool("plan"); planOnly {
+		return nil
+	}
+
+	fmt.Println("Processing changes...")
+	journal := &rollback.Journal{}
+	if admin != nil {
+		if err := restoreOrganization(admin, snap, journal); err != nil {
+			return err
+		}
+	}
+
+	if err := applyPlan(publisher, plan); err != nil {
+		return journal.Rollback(err)
+	}
+	return nil
+}
+
+// restoreOrganization replaces the organisation's metadata with the snapshot's, recording how to
+// put the current metadata back in case the rest of the restore fails.
+func restoreOrganization(admin *ops.DashboardAdmin, snap *snapshot.Snapshot, journal *rollback.Journal) error {
+	current, err := admin.GetOrganization(snap.Manifest.OrgID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restoring organisation: %v\n", snap.Manifest.OrgID)
+	if err := admin.UpdateOrganization(snap.Manifest.OrgID, snap.Org); err != nil {
+		return err
+	}
+
+	journal.Record(fmt.Sprintf("Reverted restored organisation: %v", snap.Manifest.OrgID), func() error {
+		return admin.UpdateOrganization(snap.Manifest.OrgID, current)
+	})
+	return nil
+}
+
+// withoutDeletes returns a copy of the plan that only creates and updates objects.
+func withoutDeletes(plan *objects.SyncPlan) *objects.SyncPlan {
+	filtered := *plan
+	filtered.APIs = []objects.APIChange{}
+	for _, change := range plan.APIs {
+		if change.Action != objects.ActionDelete {
+			filtered.APIs = append(filtered.APIs, change)
+		}
+	}
+
+	filtered.Policies = []objects.PolicyChange{}
+	for _, change := range plan.Policies {
+		if change.Action != objects.ActionDelete {
+			filtered.Policies = append(filtered.Policies, change)
+		}
+	}
+
+	return &filtered
+}
+
+func init() {
+	rootCmd.AddCommand(snapshotCmd)
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+
+	for _, cmd := range []*cobra.Command{snapshotCreateCmd, snapshotRestoreCmd} {
+		cmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
+		cmd.Flags().StringP("secret", "s", "", "Your API secret")
+		cmd.Flags().String("admin-secret", "", "Dashboard admin secret, used to capture and restore the organisation's metadata")
+		cmd.Flags().BoolP("insecure", "", false, "Override TLS certificate validation")
+		cli_util.AddTLSFlags(cmd)
+	}
+
+	snapshotCreateCmd.Flags().String("out", "", "File or directory to save the snapshot to (defaults to a timestamped file in the current directory)")
+	snapshotRestoreCmd.Flags().StringP("org", "o", "", "org ID override")
+	snapshotRestoreCmd.Flags().Bool("plan", false, "Show the changes restore would make without applying them")
+}
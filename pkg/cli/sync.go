@@ -1,9 +1,9 @@
 package cli
 
 import (
-	"fmt"
+	"github.com/AaronFeledy/tyk-ops/pkg/cli_util"
+	out "github.com/AaronFeledy/tyk-ops/pkg/output"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"os"
 )
 
@@ -14,34 +14,23 @@ var syncCmd = &cobra.Command{
 	Long: `This command will synchronise an API Gateway with the contents of a Github repository, the
 	sync is one way: from the repo to the gateway, the command will not write back to the repo.
 	Sync will delete any objects in the dashboard or gateway that it cannot find in the github repo,
-	update those that it can find and create those that are missing.`,
+	update those that it can find and create those that are missing. Use --plan to review these
+	changes, including a field level diff of every update, without writing to the target, and --out
+	to save them to a file that can be applied later with the apply command.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if cfg.TargetEnv != nil {
-			url := cfg.TargetEnv.Dashboard.Url
-			secret := cfg.TargetEnv.Dashboard.Secret
-			urlFlag := "dashboard"
-			serverType := viper.GetString("target-server.type")
-			if serverType == "gateway" {
-				url = cfg.TargetEnv.Gateway.Url
-				secret = cfg.TargetEnv.Gateway.Secret
-				urlFlag = "gateway"
-			}
-			if val, _ := cmd.Flags().GetString(urlFlag); val == "" {
-				cmd.Flags().Lookup(urlFlag).Value.Set(url)
-			}
-			if val, _ := cmd.Flags().GetString("secret"); val == "" {
-				cmd.Flags().Lookup("secret").Value.Set(secret)
-			}
-		}
+		setOutputFormat(cmd)
+		applyTargetEnv(cmd)
 		verificationError := verifyArguments(cmd)
 		if verificationError != nil {
-			fmt.Println(verificationError)
+			out.Println(verificationError)
+			out.Finish(cmd.Name(), verificationError)
 			os.Exit(1)
 		}
 
 		err := processSync(cmd, args)
+		out.Finish(cmd.Name(), err)
 		if err != nil {
-			fmt.Println("Error: ", err)
+			out.Println("Error: ", err)
 			os.Exit(1)
 		}
 	},
@@ -61,4 +50,13 @@ func init() {
 	syncCmd.Flags().StringSlice("policies", []string{}, "Specific Policies ids to sync")
 	syncCmd.Flags().StringSlice("apis", []string{}, "Specific Apis ids to sync")
 	syncCmd.Flags().BoolP("insecure", "", false, "Override TLS certificate validation")
+	cli_util.AddTLSFlags(syncCmd)
+	syncCmd.Flags().String("policies-file", "", "Write gateway policies to this file instead of using the gateway's policy API")
+	syncCmd.Flags().String("owner", "", "Only manage the objects stamped with this owner, see the adopt command (defaults to owner in the config file)")
+	syncCmd.Flags().Bool("strict", false, "Refuse to deploy if the APIs and policies refer to missing objects or share listen paths")
+	syncCmd.Flags().Bool("plan", false, "Show the changes sync would make without applying them")
+	syncCmd.Flags().String("out", "", "Save the plan to a file to be applied later with the apply command")
+	syncCmd.Flags().Bool("allow-mass-delete", false, "Allow the sync to delete more objects than the prune limits in the config file")
+	syncCmd.Flags().String("output", "text", "Output format: text, json or ndjson")
+	syncCmd.Flags().Int("parallel", 1, "Number of APIs or policies to write to the target at once")
 }
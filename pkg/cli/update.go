@@ -15,9 +15,9 @@
 package cli
 
 import (
-	"fmt"
+	"github.com/AaronFeledy/tyk-ops/pkg/cli_util"
+	out "github.com/AaronFeledy/tyk-ops/pkg/output"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 	"os"
 )
 
@@ -28,32 +28,19 @@ var updateCmd = &cobra.Command{
 	Long: `Update will attempt to identify matching APIs or Policies in the target, and update those APIs
 	It will not create new ones, to do this use publish or sync.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if cfg.TargetEnv != nil {
-			url := cfg.TargetEnv.Dashboard.Url
-			secret := cfg.TargetEnv.Dashboard.Secret
-			urlFlag := "dashboard"
-			serverType := viper.GetString("target-server.type")
-			if serverType == "gateway" {
-				url = cfg.TargetEnv.Gateway.Url
-				secret = cfg.TargetEnv.Gateway.Secret
-				urlFlag = "gateway"
-			}
-			if val, _ := cmd.Flags().GetString(urlFlag); val == "" {
-				cmd.Flags().Lookup(urlFlag).Value.Set(url)
-			}
-			if val, _ := cmd.Flags().GetString("secret"); val == "" {
-				cmd.Flags().Lookup("secret").Value.Set(secret)
-			}
-		}
+		setOutputFormat(cmd)
+		applyTargetEnv(cmd)
 		verificationError := verifyArguments(cmd)
 		if verificationError != nil {
-			fmt.Println(verificationError)
+			out.Println(verificationError)
+			out.Finish(cmd.Name(), verificationError)
 			os.Exit(1)
 		}
 
 		err := processPublish(cmd, args)
+		out.Finish(cmd.Name(), err)
 		if err != nil {
-			fmt.Println("Error: ", err)
+			out.Println("Error: ", err)
 			os.Exit(1)
 		}
 	},
@@ -71,4 +58,9 @@ func init() {
 	updateCmd.Flags().StringSlice("policies", []string{}, "Specific Policies ids to update")
 	updateCmd.Flags().StringSlice("apis", []string{}, "Specific Apis ids to update")
 	updateCmd.Flags().BoolP("insecure", "", false, "Override TLS certificate validation")
+	cli_util.AddTLSFlags(updateCmd)
+	updateCmd.Flags().String("policies-file", "", "Write gateway policies to this file instead of using the gateway's policy API")
+	updateCmd.Flags().Bool("strict", false, "Refuse to deploy if the APIs and policies refer to missing objects or share listen paths")
+	updateCmd.Flags().String("output", "text", "Output format: text, json or ndjson")
+	updateCmd.Flags().Int("parallel", 1, "Number of APIs or policies to write to the target at once")
 }
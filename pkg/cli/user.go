@@ -0,0 +1,322 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/cli_util"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/dashboard"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	out "github.com/AaronFeledy/tyk-ops/pkg/output"
+	"github.com/spf13/cobra"
+)
+
+// userCmd represents the user command
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage the users of a dashboard",
+	Long: `Create, update, delete and list the users of a dashboard, and reset their passwords. Users are
+	given by their ID or email address. Users can also be declared in the repo's spec, so that sync
+	manages them.`,
+}
+
+var userListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the users",
+	Args:  cobra.NoArgs,
+	Run:   dashboardRun(processUserList),
+}
+
+var userCreateCmd = &cobra.Command{
+	Use:   "create <email>",
+	Short: "Create a user, printing its ID to stdout",
+	Long: `Create a user with the given email address. The user has no password unless
+	--password-stdin is set, one can be set later with reset-password.`,
+	Args: cobra.ExactArgs(1),
+	Run:  dashboardRun(processUserCreate),
+}
+
+var userUpdateCmd = &cobra.Command{
+	Use:   "update <user>",
+	Short: "Update the name, group, permissions or state of a user",
+	Long: `Update a user, changing only the settings whose flags are set. --permission replaces the
+	user's permissions.`,
+	Args: cobra.ExactArgs(1),
+	Run:  dashboardRun(processUserUpdate),
+}
+
+var userDeleteCmd = &cobra.Command{
+	Use:   "delete <user>...",
+	Short: "Delete users",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   dashboardRun(processUserDelete),
+}
+
+var userResetPasswordCmd = &cobra.Command{
+	Use:   "reset-password <user>",
+	Short: "Set a new password for a user, read from stdin",
+	Long: `Set a new password for a user. The password is read from the first line of stdin, so that it
+	doesn't end up in the shell's history.`,
+	Args: cobra.ExactArgs(1),
+	Run:  dashboardRun(processUserResetPassword),
+}
+
+// dashboardRun returns the Run function of a command that acts on a dashboard directly, which
+// connects to the dashboard and calls process. Errors are written to stderr, so that stdout only
+// ever holds the data.
+func dashboardRun(process func(cmd *cobra.Command, c *dashboard.Client, args []string) error) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		applyTargetEnv(cmd)
+
+		c, err := newDashboardClient(cmd)
+		if err == nil {
+			err = process(cmd, c, args)
+		}
+		if err != nil {
+			out.User.Errorf("%v", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// newDashboardClient returns a client for the dashboard set with the --dashboard flag.
+func newDashboardClient(cmd *cobra.Command) (*dashboard.Client, error) {
+	dbString, _ := cmd.Flags().GetString("dashboard")
+	if dbString == "" {
+		return nil, fmt.Errorf("%s requires a dashboard target to be set", cmd.Use)
+	}
+
+	secret, err := dashboardSecret(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := dashboard.NewDashboardClientProxy(dbString, secret, "", cli_util.TLSOptions(cmd, "insecure", cfg.TargetServer("dashboard")), cfg.TargetServer("dashboard").Proxy)
+	if err != nil {
+		return nil, err
+	}
+	c.HTTP = httpOptions()
+	return c, nil
+}
+
+func processUserList(cmd *cobra.Command, c *dashboard.Client, args []string) error {
+	users, err := c.FetchUsers()
+	if err != nil {
+		return err
+	}
+
+	groups, err := c.FetchUserGroups()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 1, 1, 4, ' ', 0)
+	fmt.Fprintln(w, "ID\tEMAIL\tNAME\tGROUP\tACTIVE")
+	for _, user := range users {
+		group := ""
+		if g := objects.FindUserGroup(groups, user.GroupID); user.GroupID != "" && g != nil {
+			group = g.Name
+		}
+		name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", user.ID, user.EmailAddress, name, group, user.Active)
+	}
+	return w.Flush()
+}
+
+func processUserCreate(cmd *cobra.Command, c *dashboard.Client, args []string) error {
+	users, err := c.FetchUsers()
+	if err != nil {
+		return err
+	}
+	if objects.FindUser(users, args[0]) != nil {
+		return fmt.Errorf("user %v already exists, use update", args[0])
+	}
+
+	user := &objects.User{EmailAddress: args[0], OrgID: c.OrgID, Active: true}
+	if err := applyUserFlags(cmd, c, user); err != nil {
+		return err
+	}
+
+	if passwordStdin, _ := cmd.Flags().GetBool("password-stdin"); passwordStdin {
+		if user.Password, err = readPassword(os.Stdin); err != nil {
+			return err
+		}
+	}
+
+	id, err := c.CreateUser(user)
+	if err != nil {
+		return err
+	}
+
+	out.DataWithFlair(id).Pre("Created user: ").Post("\n").Print()
+	return nil
+}
+
+func processUserUpdate(cmd *cobra.Command, c *dashboard.Client, args []string) error {
+	user, err := fetchUser(c, args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := applyUserFlags(cmd, c, user); err != nil {
+		return err
+	}
+
+	if err := c.UpdateUser(user); err != nil {
+		return err
+	}
+
+	out.User.Printf("Updated user: %v\n", user.EmailAddress)
+	return nil
+}
+
+func processUserDelete(cmd *cobra.Command, c *dashboard.Client, args []string) error {
+	for _, ref := range args {
+		user, err := fetchUser(c, ref)
+		if err != nil {
+			return err
+		}
+
+		if err := c.DeleteUser(user.ID); err != nil {
+			return fmt.Errorf("deleting user %v: %v", user.EmailAddress, err)
+		}
+		out.User.Printf("Deleted user: %v\n", user.EmailAddress)
+	}
+
+	return nil
+}
+
+func processUserResetPassword(cmd *cobra.Command, c *dashboard.Client, args []string) error {
+	user, err := fetchUser(c, args[0])
+	if err != nil {
+		return err
+	}
+
+	password, err := readPassword(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	if err := c.ResetUserPassword(user.ID, password); err != nil {
+		return err
+	}
+
+	out.User.Printf("Reset the password of user: %v\n", user.EmailAddress)
+	return nil
+}
+
+// fetchUser returns the user with the given ID or email address.
+func fetchUser(c *dashboard.Client, ref string) (*objects.User, error) {
+	users, err := c.FetchUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	user := objects.FindUser(users, ref)
+	if user == nil {
+		return nil, fmt.Errorf("user %v was not found", ref)
+	}
+	return user, nil
+}
+
+// applyUserFlags sets the details of a user from the flags that have been set.
+func applyUserFlags(cmd *cobra.Command, c *dashboard.Client, user *objects.User) error {
+	flags := cmd.Flags()
+
+	if flags.Changed("first-name") {
+		user.FirstName, _ = flags.GetString("first-name")
+	}
+	if flags.Changed("last-name") {
+		user.LastName, _ = flags.GetString("last-name")
+	}
+	if flags.Changed("inactive") {
+		inactive, _ := flags.GetBool("inactive")
+		user.Active = !inactive
+	}
+
+	if flags.Changed("permission") {
+		perms, _ := flags.GetStringArray("permission")
+		permissions, err := parsePermissions(perms)
+		if err != nil {
+			return err
+		}
+		user.UserPermissions = permissions
+	}
+
+	if flags.Changed("group") {
+		ref, _ := flags.GetString("group")
+		user.GroupID = ""
+		if ref != "" {
+			groups, err := c.FetchUserGroups()
+			if err != nil {
+				return err
+			}
+			group := objects.FindUserGroup(groups, ref)
+			if group == nil {
+				return fmt.Errorf("user group %v was not found", ref)
+			}
+			user.GroupID = group.ID
+		}
+	}
+
+	return nil
+}
+
+// parsePermissions returns the permissions given by --permission flags, each an object and its
+// access level, such as apis=write.
+func parsePermissions(perms []string) (map[string]string, error) {
+	permissions := map[string]string{}
+	for _, perm := range perms {
+		i := strings.Index(perm, "=")
+		if i <= 0 || i == len(perm)-1 {
+			return nil, fmt.Errorf("invalid permission %q, use <object>=<access>, such as apis=write", perm)
+		}
+		permissions[perm[:i]] = perm[i+1:]
+	}
+
+	return permissions, nil
+}
+
+// readPassword reads a password from the first line of r.
+func readPassword(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("no password was given on stdin")
+	}
+	return password, nil
+}
+
+// addDashboardFlags adds the flags that select the dashboard a command acts on.
+func addDashboardFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("dashboard", "d", "", "Fully qualified dashboard target URL")
+	cmd.Flags().StringP("secret", "s", "", "Your API secret")
+	cmd.Flags().BoolP("insecure", "", false, "Override TLS certificate validation")
+	cli_util.AddTLSFlags(cmd)
+}
+
+func init() {
+	rootCmd.AddCommand(userCmd)
+	for _, cmd := range []*cobra.Command{userListCmd, userCreateCmd, userUpdateCmd, userDeleteCmd, userResetPasswordCmd} {
+		userCmd.AddCommand(cmd)
+		addDashboardFlags(cmd)
+	}
+
+	for _, cmd := range []*cobra.Command{userCreateCmd, userUpdateCmd} {
+		cmd.Flags().String("first-name", "", "First name of the user")
+		cmd.Flags().String("last-name", "", "Last name of the user")
+		cmd.Flags().String("group", "", "Name or ID of the user group the user is in, empty for none")
+		cmd.Flags().StringArray("permission", []string{}, "Permission of the user, as <object>=<access> such as apis=write, may be repeated")
+		cmd.Flags().Bool("inactive", false, "Disable the user")
+	}
+	userCreateCmd.Flags().Bool("password-stdin", false, "Read the user's password from the first line of stdin")
+}
@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePermissions(t *testing.T) {
+	perms, err := parsePermissions([]string{"apis=write", "policies=read"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, map[string]string{"apis": "write", "policies": "read"}, perms)
+
+	for _, perm := range []string{"apis", "=write", "apis="} {
+		_, err := parsePermissions([]string{perm})
+		assert.Error(t, err, perm)
+	}
+}
+
+func TestReadPassword(t *testing.T) {
+	password, err := readPassword(strings.NewReader("s3cret pass\r\nignored\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "s3cret pass", password)
+
+	_, err = readPassword(strings.NewReader(""))
+	assert.Error(t, err)
+}
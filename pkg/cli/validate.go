@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the definitions in a github repo or file system before they are deployed",
+	Long: `Validate loads the spec and checks every API definition against the Tyk API definition
+	schema, every OAS document against the Tyk OAS schema and every policy for missing or mismatched
+	fields, reporting each problem with its file and line. The target's overlay and variables are
+	applied first, as they would be by sync. Nothing is sent to a dashboard or gateway.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		ok, err := processValidate(cmd, args)
+		if err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(1)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+	},
+}
+
+// processValidate reports the problems found in the repo's definitions, returning false if there
+// are any.
+func processValidate(cmd *cobra.Command, args []string) (bool, error) {
+	getter, err := NewGetter(cmd, args)
+	if err != nil {
+		return false, err
+	}
+
+	ts, err := fetchSpec(getter, targetName())
+	if err != nil {
+		return false, err
+	}
+
+	errs, err := getter.Validate(ts)
+	if err != nil {
+		return false, err
+	}
+
+	for _, e := range errs {
+		fmt.Println(e)
+	}
+
+	if len(errs) > 0 {
+		fmt.Printf("\n%v problems found in %v API files and %v policies\n", len(errs), len(ts.Files), len(ts.Policies))
+		return false, nil
+	}
+
+	fmt.Printf("Validated %v API files and %v policies, no problems found\n", len(ts.Files), len(ts.Policies))
+	return true, nil
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().StringP("key", "k", "", "Key file location for auth (optional)")
+	validateCmd.Flags().StringP("branch", "b", "refs/heads/master", "Branch to use (defaults to refs/heads/master)")
+	validateCmd.Flags().StringP("path", "p", "", "Source directory for definition files (optional)")
+}
@@ -10,6 +10,8 @@ type ConfigData struct {
 	Environments *map[string]*ops.Environment `mapstructure:"environments"`
 	// EnvironmentDefault is a map of available environments keyed by name.
 	EnvironmentDefault string `mapstructure:"environment_default,omitempty"`
+	// Prune limits the objects a sync may delete.
+	Prune ops.Prune `mapstructure:"prune"`
 	// Target is the name of the target environment.
 	Target string `mapstructure:"target"`
 	// TargetEnv is the target environment to act on.
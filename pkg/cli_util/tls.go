@@ -0,0 +1,48 @@
+package cli_util
+
+import (
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/rest"
+	"github.com/AaronFeledy/tyk-ops/pkg/ops"
+	"github.com/spf13/cobra"
+)
+
+// AddTLSFlags adds the flags that set the CA bundle and client certificate used to connect to a server.
+func AddTLSFlags(cmd *cobra.Command) {
+	cmd.Flags().String("ca-file", "", "PEM bundle of the certificate authorities to trust on top of the system's")
+	cmd.Flags().String("cert-file", "", "PEM client certificate to present for mutual TLS")
+	cmd.Flags().String("key-file", "", "PEM key of the client certificate")
+}
+
+// TLSOptions returns the TLS settings for connecting to server. The ones given on the command line,
+// with insecureFlag and the flags added by AddTLSFlags, replace those of the server.
+func TLSOptions(cmd *cobra.Command, insecureFlag string, server ops.Server) rest.TLS {
+	tls := server.TLS()
+	if insecure, _ := cmd.Flags().GetBool(insecureFlag); insecure {
+		tls.InsecureSkipVerify = true
+	}
+	if caFile, _ := cmd.Flags().GetString("ca-file"); caFile != "" {
+		tls.CAFile = caFile
+	}
+	if certFile, _ := cmd.Flags().GetString("cert-file"); certFile != "" {
+		tls.CertFile = certFile
+	}
+	if keyFile, _ := cmd.Flags().GetString("key-file"); keyFile != "" {
+		tls.KeyFile = keyFile
+	}
+	return tls
+}
+
+// TargetServer returns the server of the given type ("dashboard", "gateway" or "mserv") in the
+// target environment, which is empty when there is no target environment.
+func (c *ConfigData) TargetServer(serverType string) ops.Server {
+	if c.TargetEnv == nil {
+		return ops.Server{}
+	}
+	switch serverType {
+	case "gateway":
+		return c.TargetEnv.Gateway
+	case "mserv":
+		return c.TargetEnv.Mserv
+	}
+	return c.TargetEnv.Dashboard
+}
@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/pool"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/rollback"
 	"github.com/AaronFeledy/tyk-ops/pkg/output"
 	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/TykTechnologies/tyk/apidef/oas"
 	"github.com/gofrs/uuid"
 	"github.com/levigross/grequests"
 	"github.com/ongoingio/urljoin"
@@ -47,11 +50,8 @@ func (c *Client) FetchAPIs() ([]objects.DBApiDefinition, error) {
 	fullPath := urljoin.Join(c.url, endpointAPIs)
 
 	ro := &grequests.RequestOptions{
-		Params: map[string]string{"p": "-2"},
-		Headers: map[string]string{
-			"Authorization": c.secret,
-		},
-		InsecureSkipVerify: c.InsecureSkipVerify,
+		Params:     map[string]string{"p": "-2"},
+		HTTPClient: c.client(),
 	}
 
 	resp, err := grequests.Get(fullPath, ro)
@@ -76,11 +76,8 @@ func (c *Client) FetchAPI(apiID string) (objects.DBApiDefinition, error) {
 	fullPath := urljoin.Join(c.url, endpointAPIs, apiID)
 
 	ro := &grequests.RequestOptions{
-		Params: map[string]string{"p": "-2"},
-		Headers: map[string]string{
-			"Authorization": c.secret,
-		},
-		InsecureSkipVerify: c.InsecureSkipVerify,
+		Params:     map[string]string{"p": "-2"},
+		HTTPClient: c.client(),
 	}
 
 	resp, err := grequests.Get(fullPath, ro)
@@ -99,6 +96,42 @@ func (c *Client) FetchAPI(apiID string) (objects.DBApiDefinition, error) {
 	return api, nil
 }
 
+// fetchOAS returns the OAS document of an OAS API, which the API list leaves out.
+func (c *Client) fetchOAS(id string) (*oas.OAS, error) {
+	fullPath := urljoin.Join(c.url, endpointOASAPIs, id)
+
+	resp, err := grequests.Get(fullPath, &grequests.RequestOptions{HTTPClient: c.client()})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("OAS API %v Returned error: %v for %v", id, resp.String(), fullPath)
+	}
+
+	doc := &oas.OAS{}
+	if err := resp.JSON(doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// withOAS fills in the OAS document of a remote OAS API, so that it can be written back as it was
+// when a change to it is rolled back.
+func (c *Client) withOAS(api *objects.DBApiDefinition) error {
+	if !api.IsOAS || api.OAS != nil {
+		return nil
+	}
+
+	doc, err := c.fetchOAS(api.Id.Hex())
+	if err != nil {
+		return fmt.Errorf("fetching the OAS definition of API %v: %v", api.Name, err)
+	}
+	api.OAS = doc
+	return nil
+}
+
 func getAPIsIdentifiers(apiDefs *[]objects.DBApiDefinition) (map[string]*objects.DBApiDefinition, map[string]*objects.DBApiDefinition, map[string]*objects.DBApiDefinition, map[string]*objects.DBApiDefinition) {
 	apiids := make(map[string]*objects.DBApiDefinition)
 	ids := make(map[string]*objects.DBApiDefinition)
@@ -116,6 +149,9 @@ func getAPIsIdentifiers(apiDefs *[]objects.DBApiDefinition) (map[string]*objects
 	return apiids, ids, slugs, paths
 }
 
+// CreateAPIs creates APIs on the dashboard, up to c.Parallel at once. Nothing is created if any of
+// the APIs already exists, unless SkipExisting is set. Every API is attempted even if some fail,
+// the failures are returned as a *pool.MultiError.
 func (c *Client) CreateAPIs(apiDefs *[]objects.DBApiDefinition) error {
 	existingAPIs, err := c.FetchAPIs()
 	if err != nil {
@@ -125,99 +161,77 @@ func (c *Client) CreateAPIs(apiDefs *[]objects.DBApiDefinition) error {
 	apiids, ids, slugs, paths := getAPIsIdentifiers(&existingAPIs)
 
 	var existsCount int64
-	retainAPIIdList := make([]objects.DBApiDefinition, 0)
+	createAPIs := make([]objects.DBApiDefinition, 0)
 	for i := range *apiDefs {
 		apiDef := (*apiDefs)[i]
-		fmt.Printf("Creating API %v: %v\n", i, apiDef.Name)
+		output.Printf("Creating API %v: %v\n", i, apiDef.Name)
 		var existsError error
 		if thisAPI, ok := apiids[apiDef.APIID]; ok && thisAPI != nil {
-			fmt.Println("Warning: API ID Exists")
+			output.Println("Warning: API ID Exists")
 			existsError = UseUpdateError
 		} else if thisAPI, ok := ids[apiDef.Id.Hex()]; ok && thisAPI != nil {
-			fmt.Println("Warning: Object ID Exists")
+			output.Println("Warning: Object ID Exists")
 			existsError = UseUpdateError
 		} else if thisAPI, ok := slugs[apiDef.Slug]; ok && thisAPI != nil {
-			fmt.Println("Warning: Slug Exists")
+			output.Println("Warning: Slug Exists")
 			existsError = UseUpdateError
 		} else if thisAPI, ok := paths[apiDef.Proxy.ListenPath+"-"+apiDef.Domain]; ok && thisAPI != nil {
-			fmt.Println("Warning: Listen Path Exists")
+			output.Println("Warning: Listen Path Exists")
 			existsError = UseUpdateError
 		}
 
 		if existsError != nil {
 			if c.SkipExisting {
 				existsCount++
+				event := objects.APIEvent(&apiDef, objects.ActionCreate, nil)
+				event.Result = output.ResultSkipped
+				output.Emit(event, "")
 				continue
 			}
+			output.Emit(objects.APIEvent(&apiDef, objects.ActionCreate, existsError), "")
 			return existsError
 		}
 
-		// Create
-		asDBDef := &apiDef
-		c.fixDBDef(asDBDef)
-
-		data, err := json.Marshal(asDBDef)
-		if err != nil {
-			return err
-		}
-
-		fullPath := urljoin.Join(c.url, endpointAPIs)
-		createResp, err := grequests.Post(fullPath, &grequests.RequestOptions{
-			JSON: data,
-			Headers: map[string]string{
-				"Authorization": c.secret,
-			},
-			Params: map[string]string{
-				"accept_additional_properties": "true",
-			},
-			InsecureSkipVerify: c.InsecureSkipVerify,
-		})
-
-		if err != nil {
-			return err
-		}
-
-		if createResp.StatusCode != 200 {
-			return fmt.Errorf("API Returned error: %v (code: %v)", createResp.String(), createResp.StatusCode)
+		// Add the API to the existing API list, so that duplicates within apiDefs are caught too.
+		// APIs without an ID or slug yet are told apart when they are created.
+		if apiDef.APIID != "" {
+			apiids[apiDef.APIID] = &apiDef
 		}
-
-		var status APIResponse
-		if err := createResp.JSON(&status); err != nil {
-			return err
+		if apiDef.Id != "" {
+			ids[apiDef.Id.Hex()] = &apiDef
 		}
-
-		if status.Status != "OK" {
-			return fmt.Errorf("API request completed, but with error: %v", status.Message)
+		if apiDef.Slug != "" {
+			slugs[apiDef.Slug] = &apiDef
 		}
-
-		// Update apiDef with its ID before adding it to the existing APIs list.
-		apiDef.Id = model.ObjectIDHex(status.Meta)
-
-		// Create will always reset the API ID on dashboard, if we want to retain it, we must use UPDATE
-		if apiDef.APIID != "" {
-			retainAPIIdList = append(retainAPIIdList, apiDef)
-		}
-
-		// Add created API to existing API list.
-		apiids[apiDef.APIID] = &apiDef
-		ids[apiDef.Id.Hex()] = &apiDef
-		slugs[apiDef.Slug] = &apiDef
 		paths[apiDef.Proxy.ListenPath+"-"+apiDef.Domain] = &apiDef
 
-		fmt.Printf("--> Status: OK, ID:%v\n", apiDef.APIID)
+		createAPIs = append(createAPIs, apiDef)
 	}
 
-	if err := c.UpdateAPIs(&retainAPIIdList); err != nil {
+	err = pool.Run(len(createAPIs), c.Parallel, func(i int) error {
+		apiDef := createAPIs[i]
+		if _, err := c.createAPI(&apiDef); err != nil {
+			output.Emit(objects.APIEvent(&apiDef, objects.ActionCreate, err), "")
+			return fmt.Errorf("creating API %v: %v", apiDef.Name, err)
+		}
+
+		output.Emit(objects.APIEvent(&apiDef, objects.ActionCreate, nil), "--> Status: OK, ID:%v\n", apiDef.APIID)
+		return nil
+	})
+	if err != nil {
 		return err
 	}
 
 	if existsCount > 0 {
-		output.User.Println("%v APIs already exist and were skipped", existsCount)
+		output.User.Printf("%v APIs already exist and were skipped\n", existsCount)
 	}
 
 	return nil
 }
 
+// UpdateAPIs updates the matching APIs on the dashboard, up to c.Parallel at once. Nothing is
+// updated if any of the APIs can't be found. Every API is attempted even if some fail, the
+// failures are returned as a *pool.MultiError.
 func (c *Client) UpdateAPIs(apiDefs *[]objects.DBApiDefinition) error {
 	existingAPIs, err := c.FetchAPIs()
 	if err != nil {
@@ -226,9 +240,10 @@ func (c *Client) UpdateAPIs(apiDefs *[]objects.DBApiDefinition) error {
 
 	apiids, ids, slugs, paths := getAPIsIdentifiers(&existingAPIs)
 
+	updateAPIs := make([]objects.DBApiDefinition, 0, len(*apiDefs))
 	for i := range *apiDefs {
 		apiDef := (*apiDefs)[i]
-		fmt.Printf("Updating API %v: %v\n", i, apiDef.Name)
+		output.Printf("Updating API %v: %v\n", i, apiDef.Name)
 		if thisAPI, ok := apiids[apiDef.APIID]; ok && thisAPI != nil {
 			apiDef.Id = thisAPI.Id
 		} else if thisAPI, ok := ids[apiDef.Id.Hex()]; ok && thisAPI != nil {
@@ -250,79 +265,133 @@ func (c *Client) UpdateAPIs(apiDefs *[]objects.DBApiDefinition) error {
 				apiDef.Id = thisAPI.Id
 			}
 		} else {
+			output.Emit(objects.APIEvent(&apiDef, objects.ActionUpdate, UseCreateError), "")
 			return UseCreateError
 		}
 
-		// Update
-		asDBDef := &apiDef
-		c.fixDBDef(asDBDef)
+		// Add updated API to existing API list.
+		apiids[apiDef.APIID] = &apiDef
+		ids[apiDef.Id.Hex()] = &apiDef
+		slugs[apiDef.Slug] = &apiDef
+		paths[apiDef.Proxy.ListenPath+"-"+apiDef.Domain] = &apiDef
 
-		endpoint := endpointAPIs
-		var payload interface{}
-		payload = asDBDef
-		if apiDef.IsOAS {
-			endpoint = endpointOASAPIs
-			payload = asDBDef.OAS
-		}
+		updateAPIs = append(updateAPIs, apiDef)
+	}
 
-		data, err := json.Marshal(payload)
-		if err != nil {
-			return err
+	return pool.Run(len(updateAPIs), c.Parallel, func(i int) error {
+		apiDef := updateAPIs[i]
+		if err := c.putAPI(&apiDef); err != nil {
+			output.Emit(objects.APIEvent(&apiDef, objects.ActionUpdate, err), "")
+			return fmt.Errorf("updating API %v: %v", apiDef.Name, err)
 		}
 
-		updatePath := urljoin.Join(c.url, endpoint, apiDef.Id.Hex())
-		updateResp, err := grequests.Put(updatePath, &grequests.RequestOptions{
-			JSON: data,
-			Headers: map[string]string{
-				"Authorization": c.secret,
-			},
-			Params: map[string]string{
-				"accept_additional_properties": "true",
-			},
-			InsecureSkipVerify: c.InsecureSkipVerify,
-		})
+		output.Emit(objects.APIEvent(&apiDef, objects.ActionUpdate, nil), "--> Status: OK, ID:%v\n", apiDef.APIID)
+		return nil
+	})
+}
 
-		if err != nil {
-			return err
-		}
+// postAPI creates an API on the dashboard and returns its new DB ID. The dashboard always assigns
+// a new API ID on create, use putAPI afterwards to keep the original one.
+func (c *Client) postAPI(apiDef *objects.DBApiDefinition) (string, error) {
+	c.fixDBDef(apiDef)
 
-		if updateResp.StatusCode != 200 {
-			return fmt.Errorf("API updating returned error: %v", updateResp.String())
-		}
+	data, err := json.Marshal(apiDef)
+	if err != nil {
+		return "", err
+	}
 
-		var status APIResponse
-		if err := updateResp.JSON(&status); err != nil {
-			return err
-		}
+	fullPath := urljoin.Join(c.url, endpointAPIs)
+	createResp, err := grequests.Post(fullPath, &grequests.RequestOptions{
+		JSON: data,
+		Params: map[string]string{
+			"accept_additional_properties": "true",
+		},
+		HTTPClient: c.client(),
+	})
 
-		if status.Status != "OK" {
-			return fmt.Errorf("API request completed, but with error: %v", status.Message)
-		}
+	if err != nil {
+		return "", err
+	}
 
-		// Add updated API to existing API list.
-		apiids[apiDef.APIID] = &apiDef
-		ids[apiDef.Id.Hex()] = &apiDef
-		slugs[apiDef.Slug] = &apiDef
-		paths[apiDef.Proxy.ListenPath+"-"+apiDef.Domain] = &apiDef
+	if createResp.StatusCode != 200 {
+		return "", fmt.Errorf("API Returned error: %v (code: %v)", createResp.String(), createResp.StatusCode)
+	}
 
-		fmt.Printf("--> Status: OK, ID:%v\n", apiDef.APIID)
+	var status APIResponse
+	if err := createResp.JSON(&status); err != nil {
+		return "", err
 	}
 
-	return nil
+	if status.Status != "OK" {
+		return "", fmt.Errorf("API request completed, but with error: %v", status.Message)
+	}
+
+	return status.Meta, nil
 }
 
-func (c *Client) SyncAPIs(apiDefs []objects.DBApiDefinition) error {
-	deleteAPIs := []string{}
-	updateAPIs := []objects.DBApiDefinition{}
-	createAPIs := []objects.DBApiDefinition{}
+// putAPI replaces the dashboard API with the same DB ID as apiDef.
+func (c *Client) putAPI(apiDef *objects.DBApiDefinition) error {
+	c.fixDBDef(apiDef)
 
-	existingAPIs, err := c.FetchAPIs()
+	endpoint := endpointAPIs
+	var payload interface{}
+	payload = apiDef
+	if apiDef.IsOAS {
+		endpoint = endpointOASAPIs
+		payload = apiDef.OAS
+	}
+
+	data, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
 
+	updatePath := urljoin.Join(c.url, endpoint, apiDef.Id.Hex())
+	updateResp, err := grequests.Put(updatePath, &grequests.RequestOptions{
+		JSON: data,
+		Params: map[string]string{
+			"accept_additional_properties": "true",
+		},
+		HTTPClient: c.client(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if updateResp.StatusCode != 200 {
+		return fmt.Errorf("API updating returned error: %v", updateResp.String())
+	}
+
+	var status APIResponse
+	if err := updateResp.JSON(&status); err != nil {
+		return err
+	}
+
+	if status.Status != "OK" {
+		return fmt.Errorf("API request completed, but with error: %v", status.Message)
+	}
+
+	return nil
+}
+
+// PlanAPIs works out which APIs a sync would delete, update and create on the dashboard without
+// making any changes. Updates are only planned for APIs that exist in both places.
+func (c *Client) PlanAPIs(apiDefs []objects.DBApiDefinition) ([]objects.APIChange, error) {
+	existingAPIs, err := c.FetchAPIs()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.planAPIs(existingAPIs, apiDefs)
+}
+
+func (c *Client) planAPIs(existingAPIs []objects.DBApiDefinition, apiDefs []objects.DBApiDefinition) ([]objects.APIChange, error) {
+	changes := []objects.APIChange{}
+
 	DashIDMap := map[string]int{}
 	GitIDMap := map[string]int{}
+	gitKeys := make([]string, len(apiDefs))
 
 	// Build the dash ID map
 	for i, api := range existingAPIs {
@@ -337,96 +406,188 @@ func (c *Client) SyncAPIs(apiDefs []objects.DBApiDefinition) error {
 	// Build the Git ID Map
 	for i, def := range apiDefs {
 		if c.isCloud {
-			GitIDMap[def.Slug] = i
-			continue
-		}
-
-		if def.APIID != "" {
-			GitIDMap[def.APIID] = i
-			continue
+			gitKeys[i] = def.Slug
+		} else if def.APIID != "" {
+			gitKeys[i] = def.APIID
 		} else if def.Id.Hex() != "" {
 			// No API ID? Let's try the actual DB ID
-			GitIDMap[def.Id.Hex()] = i
-			continue
+			gitKeys[i] = def.Id.Hex()
 		} else {
 			uid, err := uuid.NewV4()
 			if err != nil {
-				fmt.Println("error generating UUID", err)
-				return err
+				output.Println("error generating UUID", err)
+				return nil, err
 			}
-			created := fmt.Sprintf("temp-%v", uid.String())
-			GitIDMap[created] = i
+			gitKeys[i] = fmt.Sprintf("temp-%v", uid.String())
 		}
+		GitIDMap[gitKeys[i]] = i
 	}
 
-	// Updates are when we find items in git that are also in dash
-	for key, index := range GitIDMap {
-		fmt.Printf("Checking: %v\n", key)
-		dashIndex, ok := DashIDMap[key]
-		if ok {
-			// Make sure we are targeting the correct DB ID
-			api := apiDefs[index]
-			api.Id = existingAPIs[dashIndex].Id
-			api.APIID = existingAPIs[dashIndex].APIID
-			updateAPIs = append(updateAPIs, api)
+	// Deletes are when we find items in the dash that are not in git
+	for i, api := range existingAPIs {
+		key := api.APIID
+		if c.isCloud {
+			key = api.Slug
+		}
+		if DashIDMap[key] != i {
+			continue
+		}
+		if _, ok := GitIDMap[key]; !ok {
+			remote := existingAPIs[i]
+			changes = append(changes, objects.APIChange{Action: objects.ActionDelete, Remote: &remote})
 		}
 	}
 
-	// Deletes are when we find items in the dash that are not in git
-	for key, dashIndex := range DashIDMap {
-		_, ok := GitIDMap[key]
+	// Updates are when we find items in git that are also in dash, creates are when we find things
+	// in git that are not in the dashboard
+	for i, key := range gitKeys {
+		if GitIDMap[key] != i {
+			continue
+		}
+
+		local := apiDefs[i]
+		dashIndex, ok := DashIDMap[key]
 		if !ok {
-			// Make sure we always target the DB ID
-			deleteAPIs = append(deleteAPIs, existingAPIs[dashIndex].Id.Hex())
+			changes = append(changes, objects.APIChange{Action: objects.ActionCreate, Local: &local})
+			continue
 		}
+
+		// Make sure we are targeting the correct DB ID
+		remote := existingAPIs[dashIndex]
+		local.Id = remote.Id
+		local.APIID = remote.APIID
+		changes = append(changes, objects.APIChange{Action: objects.ActionUpdate, Local: &local, Remote: &remote})
 	}
 
-	// Create operations are when we find things in Git that are not in the dashboard
-	for key, index := range GitIDMap {
-		_, ok := DashIDMap[key]
-		if !ok {
-			createAPIs = append(createAPIs, apiDefs[index])
+	return objects.ClaimAPIChanges(changes, c.Owner)
+}
+
+// SyncAPIs makes the dashboard's APIs match apiDefs. If any change fails, the changes already made
+// are rolled back and a *rollback.Error is returned.
+func (c *Client) SyncAPIs(apiDefs []objects.DBApiDefinition) error {
+	changes, err := c.PlanAPIs(apiDefs)
+	if err != nil {
+		return err
+	}
+
+	journal := &rollback.Journal{}
+	if err := c.applyAPIChanges(changes, journal); err != nil {
+		return journal.Rollback(err)
+	}
+
+	return nil
+}
+
+// applyAPIChanges makes the planned API changes, recording how to revert each one in the journal.
+// Deletes, updates and creates are made in that order, up to c.Parallel at once. Every change of a
+// kind is attempted even if some fail, but the next kind is only started if they all succeeded.
+func (c *Client) applyAPIChanges(changes []objects.APIChange, journal *rollback.Journal) error {
+	deleteAPIs := []objects.DBApiDefinition{}
+	updateAPIs := []objects.APIChange{}
+	createAPIs := []objects.DBApiDefinition{}
+
+	for _, change := range changes {
+		switch change.Action {
+		case objects.ActionDelete:
+			deleteAPIs = append(deleteAPIs, *change.Remote)
+		case objects.ActionUpdate:
+			updateAPIs = append(updateAPIs, change)
+		case objects.ActionCreate:
+			createAPIs = append(createAPIs, *change.Local)
 		}
 	}
 
-	fmt.Printf("Deleting: %v\n", len(deleteAPIs))
-	fmt.Printf("Updating: %v\n", len(updateAPIs))
-	fmt.Printf("Creating: %v\n", len(createAPIs))
+	output.Printf("Deleting: %v\n", len(deleteAPIs))
+	output.Printf("Updating: %v\n", len(updateAPIs))
+	output.Printf("Creating: %v\n", len(createAPIs))
 
 	// Do the deletes
-	for _, dbId := range deleteAPIs {
-		fmt.Printf("SYNC Deleting: %v\n", dbId)
-		if err := c.DeleteAPI(dbId); err != nil {
+	err := pool.Run(len(deleteAPIs), c.Parallel, func(i int) error {
+		// Make sure we always target the DB ID
+		remote := deleteAPIs[i]
+		if err := c.withOAS(&remote); err != nil {
+			output.Emit(objects.APIEvent(&remote, objects.ActionDelete, err), "")
 			return err
 		}
+		if err := c.DeleteAPI(remote.Id.Hex()); err != nil {
+			output.Emit(objects.APIEvent(&remote, objects.ActionDelete, err), "")
+			return fmt.Errorf("deleting API %v: %v", remote.Name, err)
+		}
+		journal.Record(fmt.Sprintf("Restored deleted API: %v", remote.Name), func() error {
+			_, err := c.createAPI(&remote)
+			return err
+		})
+		output.Emit(objects.APIEvent(&remote, objects.ActionDelete, nil), "SYNC Deleted: %v\n", remote.Id.Hex())
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	// Do the updates
-	if err := c.UpdateAPIs(&updateAPIs); err != nil {
+	err = pool.Run(len(updateAPIs), c.Parallel, func(i int) error {
+		local, remote := *updateAPIs[i].Local, *updateAPIs[i].Remote
+		if err := c.withOAS(&remote); err != nil {
+			output.Emit(objects.APIEvent(&local, objects.ActionUpdate, err), "")
+			return err
+		}
+		if err := c.putAPI(&local); err != nil {
+			output.Emit(objects.APIEvent(&local, objects.ActionUpdate, err), "")
+			return fmt.Errorf("updating API %v: %v", local.Name, err)
+		}
+		journal.Record(fmt.Sprintf("Reverted updated API: %v", remote.Name), func() error {
+			return c.putAPI(&remote)
+		})
+		output.Emit(objects.APIEvent(&local, objects.ActionUpdate, nil), "SYNC Updated: %v\n", local.Id.Hex())
+		return nil
+	})
+	if err != nil {
 		return err
 	}
-	for _, apiDef := range updateAPIs {
-		fmt.Printf("SYNC Updated: %v\n", apiDef.Id.Hex())
-	}
 
 	// Do the creates
-	if err := c.CreateAPIs(&createAPIs); err != nil {
-		return err
+	return pool.Run(len(createAPIs), c.Parallel, func(i int) error {
+		local := createAPIs[i]
+		id, err := c.createAPI(&local)
+		if id != "" {
+			journal.Record(fmt.Sprintf("Removed created API: %v", local.Name), func() error {
+				return c.DeleteAPI(id)
+			})
+		}
+		if err != nil {
+			output.Emit(objects.APIEvent(&local, objects.ActionCreate, err), "")
+			return fmt.Errorf("creating API %v: %v", local.Name, err)
+		}
+		output.Emit(objects.APIEvent(&local, objects.ActionCreate, nil), "SYNC Created: %v\n", local.Name)
+		return nil
+	})
+}
+
+// createAPI creates an API on the dashboard, keeping its API ID if it has one. The new DB ID is
+// returned whenever the API was created, even if keeping the API ID failed.
+func (c *Client) createAPI(apiDef *objects.DBApiDefinition) (string, error) {
+	apiID := apiDef.APIID
+	id, err := c.postAPI(apiDef)
+	if err != nil {
+		return "", err
 	}
-	for _, apiDef := range createAPIs {
-		fmt.Printf("SYNC Created: %v\n", apiDef.Name)
+
+	// Create will always reset the API ID on dashboard, if we want to retain it, we must use UPDATE
+	apiDef.Id = model.ObjectIDHex(id)
+	if apiID != "" {
+		apiDef.APIID = apiID
+		if err := c.putAPI(apiDef); err != nil {
+			return id, err
+		}
 	}
 
-	return nil
+	return id, nil
 }
 
 func (c *Client) DeleteAPI(id string) error {
 	delPath := urljoin.Join(c.url, endpointAPIs, id)
 	delResp, err := grequests.Delete(delPath, &grequests.RequestOptions{
-		Headers: map[string]string{
-			"Authorization": c.secret,
-		},
-		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient: c.client(),
 	})
 
 	if err != nil {
@@ -0,0 +1,281 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/pool"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/rollback"
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestDef(apiID, name, target string) objects.DBApiDefinition {
+	def := objects.DBApiDefinition{APIDefinition: &objects.APIDefinition{}}
+	def.APIID = apiID
+	def.Name = name
+	def.Proxy.TargetURL = target
+	return def
+}
+
+func TestClient_PlanAPIs(t *testing.T) {
+	remoteKept := newTestDef("kept", "Kept", "http://old")
+	remoteKept.Id = model.NewObjectID()
+	remoteGone := newTestDef("gone", "Gone", "http://gone")
+	remoteGone.Id = model.NewObjectID()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(APISResponse{Apis: []objects.DBApiDefinition{remoteKept, remoteGone}})
+	}))
+	defer server.Close()
+
+	c, err := NewDashboardClient(server.URL, "secret", "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := c.PlanAPIs([]objects.DBApiDefinition{
+		newTestDef("kept", "Kept", "http://new"),
+		newTestDef("new", "New", "http://new"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !assert.Len(t, changes, 3) {
+		return
+	}
+
+	assert.Equal(t, objects.ActionDelete, changes[0].Action)
+	assert.Equal(t, "gone", changes[0].Remote.APIID)
+
+	assert.Equal(t, objects.ActionUpdate, changes[1].Action)
+	assert.Equal(t, remoteKept.Id, changes[1].Local.Id, "updates should target the remote DB ID")
+	diffs, err := changes[1].Diff()
+	assert.NoError(t, err)
+	if assert.Len(t, diffs, 1) {
+		assert.Equal(t, "api_definition.proxy.target_url", diffs[0].Path)
+	}
+
+	assert.Equal(t, objects.ActionCreate, changes[2].Action)
+	assert.Equal(t, "new", changes[2].Local.APIID)
+}
+
+func TestClient_PlanAPIs_Owner(t *testing.T) {
+	ours := newTestDef("ours", "Ours", "http://old")
+	ours.SetOwner("team-a")
+	theirs := newTestDef("theirs", "Theirs", "http://theirs")
+	theirs.SetOwner("team-b")
+	unmanaged := newTestDef("unmanaged", "Unmanaged", "http://unmanaged")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(APISResponse{Apis: []objects.DBApiDefinition{ours, theirs, unmanaged}})
+	}))
+	defer server.Close()
+
+	c, err := NewDashboardClient(server.URL, "secret", "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Owner = "team-a"
+
+	t.Run("only owned objects are deleted", func(t *testing.T) {
+		changes, err := c.PlanAPIs([]objects.DBApiDefinition{newTestDef("new", "New", "http://new")})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !assert.Len(t, changes, 2) {
+			return
+		}
+		assert.Equal(t, objects.ActionDelete, changes[0].Action)
+		assert.Equal(t, "ours", changes[0].Remote.APIID)
+		assert.Equal(t, objects.ActionCreate, changes[1].Action)
+		assert.Equal(t, "team-a", changes[1].Local.Owner(), "created objects should be stamped")
+	})
+
+	t.Run("updates of objects owned by others are refused", func(t *testing.T) {
+		for _, apiID := range []string{"theirs", "unmanaged"} {
+			_, err := c.PlanAPIs([]objects.DBApiDefinition{newTestDef(apiID, "Mine", "http://mine")})
+			assert.IsType(t, &objects.NotOwnedError{}, err, apiID)
+		}
+	})
+
+	t.Run("stamping doesn't change the repo's definitions", func(t *testing.T) {
+		local := newTestDef("ours", "Ours", "http://new")
+		changes, err := c.PlanAPIs([]objects.DBApiDefinition{local})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if assert.Len(t, changes, 1) {
+			assert.Equal(t, "team-a", changes[0].Local.Owner())
+		}
+		assert.Empty(t, local.Owner())
+	})
+}
+
+func TestClient_SyncAPIs_Rollback(t *testing.T) {
+	remoteKept := newTestDef("kept", "Kept", "http://old")
+	remoteKept.Id = model.NewObjectID()
+	remoteGone := newTestDef("gone", "Gone", "http://gone")
+	remoteGone.Id = model.NewObjectID()
+	restoredID := model.NewObjectID()
+
+	var requests []string
+	puts := map[string]string{}
+	posts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method {
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode(APISResponse{Apis: []objects.DBApiDefinition{remoteKept, remoteGone}})
+		case http.MethodPost:
+			posts++
+			// The first create is the new API, which fails. The second is the deleted API being restored.
+			if posts == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(APIResponse{Status: "OK", Meta: restoredID.Hex()})
+		case http.MethodPut:
+			def := objects.DBApiDefinition{}
+			_ = json.NewDecoder(r.Body).Decode(&def)
+			puts[r.URL.Path] = def.Proxy.TargetURL
+			_ = json.NewEncoder(w).Encode(APIResponse{Status: "OK"})
+		default:
+			_ = json.NewEncoder(w).Encode(APIResponse{Status: "OK"})
+		}
+	}))
+	defer server.Close()
+
+	c, err := NewDashboardClient(server.URL, "secret", "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.SyncAPIs([]objects.DBApiDefinition{
+		newTestDef("kept", "Kept", "http://new"),
+		newTestDef("new", "New", "http://new"),
+	})
+
+	rbErr, ok := err.(*rollback.Error)
+	if !ok {
+		t.Fatalf("expected a rollback error, got %v", err)
+	}
+	assert.Len(t, rbErr.RolledBack, 2)
+	assert.Empty(t, rbErr.Failed)
+
+	assert.Contains(t, requests, "DELETE "+endpointAPIs+"/"+remoteGone.Id.Hex())
+	assert.Equal(t, "http://old", puts[endpointAPIs+"/"+remoteKept.Id.Hex()], "updated API should be reverted")
+	assert.Equal(t, "http://gone", puts[endpointAPIs+"/"+restoredID.Hex()], "deleted API should be restored with its API ID")
+}
+
+func TestClient_SyncAPIs_RollbackOAS(t *testing.T) {
+	const oldDoc = `{"openapi": "3.0.3", "info": {"title": "Kept v1", "version": "1"}, "paths": {}}`
+
+	// The API list leaves out the OAS document
+	remote := newTestDef("kept", "Kept", "")
+	remote.Id = model.NewObjectID()
+	remote.IsOAS = true
+
+	local := newTestDef("kept", "Kept", "")
+	local.IsOAS = true
+	local.OAS = &oas.OAS{}
+	if err := json.Unmarshal([]byte(`{"openapi": "3.0.3", "info": {"title": "Kept v2", "version": "1"}, "paths": {}}`), local.OAS); err != nil {
+		t.Fatal(err)
+	}
+
+	oasPath := endpointOASAPIs + "/" + remote.Id.Hex()
+	titles := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == oasPath:
+			_, _ = w.Write([]byte(oldDoc))
+		case r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(APISResponse{Apis: []objects.DBApiDefinition{remote}})
+		case r.Method == http.MethodPut && r.URL.Path == oasPath:
+			doc := struct {
+				Info struct {
+					Title string `json:"title"`
+				} `json:"info"`
+			}{}
+			_ = json.NewDecoder(r.Body).Decode(&doc)
+			titles = append(titles, doc.Info.Title)
+			_ = json.NewEncoder(w).Encode(APIResponse{Status: "OK"})
+		case r.Method == http.MethodPost:
+			// The new API fails to be created, so the update is rolled back
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			_ = json.NewEncoder(w).Encode(APIResponse{Status: "OK"})
+		}
+	}))
+	defer server.Close()
+
+	c, err := NewDashboardClient(server.URL, "secret", "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.SyncAPIs([]objects.DBApiDefinition{local, newTestDef("new", "New", "http://new")})
+
+	rbErr, ok := err.(*rollback.Error)
+	if !ok {
+		t.Fatalf("expected a rollback error, got %v", err)
+	}
+	assert.Empty(t, rbErr.Failed)
+	assert.Equal(t, []string{"Kept v2", "Kept v1"}, titles, "the OAS document should be written back")
+}
+
+func TestClient_CreateAPIs_Parallel(t *testing.T) {
+	var mu sync.Mutex
+	posted := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode(APISResponse{})
+		case http.MethodPost:
+			def := objects.DBApiDefinition{}
+			_ = json.NewDecoder(r.Body).Decode(&def)
+			mu.Lock()
+			posted[def.Name] = true
+			mu.Unlock()
+			if def.Name == "Broken" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(APIResponse{Status: "OK", Meta: model.NewObjectID().Hex()})
+		default:
+			_ = json.NewEncoder(w).Encode(APIResponse{Status: "OK"})
+		}
+	}))
+	defer server.Close()
+
+	c, err := NewDashboardClient(server.URL, "secret", "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Parallel = 3
+
+	defs := []objects.DBApiDefinition{}
+	for _, name := range []string{"One", "Two", "Broken", "Four", "Five", "Six"} {
+		def := newTestDef(name, name, "http://"+name)
+		def.Proxy.ListenPath = "/" + name + "/"
+		defs = append(defs, def)
+	}
+
+	err = c.CreateAPIs(&defs)
+
+	multi, ok := err.(*pool.MultiError)
+	if !ok {
+		t.Fatalf("expected a *pool.MultiError, got %v", err)
+	}
+	assert.Len(t, multi.Errors, 1)
+	assert.Contains(t, err.Error(), "Broken")
+	assert.Len(t, posted, 6, "every API should be created despite the failure")
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/levigross/grequests"
 	"github.com/ongoingio/urljoin"
 	"io"
 	"io/ioutil"
@@ -30,11 +31,16 @@ func (c *Client) CreateCertificate(cert []byte) (string, error) {
 	}
 
 	req, err := http.NewRequest("POST", fullPath, body)
+	if err != nil {
+		return "", err
+	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("Authorization", c.secret)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
 
 	rBody, _ := ioutil.ReadAll(resp.Body)
 	if resp.StatusCode != 200 {
@@ -52,3 +58,68 @@ func (c *Client) CreateCertificate(cert []byte) (string, error) {
 
 	return dbResp.Id, nil
 }
+
+// FetchCertificates returns the metadata of every certificate stored on the dashboard.
+func (c *Client) FetchCertificates() ([]objects.CertificateMeta, error) {
+	fullPath := urljoin.Join(c.url, endpointCerts)
+
+	resp, err := grequests.Get(fullPath, &grequests.RequestOptions{
+		Params:     map[string]string{"mode": "detailed", "p": "-2"},
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	certs := objects.CertificateList{}
+	if err := resp.JSON(&certs); err != nil {
+		return nil, err
+	}
+
+	return certs.Certs, nil
+}
+
+// FetchCertificate returns the metadata of the certificate with the given ID.
+func (c *Client) FetchCertificate(id string) (*objects.CertificateMeta, error) {
+	fullPath := urljoin.Join(c.url, endpointCerts, id)
+
+	resp, err := grequests.Get(fullPath, &grequests.RequestOptions{
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	cert := objects.CertificateMeta{}
+	if err := resp.JSON(&cert); err != nil {
+		return nil, err
+	}
+
+	return &cert, nil
+}
+
+// DeleteCertificate removes the certificate with the given ID from the dashboard.
+func (c *Client) DeleteCertificate(id string) error {
+	fullPath := urljoin.Join(c.url, endpointCerts, id)
+
+	resp, err := grequests.Delete(fullPath, &grequests.RequestOptions{
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	return nil
+}
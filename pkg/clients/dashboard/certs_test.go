@@ -0,0 +1,56 @@
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Certificates(t *testing.T) {
+	const meta = `{"id":"org1abc","fingerprint":"abc","has_private":false,"subject":{"CommonName":"orders.internal"},
+		"not_after":"2030-01-02T03:04:05Z","dns_names":["orders.internal","orders"],"is_ca":false}`
+	deleted := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == endpointCerts:
+			assert.Equal(t, "detailed", r.URL.Query().Get("mode"))
+			_, _ = w.Write([]byte(`{"certs":[` + meta + `],"pages":1}`))
+		case r.Method == http.MethodGet && r.URL.Path == endpointCerts+"/org1abc":
+			_, _ = w.Write([]byte(meta))
+		case r.Method == http.MethodDelete:
+			deleted = r.URL.Path
+			_, _ = w.Write([]byte(`{"Status":"OK"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := &Client{url: server.URL, secret: "s3cret"}
+
+	certs, err := c.FetchCertificates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) != 1 {
+		t.Fatalf("expected 1 certificate, got %v", len(certs))
+	}
+	assert.Equal(t, "orders.internal", certs[0].Subject.CommonName)
+	assert.Equal(t, []string{"orders.internal", "orders"}, certs[0].DNSNames)
+	assert.Equal(t, time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC), certs[0].NotAfter)
+
+	cert, err := c.FetchCertificate("org1abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "abc", cert.Fingerprint)
+
+	_, err = c.FetchCertificate("missing")
+	assert.Error(t, err)
+
+	assert.NoError(t, c.DeleteCertificate("org1abc"))
+	assert.Equal(t, endpointCerts+"/org1abc", deleted)
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/rest"
+	"net/http"
 	"strings"
 
 	"github.com/levigross/grequests"
@@ -11,21 +13,40 @@ import (
 )
 
 type Client struct {
-	url                string
-	secret             string
-	isCloud            bool
-	InsecureSkipVerify bool
-	OrgID              string
+	url     string
+	secret  string
+	isCloud bool
+	OrgID   string
+	// TLS sets how the TLS connections to the dashboard are made.
+	rest.TLS
 	// Skip creating APIs if they already exist
 	SkipExisting bool
+	// Owner restricts syncs to the objects stamped with this owner, and stamps the objects they
+	// create or update. Syncs manage every object when it is empty.
+	Owner string
+	// Parallel is the number of objects created, updated or deleted at once, they are handled one at a
+	// time when it is 1 or less.
+	Parallel int
+	// HTTP sets the timeouts, retries and rate limit of the requests made to the dashboard.
+	HTTP rest.Options
+	// Proxy is the URL of the proxy the requests to the dashboard are sent through, the proxy environment
+	// variables are used when it is empty.
+	Proxy string
+
+	httpClient rest.Lazy
 }
 
 const (
-	endpointAPIs     string = "/api/apis"
-	endpointOASAPIs  string = "/api/apis/oas"
-	endpointPolicies string = "/api/portal/policies"
-	endpointCerts    string = "/api/certs"
-	endpointUsers    string = "/api/users"
+	endpointAPIs       string = "/api/apis"
+	endpointOASAPIs    string = "/api/apis/oas"
+	endpointPolicies   string = "/api/portal/policies"
+	endpointCerts      string = "/api/certs"
+	endpointUsers      string = "/api/users"
+	endpointUserGroups string = "/api/usergroups"
+	endpointKeys       string = "/api/keys"
+
+	// authHeader is the header the dashboard secret is sent in
+	authHeader = "Authorization"
 )
 
 var (
@@ -35,20 +56,32 @@ var (
 )
 
 func NewDashboardClient(url, secret, orgID string) (*Client, error) {
+	return NewDashboardClientTLS(url, secret, orgID, rest.TLS{})
+}
+
+// NewDashboardClientTLS creates a dashboard client that connects with the given TLS settings. They
+// are used from the start, as the org ID is looked up on the dashboard when none is given.
+func NewDashboardClientTLS(url, secret, orgID string, tls rest.TLS) (*Client, error) {
+	return NewDashboardClientProxy(url, secret, orgID, tls, "")
+}
+
+// NewDashboardClientProxy creates a dashboard client that connects with the given TLS settings
+// through the given proxy, which is used from the start like the TLS settings.
+func NewDashboardClientProxy(url, secret, orgID string, tls rest.TLS, proxy string) (*Client, error) {
 	client := &Client{
 		url:     url,
 		secret:  secret,
 		isCloud: strings.Contains(url, "tyk.io"),
+		Proxy:   proxy,
 	}
+	client.SetTLS(tls)
 
 	if orgID == "" {
 		fullPath := urljoin.Join(url, endpointUsers)
 
 		ro := &grequests.RequestOptions{
-			Params: map[string]string{"p": "-2"},
-			Headers: map[string]string{
-				"Authorization": secret,
-			},
+			Params:     map[string]string{"p": "-2"},
+			HTTPClient: rest.New(rest.Config{AuthHeader: authHeader, Secret: secret, TLS: tls, Proxy: proxy}),
 		}
 
 		resp, err := grequests.Get(fullPath, ro)
@@ -72,3 +105,10 @@ func NewDashboardClient(url, secret, orgID string) (*Client, error) {
 
 	return client, nil
 }
+
+// client returns the HTTP client that requests to the dashboard are made with, see rest.Lazy.
+func (c *Client) client() *http.Client {
+	return c.httpClient.Client(func() rest.Config {
+		return rest.Config{AuthHeader: authHeader, Secret: c.secret, TLS: c.TLS, Proxy: c.Proxy, HTTP: c.HTTP}
+	})
+}
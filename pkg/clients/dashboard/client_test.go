@@ -0,0 +1,63 @@
+package dashboard
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/rest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDashboardClientTLS(t *testing.T) {
+	var secret string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"users":[{"org_id":"org1"}]}`))
+	}))
+	defer server.Close()
+
+	// The org ID is looked up while the client is created, so the CA must already be trusted
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, ca, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewDashboardClientTLS(server.URL, "s3cret", "", rest.TLS{CAFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "org1", c.OrgID)
+	assert.Equal(t, "s3cret", secret)
+	assert.Equal(t, caFile, c.CAFile)
+}
+
+func TestNewDashboardClientProxy(t *testing.T) {
+	hosts := []string{}
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.URL.Host)
+		if r.URL.Path == endpointUsers {
+			_, _ = w.Write([]byte(`{"users":[{"org_id":"org1"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"apis":[]}`))
+	}))
+	defer proxy.Close()
+
+	// The org ID is looked up while the client is created, so the proxy must already be used
+	c, err := NewDashboardClientProxy("http://dashboard.example", "s3cret", "", rest.TLS{}, proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "org1", c.OrgID)
+
+	if _, err := c.FetchAPIs(); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"dashboard.example", "dashboard.example"}, hosts)
+}
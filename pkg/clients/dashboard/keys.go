@@ -0,0 +1,138 @@
+package dashboard
+
+import (
+	"fmt"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/levigross/grequests"
+	"github.com/ongoingio/urljoin"
+)
+
+// CreateKey creates a key with the given session, returning the key. The dashboard generates the key
+// unless one is given.
+func (c *Client) CreateKey(key string, session *objects.Session) (string, error) {
+	fullPath := urljoin.Join(c.url, endpointKeys)
+	if key != "" {
+		fullPath = urljoin.Join(c.url, endpointKeys, key)
+	}
+
+	resp, err := grequests.Post(fullPath, &grequests.RequestOptions{
+		JSON:       session,
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	keyResp := objects.KeyResponse{}
+	if err := resp.JSON(&keyResp); err != nil {
+		return "", err
+	}
+
+	return keyResp.KeyID, nil
+}
+
+// FetchKey returns the session of a key.
+func (c *Client) FetchKey(key string) (*objects.Session, error) {
+	return c.fetchKey(key, false)
+}
+
+// FetchKeyHash returns the session of the key with the given hash, as listed by dashboards that
+// hash keys.
+func (c *Client) FetchKeyHash(hash string) (*objects.Session, error) {
+	return c.fetchKey(hash, true)
+}
+
+func (c *Client) fetchKey(key string, hashed bool) (*objects.Session, error) {
+	fullPath := urljoin.Join(c.url, endpointKeys, key)
+
+	ro := &grequests.RequestOptions{
+		HTTPClient: c.client(),
+	}
+	if hashed {
+		ro.Params = map[string]string{"hashed": "true"}
+	}
+
+	resp, err := grequests.Get(fullPath, ro)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	keyResp := objects.KeyResponse{}
+	if err := resp.JSON(&keyResp); err != nil {
+		return nil, err
+	}
+
+	return &keyResp.Data, nil
+}
+
+// UpdateKey replaces the session of a key.
+func (c *Client) UpdateKey(key string, session *objects.Session) error {
+	fullPath := urljoin.Join(c.url, endpointKeys, key)
+
+	resp, err := grequests.Put(fullPath, &grequests.RequestOptions{
+		JSON:       session,
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	return nil
+}
+
+// DeleteKey removes a key from the dashboard.
+func (c *Client) DeleteKey(key string) error {
+	fullPath := urljoin.Join(c.url, endpointKeys, key)
+
+	resp, err := grequests.Delete(fullPath, &grequests.RequestOptions{
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	return nil
+}
+
+// FetchKeys returns the keys of the organisation.
+func (c *Client) FetchKeys() ([]string, error) {
+	fullPath := urljoin.Join(c.url, endpointKeys)
+
+	resp, err := grequests.Get(fullPath, &grequests.RequestOptions{
+		Params:     map[string]string{"p": "-1"},
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	keys := struct {
+		Data objects.KeyList `json:"data"`
+	}{}
+	if err := resp.JSON(&keys); err != nil {
+		return nil, err
+	}
+
+	return keys.Data.Keys, nil
+}
@@ -0,0 +1,57 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Keys(t *testing.T) {
+	var created objects.Session
+	createdPath := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost:
+			createdPath = r.URL.Path
+			_ = json.NewDecoder(r.Body).Decode(&created)
+			_, _ = w.Write([]byte(`{"api_model":{},"key_id":"custom","data":{}}`))
+		case r.Method == http.MethodGet && r.URL.Path == endpointKeys:
+			_, _ = w.Write([]byte(`{"data":{"keys":["one","two"]},"pages":1}`))
+		case r.Method == http.MethodGet && r.URL.Path == endpointKeys+"/one":
+			_, _ = w.Write([]byte(`{"api_model":{},"key_id":"one","data":{"apply_policies":["gold"],"alias":"svc"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := &Client{url: server.URL, secret: "s3cret"}
+
+	key, err := c.CreateKey("custom", &objects.Session{ApplyPolicies: []string{"gold"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "custom", key)
+	assert.Equal(t, endpointKeys+"/custom", createdPath)
+	assert.Equal(t, []string{"gold"}, created.ApplyPolicies)
+
+	keys, err := c.FetchKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"one", "two"}, keys)
+
+	session, err := c.FetchKey("one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "svc", session.Alias)
+	assert.Equal(t, []string{"gold"}, session.ApplyPolicies)
+
+	_, err = c.FetchKey("missing")
+	assert.Error(t, err)
+}
@@ -0,0 +1,134 @@
+package dashboard
+
+import (
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/rollback"
+)
+
+// Plan works out all the changes a sync would make to the dashboard and records the state of the
+// dashboard it was computed against. Policies are only planned when pols is not empty, so that a
+// repository without policies leaves the dashboard's policies alone.
+func (c *Client) Plan(apiDefs []objects.DBApiDefinition, pols []objects.Policy) (*objects.SyncPlan, error) {
+	plan := &objects.SyncPlan{Version: objects.PlanVersion}
+
+	if len(pols) > 0 {
+		existingPols, err := c.FetchPolicies()
+		if err != nil {
+			return nil, err
+		}
+
+		if plan.PoliciesFingerprint, err = objects.FingerprintPolicies(existingPols); err != nil {
+			return nil, err
+		}
+
+		if plan.Policies, err = c.planPolicies(existingPols, pols); err != nil {
+			return nil, err
+		}
+	}
+
+	existingAPIs, err := c.FetchAPIs()
+	if err != nil {
+		return nil, err
+	}
+
+	if plan.APIsFingerprint, err = objects.FingerprintAPIs(existingAPIs); err != nil {
+		return nil, err
+	}
+
+	if plan.APIs, err = c.planAPIs(existingAPIs, apiDefs); err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// ApplyPlan makes exactly the changes recorded in a plan. It refuses to make any changes if the
+// dashboard's APIs, policies, users or user groups no longer match the state the plan was created
+// against.
+//
+// The remote objects recorded in the plan act as a snapshot of everything the plan touches, if any
+// change fails all changes made so far are reverted to it and a *rollback.Error is returned.
+func (c *Client) ApplyPlan(plan *objects.SyncPlan) error {
+	if err := c.checkPlan(plan); err != nil {
+		return err
+	}
+
+	// APIs go first, so that the policies that grant access to them are applied after they exist
+	journal := &rollback.Journal{}
+	if err := c.applyAPIChanges(plan.APIs, journal); err != nil {
+		return journal.Rollback(err)
+	}
+
+	if len(plan.Policies) > 0 {
+		if err := c.applyPolicyChanges(plan.Policies, journal); err != nil {
+			return journal.Rollback(err)
+		}
+	}
+
+	if len(plan.UserGroups) > 0 || len(plan.Users) > 0 {
+		if err := c.applyUserChanges(plan, journal); err != nil {
+			return journal.Rollback(err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) checkPlan(plan *objects.SyncPlan) error {
+	if plan.Version != objects.PlanVersion {
+		return objects.UnsupportedPlanError
+	}
+
+	if plan.PoliciesFingerprint != "" {
+		existingPols, err := c.FetchPolicies()
+		if err != nil {
+			return err
+		}
+
+		fingerprint, err := objects.FingerprintPolicies(existingPols)
+		if err != nil {
+			return err
+		}
+
+		if fingerprint != plan.PoliciesFingerprint {
+			return objects.StalePlanError
+		}
+	}
+
+	if plan.UsersFingerprint != "" {
+		existingUsers, err := c.FetchUsers()
+		if err != nil {
+			return err
+		}
+
+		existingGroups, err := c.FetchUserGroups()
+		if err != nil {
+			return err
+		}
+
+		fingerprint, err := objects.FingerprintUsers(existingUsers, existingGroups)
+		if err != nil {
+			return err
+		}
+
+		if fingerprint != plan.UsersFingerprint {
+			return objects.StalePlanError
+		}
+	}
+
+	existingAPIs, err := c.FetchAPIs()
+	if err != nil {
+		return err
+	}
+
+	fingerprint, err := objects.FingerprintAPIs(existingAPIs)
+	if err != nil {
+		return err
+	}
+
+	if fingerprint != plan.APIsFingerprint {
+		return objects.StalePlanError
+	}
+
+	return nil
+}
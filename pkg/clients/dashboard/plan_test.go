@@ -0,0 +1,50 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_ApplyPlan(t *testing.T) {
+	remote := []objects.DBApiDefinition{newTestDef("one", "One", "http://one")}
+	writes := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writes++
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(APISResponse{Apis: remote})
+	}))
+	defer server.Close()
+
+	c, err := NewDashboardClient(server.URL, "secret", "org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := c.Plan([]objects.DBApiDefinition{newTestDef("one", "One", "http://one")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEmpty(t, plan.APIsFingerprint)
+	assert.Empty(t, plan.PoliciesFingerprint, "policies should not be planned when none are given")
+
+	t.Run("stale plans are refused", func(t *testing.T) {
+		remote[0].Proxy.TargetURL = "http://changed"
+		err := c.ApplyPlan(plan)
+		assert.Equal(t, objects.StalePlanError, err)
+		assert.Equal(t, 0, writes)
+	})
+
+	t.Run("plans from other versions are refused", func(t *testing.T) {
+		plan.Version = objects.PlanVersion + 1
+		assert.Equal(t, objects.UnsupportedPlanError, c.ApplyPlan(plan))
+	})
+}
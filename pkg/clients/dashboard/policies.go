@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/pool"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/rollback"
 	"github.com/AaronFeledy/tyk-ops/pkg/output"
 
 	"github.com/gofrs/uuid"
@@ -21,11 +23,8 @@ func (c *Client) FetchPolicies() ([]objects.Policy, error) {
 	fullPath := urljoin.Join(c.url, endpointPolicies)
 
 	ro := &grequests.RequestOptions{
-		Params: map[string]string{"p": "-2"},
-		Headers: map[string]string{
-			"Authorization": c.secret,
-		},
-		InsecureSkipVerify: c.InsecureSkipVerify,
+		Params:     map[string]string{"p": "-2"},
+		HTTPClient: c.client(),
 	}
 
 	resp, err := grequests.Get(fullPath, ro)
@@ -58,6 +57,8 @@ func getPoliciesIdentifiers(pols *[]objects.Policy) (map[string]*objects.Policy,
 	return mids, ids
 }
 
+// CreatePolicies creates policies on the dashboard, up to c.Parallel at once, in the same way as
+// CreateAPIs.
 func (c *Client) CreatePolicies(pols *[]objects.Policy) error {
 	existingPols, err := c.FetchPolicies()
 	if err != nil {
@@ -67,79 +68,130 @@ func (c *Client) CreatePolicies(pols *[]objects.Policy) error {
 	mids, ids := getPoliciesIdentifiers(&existingPols)
 
 	var existsCount int64
+	createPols := make([]objects.Policy, 0)
 	for i := range *pols {
 		pol := (*pols)[i]
-		fmt.Printf("Creating Policy %v: %v\n", i, pol.Name)
+		output.Printf("Creating Policy %v: %v\n", i, pol.Name)
 		var existsError error
 		if thisPol, ok := mids[pol.MID.Hex()]; ok && thisPol != nil {
-			fmt.Println("Warning: Policy MID Exists")
+			output.Println("Warning: Policy MID Exists")
 			existsError = UseUpdateError
 		} else if thisPol, ok := ids[pol.ID]; ok && thisPol != nil {
-			fmt.Println("Warning: Policy ID Exists")
+			output.Println("Warning: Policy ID Exists")
 			existsError = UseUpdateError
 		}
 
 		if existsError != nil {
 			if c.SkipExisting {
 				existsCount++
+				event := objects.PolicyEvent(&pol, objects.ActionCreate, nil)
+				event.Result = output.ResultSkipped
+				output.Emit(event, "")
 				continue
 			}
+			output.Emit(objects.PolicyEvent(&pol, objects.ActionCreate, existsError), "")
 			return existsError
 		}
 
-		fullPath := urljoin.Join(c.url, endpointPolicies)
-
-		ro := &grequests.RequestOptions{
-			JSON: pol,
-			Headers: map[string]string{
-				"Authorization": c.secret,
-			},
-			InsecureSkipVerify: c.InsecureSkipVerify,
+		// Add the policy to the existing policies, so that duplicates within pols are caught too.
+		if pol.MID.Valid() {
+			mids[pol.MID.Hex()] = &pol
+		}
+		if pol.ID != "" {
+			ids[pol.ID] = &pol
 		}
 
-		resp, err := grequests.Post(fullPath, ro)
+		createPols = append(createPols, pol)
+	}
+
+	err = pool.Run(len(createPols), c.Parallel, func(i int) error {
+		pol := createPols[i]
+		mid, meta, err := c.postPolicy(&pol)
 		if err != nil {
-			return err
+			output.Emit(objects.PolicyEvent(&pol, objects.ActionCreate, err), "")
+			return fmt.Errorf("creating policy %v: %v", pol.Name, err)
 		}
 
-		if resp.StatusCode != 200 {
-			return fmt.Errorf("API Returned error: %v", resp.String())
-		}
+		pol.MID = bson.ObjectIdHex(mid)
+		output.Emit(objects.PolicyEvent(&pol, objects.ActionCreate, nil), "--> Status: OK, ID:%v\n", meta)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-		dbResp := APIResponse{}
-		if err := resp.JSON(&dbResp); err != nil {
-			return err
-		}
+	if existsCount > 0 {
+		output.User.Printf("%v policies already exist and were skipped\n", existsCount)
+	}
 
-		if dbResp.Status != "OK" {
-			return fmt.Errorf("API request completed, but with error: %v", dbResp.Message)
-		}
+	return nil
+}
 
-		// Update pol with its ID before adding it to the existing policies list.
-		pol.MID = bson.ObjectIdHex(dbResp.Message)
+// postPolicy creates a policy on the dashboard, returning its new DB ID and the response metadata.
+func (c *Client) postPolicy(pol *objects.Policy) (string, string, error) {
+	fullPath := urljoin.Join(c.url, endpointPolicies)
 
-		// Add created Policy to existing policies
-		mids[pol.MID.Hex()] = &pol
-		ids[pol.ID] = &pol
+	ro := &grequests.RequestOptions{
+		JSON:       pol,
+		HTTPClient: c.client(),
+	}
 
-		fmt.Printf("--> Status: OK, ID:%v\n", dbResp.Meta)
+	resp, err := grequests.Post(fullPath, ro)
+	if err != nil {
+		return "", "", err
 	}
 
-	if existsCount > 0 {
-		output.User.Println("%v policies already exist and were skipped", existsCount)
+	if resp.StatusCode != 200 {
+		return "", "", fmt.Errorf("API Returned error: %v", resp.String())
 	}
 
-	return nil
+	dbResp := APIResponse{}
+	if err := resp.JSON(&dbResp); err != nil {
+		return "", "", err
+	}
+
+	if dbResp.Status != "OK" {
+		return "", "", fmt.Errorf("API request completed, but with error: %v", dbResp.Message)
+	}
+
+	return dbResp.Message, dbResp.Meta, nil
+}
+
+// putPolicy replaces the dashboard policy with the same DB ID as pol, returning the response metadata.
+func (c *Client) putPolicy(pol *objects.Policy) (string, error) {
+	fullPath := urljoin.Join(c.url, endpointPolicies, pol.MID.Hex())
+
+	ro := &grequests.RequestOptions{
+		JSON:       pol,
+		HTTPClient: c.client(),
+	}
+
+	resp, err := grequests.Put(fullPath, ro)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	dbResp := APIResponse{}
+	if err := resp.JSON(&dbResp); err != nil {
+		return "", err
+	}
+
+	if dbResp.Status != "OK" {
+		return "", fmt.Errorf("API request completed, but with error: %v", dbResp.Message)
+	}
+
+	return dbResp.Meta, nil
 }
 
 func (c *Client) DeletePolicy(id string) error {
 	fullPath := urljoin.Join(c.url, endpointPolicies, id)
 
 	ro := &grequests.RequestOptions{
-		Headers: map[string]string{
-			"Authorization": c.secret,
-		},
-		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient: c.client(),
 	}
 
 	resp, err := grequests.Delete(fullPath, ro)
@@ -158,10 +210,7 @@ func (c *Client) FetchPolicy(id string) (*objects.Policy, error) {
 	fullPath := urljoin.Join(c.url, endpointPolicies, id)
 
 	ro := &grequests.RequestOptions{
-		Headers: map[string]string{
-			"Authorization": c.secret,
-		},
-		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient: c.client(),
 	}
 
 	resp, err := grequests.Get(fullPath, ro)
@@ -181,6 +230,8 @@ func (c *Client) FetchPolicy(id string) (*objects.Policy, error) {
 	return &pol, nil
 }
 
+// UpdatePolicies updates the matching policies on the dashboard, up to c.Parallel at once, in the
+// same way as UpdateAPIs.
 func (c *Client) UpdatePolicies(pols *[]objects.Policy) error {
 	existingPols, err := c.FetchPolicies()
 	if err != nil {
@@ -189,155 +240,208 @@ func (c *Client) UpdatePolicies(pols *[]objects.Policy) error {
 
 	mids, ids := getPoliciesIdentifiers(&existingPols)
 
+	updatePols := make([]objects.Policy, 0, len(*pols))
 	for i := range *pols {
 		pol := (*pols)[i]
-		fmt.Printf("Updating Policy %v: %v\n", i, pol.Name)
+		output.Printf("Updating Policy %v: %v\n", i, pol.Name)
 		if pol.MID.Hex() == "" && pol.ID == "" {
 			return errors.New("--> Can't update policy without an ID or explicit (legacy) ID")
 		}
 
 		if thisPol, ok := ids[pol.ID]; ok && thisPol != nil {
-			fmt.Println("--> Found policy using explicit ID, substituting remote ID for update")
+			output.Println("--> Found policy using explicit ID, substituting remote ID for update")
 
 			pol.MID = thisPol.MID
 		} else if thisPol, ok := mids[pol.MID.Hex()]; !ok || thisPol == nil {
+			output.Emit(objects.PolicyEvent(&pol, objects.ActionUpdate, UseCreateError), "")
 			return UseCreateError
 		}
 
-		fullPath := urljoin.Join(c.url, endpointPolicies, pol.MID.Hex())
-
-		ro := &grequests.RequestOptions{
-			JSON: pol,
-			Headers: map[string]string{
-				"Authorization": c.secret,
-			},
-			InsecureSkipVerify: c.InsecureSkipVerify,
-		}
+		updatePols = append(updatePols, pol)
+	}
 
-		resp, err := grequests.Put(fullPath, ro)
+	return pool.Run(len(updatePols), c.Parallel, func(i int) error {
+		pol := updatePols[i]
+		meta, err := c.putPolicy(&pol)
 		if err != nil {
-			return err
-		}
-
-		if resp.StatusCode != 200 {
-			return fmt.Errorf("API Returned error: %v", resp.String())
-		}
-
-		dbResp := APIResponse{}
-		if err := resp.JSON(&dbResp); err != nil {
-			return err
+			output.Emit(objects.PolicyEvent(&pol, objects.ActionUpdate, err), "")
+			return fmt.Errorf("updating policy %v: %v", pol.Name, err)
 		}
 
-		if dbResp.Status != "OK" {
-			return fmt.Errorf("API request completed, but with error: %v", dbResp.Message)
-		}
-
-		// Add updated Policy to existing policies
-		mids[pol.MID.Hex()] = &pol
-		ids[pol.ID] = &pol
-
-		fmt.Printf("--> Status: OK, ID:%v\n", dbResp.Meta)
-	}
-
-	return nil
+		output.Emit(objects.PolicyEvent(&pol, objects.ActionUpdate, nil), "--> Status: OK, ID:%v\n", meta)
+		return nil
+	})
 }
 
-func (c *Client) SyncPolicies(pols []objects.Policy) error {
-	deletePols := []string{}
-	updatePols := []objects.Policy{}
-	createPols := []objects.Policy{}
-
+// PlanPolicies works out which policies a sync would delete, update and create on the dashboard
+// without making any changes.
+func (c *Client) PlanPolicies(pols []objects.Policy) ([]objects.PolicyChange, error) {
 	// Fetch the running Policy list
-
 	ePols, err := c.FetchPolicies()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	return c.planPolicies(ePols, pols)
+}
+
+func (c *Client) planPolicies(ePols []objects.Policy, pols []objects.Policy) ([]objects.PolicyChange, error) {
+	changes := []objects.PolicyChange{}
+
 	DashIDMap := map[string]int{}
 	GitIDMap := map[string]int{}
+	dashKeys := make([]string, len(ePols))
+	gitKeys := make([]string, len(pols))
 
 	// Build the dash ID map
 	for i, pol := range ePols {
 		// Lets get a full list of existing IDs
 		if pol.ID != "" {
-			DashIDMap[pol.ID] = i
+			dashKeys[i] = pol.ID
 		} else {
-			DashIDMap[pol.MID.Hex()] = i
+			dashKeys[i] = pol.MID.Hex()
 		}
+		DashIDMap[dashKeys[i]] = i
 	}
 
 	// Build the Git ID Map
 	for i, pol := range pols {
 		if pol.ID != "" {
-			GitIDMap[pol.ID] = i
+			gitKeys[i] = pol.ID
 		} else if pol.MID.Hex() != "" {
-			GitIDMap[pol.MID.Hex()] = i
+			gitKeys[i] = pol.MID.Hex()
 		} else {
 			uid, err := uuid.NewV4()
 			if err != nil {
-				fmt.Println("error generating UUID", err)
-				return err
+				output.Println("error generating UUID", err)
+				return nil, err
 			}
-			created := fmt.Sprintf("temp-pol-%v", uid.String())
-			GitIDMap[created] = i
+			gitKeys[i] = fmt.Sprintf("temp-pol-%v", uid.String())
 		}
+		GitIDMap[gitKeys[i]] = i
 	}
 
-	// Updates are when we find items in git that are also in dash
-	for key, index := range GitIDMap {
-		dashIndex, ok := DashIDMap[key]
-		if ok {
-			p := pols[index]
-			// Make sure we target the correct DB ID
-			p.MID = ePols[dashIndex].MID
-			updatePols = append(updatePols, p)
+	// Deletes are when we find items in the dash that are not in git
+	for i, key := range dashKeys {
+		if DashIDMap[key] != i {
+			continue
+		}
+		if _, ok := GitIDMap[key]; !ok {
+			remote := ePols[i]
+			changes = append(changes, objects.PolicyChange{Action: objects.ActionDelete, Remote: &remote})
 		}
 	}
 
-	// Deletes are when we find items in the dash that are not in git
-	for key, i := range DashIDMap {
-		_, ok := GitIDMap[key]
+	// Updates are when we find items in git that are also in dash, creates are when we find things
+	// in git that are not in the dashboard
+	for i, key := range gitKeys {
+		if GitIDMap[key] != i {
+			continue
+		}
+
+		local := pols[i]
+		dashIndex, ok := DashIDMap[key]
 		if !ok {
-			deletePols = append(deletePols, ePols[i].MID.Hex())
+			changes = append(changes, objects.PolicyChange{Action: objects.ActionCreate, Local: &local})
+			continue
 		}
+
+		// Make sure we target the correct DB ID
+		remote := ePols[dashIndex]
+		local.MID = remote.MID
+		changes = append(changes, objects.PolicyChange{Action: objects.ActionUpdate, Local: &local, Remote: &remote})
 	}
 
-	// Create operations are when we find things in Git that are not in the dashboard
-	for key, index := range GitIDMap {
-		_, ok := DashIDMap[key]
-		if !ok {
-			createPols = append(createPols, pols[index])
+	return objects.ClaimPolicyChanges(changes, c.Owner)
+}
+
+// SyncPolicies makes the dashboard's policies match pols. If any change fails, the changes already
+// made are rolled back and a *rollback.Error is returned.
+func (c *Client) SyncPolicies(pols []objects.Policy) error {
+	changes, err := c.PlanPolicies(pols)
+	if err != nil {
+		return err
+	}
+
+	journal := &rollback.Journal{}
+	if err := c.applyPolicyChanges(changes, journal); err != nil {
+		return journal.Rollback(err)
+	}
+
+	return nil
+}
+
+// applyPolicyChanges makes the planned policy changes, recording how to revert each one in the journal.
+// The changes are made up to c.Parallel at once, in the same way as applyAPIChanges.
+func (c *Client) applyPolicyChanges(changes []objects.PolicyChange, journal *rollback.Journal) error {
+	deletePols := []objects.Policy{}
+	updatePols := []objects.PolicyChange{}
+	createPols := []objects.Policy{}
+
+	for _, change := range changes {
+		switch change.Action {
+		case objects.ActionDelete:
+			deletePols = append(deletePols, *change.Remote)
+		case objects.ActionUpdate:
+			updatePols = append(updatePols, change)
+		case objects.ActionCreate:
+			createPols = append(createPols, *change.Local)
 		}
 	}
 
-	fmt.Printf("Deleting policies: %v\n", len(deletePols))
-	fmt.Printf("Updating policies: %v\n", len(updatePols))
-	fmt.Printf("Creating policies: %v\n", len(createPols))
+	output.Printf("Deleting policies: %v\n", len(deletePols))
+	output.Printf("Updating policies: %v\n", len(updatePols))
+	output.Printf("Creating policies: %v\n", len(createPols))
 
 	// Do the deletes
-	for _, dbId := range deletePols {
-		fmt.Printf("SYNC Deleting Policy: %v\n", dbId)
-		if err := c.DeletePolicy(dbId); err != nil {
-			return err
+	err := pool.Run(len(deletePols), c.Parallel, func(i int) error {
+		remote := deletePols[i]
+		if err := c.DeletePolicy(remote.MID.Hex()); err != nil {
+			output.Emit(objects.PolicyEvent(&remote, objects.ActionDelete, err), "")
+			return fmt.Errorf("deleting policy %v: %v", remote.Name, err)
 		}
+		// The policy is re-created with its original IDs
+		journal.Record(fmt.Sprintf("Restored deleted policy: %v", remote.Name), func() error {
+			_, _, err := c.postPolicy(&remote)
+			return err
+		})
+		output.Emit(objects.PolicyEvent(&remote, objects.ActionDelete, nil), "SYNC Deleted Policy: %v\n", remote.MID.Hex())
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	// Do the updates
-	if err := c.UpdatePolicies(&updatePols); err != nil {
+	err = pool.Run(len(updatePols), c.Parallel, func(i int) error {
+		local, remote := *updatePols[i].Local, *updatePols[i].Remote
+		if _, err := c.putPolicy(&local); err != nil {
+			output.Emit(objects.PolicyEvent(&local, objects.ActionUpdate, err), "")
+			return fmt.Errorf("updating policy %v: %v", local.Name, err)
+		}
+		journal.Record(fmt.Sprintf("Reverted updated policy: %v", remote.Name), func() error {
+			_, err := c.putPolicy(&remote)
+			return err
+		})
+		output.Emit(objects.PolicyEvent(&local, objects.ActionUpdate, nil), "SYNC Updated Policy: %v\n", local.Name)
+		return nil
+	})
+	if err != nil {
 		return err
 	}
-	for _, pol := range updatePols {
-		fmt.Printf("SYNC Updated Policy: %v\n", pol.Name)
-	}
 
 	// Do the creates
-	if err = c.CreatePolicies(&createPols); err != nil {
-		return err
-	}
-	for _, pol := range createPols {
-		fmt.Printf("SYNC Created Policy: %v\n", pol.Name)
-	}
-
-	return nil
+	return pool.Run(len(createPols), c.Parallel, func(i int) error {
+		local := createPols[i]
+		mid, _, err := c.postPolicy(&local)
+		if err != nil {
+			output.Emit(objects.PolicyEvent(&local, objects.ActionCreate, err), "")
+			return fmt.Errorf("creating policy %v: %v", local.Name, err)
+		}
+		journal.Record(fmt.Sprintf("Removed created policy: %v", local.Name), func() error {
+			return c.DeletePolicy(mid)
+		})
+		output.Emit(objects.PolicyEvent(&local, objects.ActionCreate, nil), "SYNC Created Policy: %v\n", local.Name)
+		return nil
+	})
 }
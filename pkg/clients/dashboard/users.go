@@ -0,0 +1,549 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/pool"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/rollback"
+	"github.com/AaronFeledy/tyk-ops/pkg/output"
+	"github.com/levigross/grequests"
+	"github.com/ongoingio/urljoin"
+)
+
+// userResponse is the dashboard's response to a change to a user or user group. Meta holds the new
+// user for user creations and the ID of the group for group creations.
+type userResponse struct {
+	Status  string
+	Message string
+	Meta    json.RawMessage
+}
+
+// id returns the ID of the object a change created.
+func (r userResponse) id() string {
+	var id string
+	if err := json.Unmarshal(r.Meta, &id); err == nil {
+		return id
+	}
+
+	var obj struct {
+		ID string `json:"id"`
+	}
+	_ = json.Unmarshal(r.Meta, &obj)
+	return obj.ID
+}
+
+// FetchUsers returns the users of the organisation.
+func (c *Client) FetchUsers() ([]objects.User, error) {
+	fullPath := urljoin.Join(c.url, endpointUsers)
+
+	resp, err := grequests.Get(fullPath, &grequests.RequestOptions{
+		Params:     map[string]string{"p": "-2"},
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	users := objects.UsersResponse{}
+	if err := resp.JSON(&users); err != nil {
+		return nil, err
+	}
+
+	return users.Users, nil
+}
+
+// FetchUser returns the user with the given ID.
+func (c *Client) FetchUser(id string) (*objects.User, error) {
+	fullPath := urljoin.Join(c.url, endpointUsers, id)
+
+	resp, err := grequests.Get(fullPath, &grequests.RequestOptions{
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	user := objects.User{}
+	if err := resp.JSON(&user); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// CreateUser creates a user, returning its ID. The user is given a password only if it has one.
+func (c *Client) CreateUser(user *objects.User) (string, error) {
+	payload := *user
+	payload.AccessKey, payload.Group = "", ""
+
+	resp, err := grequests.Post(urljoin.Join(c.url, endpointUsers), &grequests.RequestOptions{
+		JSON:       payload,
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	userResp, err := checkUserResponse(resp)
+	if err != nil {
+		return "", err
+	}
+
+	return userResp.id(), nil
+}
+
+// UpdateUser replaces the details and permissions of a user. Its password and access key are left
+// as they are.
+func (c *Client) UpdateUser(user *objects.User) error {
+	payload := user.WithoutSecrets()
+	payload.Group = ""
+
+	resp, err := grequests.Put(urljoin.Join(c.url, endpointUsers, user.ID), &grequests.RequestOptions{
+		JSON:       payload,
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = checkUserResponse(resp)
+	return err
+}
+
+// DeleteUser removes a user from the dashboard.
+func (c *Client) DeleteUser(id string) error {
+	resp, err := grequests.Delete(urljoin.Join(c.url, endpointUsers, id), &grequests.RequestOptions{
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = checkUserResponse(resp)
+	return err
+}
+
+// ResetUserPassword sets a new password for a user.
+func (c *Client) ResetUserPassword(id, password string) error {
+	resp, err := grequests.Post(urljoin.Join(c.url, endpointUsers, id, "actions", "reset"), &grequests.RequestOptions{
+		JSON:       map[string]string{"new_password": password},
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = checkUserResponse(resp)
+	return err
+}
+
+// FetchUserGroups returns the user groups of the organisation.
+func (c *Client) FetchUserGroups() ([]objects.UserGroup, error) {
+	fullPath := urljoin.Join(c.url, endpointUserGroups)
+
+	resp, err := grequests.Get(fullPath, &grequests.RequestOptions{
+		Params:     map[string]string{"p": "-2"},
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	groups := objects.UserGroupsResponse{}
+	if err := resp.JSON(&groups); err != nil {
+		return nil, err
+	}
+
+	return groups.Groups, nil
+}
+
+// CreateUserGroup creates a user group, returning its ID.
+func (c *Client) CreateUserGroup(group *objects.UserGroup) (string, error) {
+	resp, err := grequests.Post(urljoin.Join(c.url, endpointUserGroups), &grequests.RequestOptions{
+		JSON:       group,
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	userResp, err := checkUserResponse(resp)
+	if err != nil {
+		return "", err
+	}
+
+	return userResp.id(), nil
+}
+
+// UpdateUserGroup replaces the name, description and permissions of a user group.
+func (c *Client) UpdateUserGroup(group *objects.UserGroup) error {
+	resp, err := grequests.Put(urljoin.Join(c.url, endpointUserGroups, group.ID), &grequests.RequestOptions{
+		JSON:       group,
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = checkUserResponse(resp)
+	return err
+}
+
+// DeleteUserGroup removes a user group from the dashboard.
+func (c *Client) DeleteUserGroup(id string) error {
+	resp, err := grequests.Delete(urljoin.Join(c.url, endpointUserGroups, id), &grequests.RequestOptions{
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = checkUserResponse(resp)
+	return err
+}
+
+// checkUserResponse checks the response to a change to a user or user group.
+func checkUserResponse(resp *grequests.Response) (*userResponse, error) {
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	userResp := userResponse{}
+	if err := resp.JSON(&userResp); err != nil {
+		return nil, err
+	}
+
+	if userResp.Status != "OK" {
+		return nil, fmt.Errorf("API request completed, but with error: %v", userResp.Message)
+	}
+
+	return &userResp, nil
+}
+
+// PlanUsers adds the changes a sync of the given users and user groups would make to a plan, and
+// records the state of the dashboard's users and user groups it was computed against. Users are
+// matched by their email address and user groups by their name. Users and user groups are each
+// left alone when none are given, and the user the sync runs as is never deleted. When the client
+// has an owner, users and user groups that aren't given are never deleted, as they can't be told
+// apart from those of other owners.
+func (c *Client) PlanUsers(plan *objects.SyncPlan, users []objects.User, groups []objects.UserGroup) error {
+	if len(users) == 0 && len(groups) == 0 {
+		return nil
+	}
+
+	existingUsers, err := c.FetchUsers()
+	if err != nil {
+		return err
+	}
+
+	existingGroups, err := c.FetchUserGroups()
+	if err != nil {
+		return err
+	}
+
+	if plan.UsersFingerprint, err = objects.FingerprintUsers(existingUsers, existingGroups); err != nil {
+		return err
+	}
+
+	if len(groups) > 0 {
+		if plan.UserGroups, err = c.planUserGroups(existingGroups, groups); err != nil {
+			return err
+		}
+	}
+
+	if len(users) > 0 {
+		// Groups that the sync deletes can't be referred to, and those it creates have no ID yet
+		available := existingGroups
+		if len(groups) > 0 {
+			available = groups
+		}
+		if plan.Users, err = c.planUsers(existingUsers, existingGroups, available, users); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) planUserGroups(existing, groups []objects.UserGroup) ([]objects.UserGroupChange, error) {
+	changes := []objects.UserGroupChange{}
+	seen := map[string]bool{}
+
+	for i := range groups {
+		local := groups[i]
+		if seen[local.Name] {
+			return nil, fmt.Errorf("user group %v is in the repository more than once", local.Name)
+		}
+		seen[local.Name] = true
+
+		if local.OrgID == "" {
+			local.OrgID = c.OrgID
+		}
+
+		remote := objects.FindUserGroup(existing, local.Name)
+		if remote == nil {
+			local.ID = ""
+			changes = append(changes, objects.UserGroupChange{Action: objects.ActionCreate, Local: &local})
+			continue
+		}
+
+		local.ID = remote.ID
+		changes = append(changes, objects.UserGroupChange{Action: objects.ActionUpdate, Local: &local, Remote: remote})
+	}
+
+	// Groups have nowhere to be stamped with an owner, so a sync with one leaves the rest alone
+	if c.Owner != "" {
+		return changes, nil
+	}
+
+	for i := range existing {
+		remote := existing[i]
+		if !seen[remote.Name] {
+			changes = append(changes, objects.UserGroupChange{Action: objects.ActionDelete, Remote: &remote})
+		}
+	}
+
+	return changes, nil
+}
+
+func (c *Client) planUsers(existing []objects.User, existingGroups, available []objects.UserGroup, users []objects.User) ([]objects.UserChange, error) {
+	changes := []objects.UserChange{}
+	seen := []string{}
+
+	for i := range users {
+		local := users[i].WithoutSecrets()
+		for _, email := range seen {
+			if objects.SameEmail(email, local.EmailAddress) {
+				return nil, fmt.Errorf("user %v is in the repository more than once", local.EmailAddress)
+			}
+		}
+		seen = append(seen, local.EmailAddress)
+
+		if local.OrgID == "" {
+			local.OrgID = c.OrgID
+		}
+
+		if local.Group != "" {
+			if objects.FindUserGroup(available, local.Group) == nil {
+				return nil, fmt.Errorf("user %v is in group %v, which is neither on the target nor in the repository", local.EmailAddress, local.Group)
+			}
+			// Groups the sync creates get their ID when the plan is applied
+			local.GroupID = ""
+			if group := objects.FindUserGroup(existingGroups, local.Group); group != nil {
+				local.GroupID = group.ID
+			}
+		}
+
+		var remote *objects.User
+		for j := range existing {
+			if objects.SameEmail(existing[j].EmailAddress, local.EmailAddress) {
+				r := existing[j].WithoutSecrets()
+				remote = &r
+				break
+			}
+		}
+
+		if remote == nil {
+			local.ID = ""
+			changes = append(changes, objects.UserChange{Action: objects.ActionCreate, Local: &local})
+			continue
+		}
+
+		local.ID = remote.ID
+		changes = append(changes, objects.UserChange{Action: objects.ActionUpdate, Local: &local, Remote: remote})
+	}
+
+	// Users have nowhere to be stamped with an owner, so a sync with one leaves the rest alone
+	if c.Owner != "" {
+		return changes, nil
+	}
+
+	for i := range existing {
+		remote := existing[i]
+		wanted := false
+		for _, email := range seen {
+			if objects.SameEmail(email, remote.EmailAddress) {
+				wanted = true
+				break
+			}
+		}
+		if wanted {
+			continue
+		}
+
+		if remote.AccessKey != "" && remote.AccessKey == c.secret {
+			output.Printf("--> User %v is the user the sync runs as and will not be deleted\n", remote.EmailAddress)
+			continue
+		}
+
+		remote = remote.WithoutSecrets()
+		changes = append(changes, objects.UserChange{Action: objects.ActionDelete, Remote: &remote})
+	}
+
+	return changes, nil
+}
+
+// applyUserChanges makes the user and user group changes of a plan. Groups are created and updated
+// first so that users can be moved into them, and deleted last once no user is left in them. Users
+// are deleted after every other change, as deleting a user can't be rolled back.
+func (c *Client) applyUserChanges(plan *objects.SyncPlan, journal *rollback.Journal) error {
+	var createGroups, updateGroups, deleteGroups []objects.UserGroupChange
+	for _, change := range plan.UserGroups {
+		switch change.Action {
+		case objects.ActionCreate:
+			createGroups = append(createGroups, change)
+		case objects.ActionUpdate:
+			updateGroups = append(updateGroups, change)
+		case objects.ActionDelete:
+			deleteGroups = append(deleteGroups, change)
+		}
+	}
+
+	var createUsers, updateUsers, deleteUsers []objects.UserChange
+	for _, change := range plan.Users {
+		switch change.Action {
+		case objects.ActionCreate:
+			createUsers = append(createUsers, change)
+		case objects.ActionUpdate:
+			updateUsers = append(updateUsers, change)
+		case objects.ActionDelete:
+			deleteUsers = append(deleteUsers, change)
+		}
+	}
+
+	output.Printf("Deleting user groups: %v\n", len(deleteGroups))
+	output.Printf("Updating user groups: %v\n", len(updateGroups))
+	output.Printf("Creating user groups: %v\n", len(createGroups))
+	output.Printf("Deleting users: %v\n", len(deleteUsers))
+	output.Printf("Updating users: %v\n", len(updateUsers))
+	output.Printf("Creating users: %v\n", len(createUsers))
+
+	// The IDs of the groups created here, by name, for the users that are moved into them
+	groupIDs := map[string]string{}
+	var mu sync.Mutex
+
+	err := pool.Run(len(createGroups), c.Parallel, func(i int) error {
+		local := *createGroups[i].Local
+		id, err := c.CreateUserGroup(&local)
+		if err != nil {
+			output.Emit(objects.UserGroupEvent(&local, objects.ActionCreate, err), "")
+			return fmt.Errorf("creating user group %v: %v", local.Name, err)
+		}
+		journal.Record(fmt.Sprintf("Deleted created user group: %v", local.Name), func() error {
+			return c.DeleteUserGroup(id)
+		})
+
+		mu.Lock()
+		groupIDs[local.Name] = id
+		mu.Unlock()
+
+		local.ID = id
+		output.Emit(objects.UserGroupEvent(&local, objects.ActionCreate, nil), "SYNC Created User Group: %v\n", local.Name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = pool.Run(len(updateGroups), c.Parallel, func(i int) error {
+		local, remote := *updateGroups[i].Local, *updateGroups[i].Remote
+		if err := c.UpdateUserGroup(&local); err != nil {
+			output.Emit(objects.UserGroupEvent(&local, objects.ActionUpdate, err), "")
+			return fmt.Errorf("updating user group %v: %v", local.Name, err)
+		}
+		journal.Record(fmt.Sprintf("Reverted updated user group: %v", remote.Name), func() error {
+			return c.UpdateUserGroup(&remote)
+		})
+		output.Emit(objects.UserGroupEvent(&local, objects.ActionUpdate, nil), "SYNC Updated User Group: %v\n", local.Name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// withGroup returns a user with the ID of the group it was put in by name
+	withGroup := func(user objects.User) objects.User {
+		if user.Group != "" && user.GroupID == "" {
+			user.GroupID = groupIDs[user.Group]
+		}
+		return user
+	}
+
+	err = pool.Run(len(updateUsers), c.Parallel, func(i int) error {
+		local, remote := withGroup(*updateUsers[i].Local), *updateUsers[i].Remote
+		if err := c.UpdateUser(&local); err != nil {
+			output.Emit(objects.UserEvent(&local, objects.ActionUpdate, err), "")
+			return fmt.Errorf("updating user %v: %v", local.EmailAddress, err)
+		}
+		journal.Record(fmt.Sprintf("Reverted updated user: %v", remote.EmailAddress), func() error {
+			return c.UpdateUser(&remote)
+		})
+		output.Emit(objects.UserEvent(&local, objects.ActionUpdate, nil), "SYNC Updated User: %v\n", local.EmailAddress)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = pool.Run(len(createUsers), c.Parallel, func(i int) error {
+		local := withGroup(*createUsers[i].Local)
+		id, err := c.CreateUser(&local)
+		if err != nil {
+			output.Emit(objects.UserEvent(&local, objects.ActionCreate, err), "")
+			return fmt.Errorf("creating user %v: %v", local.EmailAddress, err)
+		}
+		journal.Record(fmt.Sprintf("Deleted created user: %v", local.EmailAddress), func() error {
+			return c.DeleteUser(id)
+		})
+		local.ID = id
+		output.Emit(objects.UserEvent(&local, objects.ActionCreate, nil), "SYNC Created User: %v\n", local.EmailAddress)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Deleted users can't be restored, as a new user would get a new ID and neither the password
+	// nor the access key, so they are deleted once everything else has been applied
+	err = pool.Run(len(deleteUsers), c.Parallel, func(i int) error {
+		remote := *deleteUsers[i].Remote
+		if err := c.DeleteUser(remote.ID); err != nil {
+			output.Emit(objects.UserEvent(&remote, objects.ActionDelete, err), "")
+			return fmt.Errorf("deleting user %v: %v", remote.EmailAddress, err)
+		}
+		output.Emit(objects.UserEvent(&remote, objects.ActionDelete, nil), "SYNC Deleted User: %v\n", remote.EmailAddress)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return pool.Run(len(deleteGroups), c.Parallel, func(i int) error {
+		remote := *deleteGroups[i].Remote
+		if err := c.DeleteUserGroup(remote.ID); err != nil {
+			output.Emit(objects.UserGroupEvent(&remote, objects.ActionDelete, err), "")
+			return fmt.Errorf("deleting user group %v: %v", remote.Name, err)
+		}
+		journal.Record(fmt.Sprintf("Restored deleted user group: %v", remote.Name), func() error {
+			_, err := c.CreateUserGroup(&remote)
+			return err
+		})
+		output.Emit(objects.UserGroupEvent(&remote, objects.ActionDelete, nil), "SYNC Deleted User Group: %v\n", remote.Name)
+		return nil
+	})
+}
@@ -0,0 +1,176 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/rollback"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeUserDashboard stores users and user groups in memory, in the way the dashboard's API does.
+type fakeUserDashboard struct {
+	mu     sync.Mutex
+	nextID int
+	users  map[string]objects.User
+	groups map[string]objects.UserGroup
+}
+
+func (d *fakeUserDashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	isGroups := strings.HasPrefix(r.URL.Path, endpointUserGroups)
+	prefix := endpointUsers
+	if isGroups {
+		prefix = endpointUserGroups
+	}
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, prefix), "/")
+
+	switch r.Method {
+	case http.MethodGet:
+		if isGroups {
+			resp := objects.UserGroupsResponse{Groups: []objects.UserGroup{}}
+			for _, group := range d.groups {
+				resp.Groups = append(resp.Groups, group)
+			}
+			_ = json.NewEncoder(w).Encode(resp)
+			return
+		}
+		resp := objects.UsersResponse{Users: []objects.User{}}
+		for _, user := range d.users {
+			resp.Users = append(resp.Users, user)
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	case http.MethodPost, http.MethodPut:
+		if r.Method == http.MethodPost {
+			d.nextID++
+			id = fmt.Sprintf("new-%v", d.nextID)
+		}
+		if isGroups {
+			group := objects.UserGroup{}
+			_ = json.NewDecoder(r.Body).Decode(&group)
+			group.ID = id
+			d.groups[id] = group
+			_, _ = w.Write([]byte(`{"Status":"OK","Message":"User group created","Meta":"` + id + `"}`))
+			return
+		}
+		user := objects.User{}
+		_ = json.NewDecoder(r.Body).Decode(&user)
+		user.ID = id
+		d.users[id] = user
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"Status": "OK", "Message": "User created", "Meta": user})
+		return
+	case http.MethodDelete:
+		delete(d.users, id)
+		delete(d.groups, id)
+	}
+	_, _ = w.Write([]byte(`{"Status":"OK","Message":"done"}`))
+}
+
+func TestClient_PlanUsers(t *testing.T) {
+	d := &fakeUserDashboard{
+		users: map[string]objects.User{
+			"u1": {ID: "u1", EmailAddress: "alice@example.com", FirstName: "Alice", Active: true, AccessKey: "alice-key"},
+			"u2": {ID: "u2", EmailAddress: "carol@example.com", AccessKey: "carol-key"},
+			"u3": {ID: "u3", EmailAddress: "sync@example.com", AccessKey: "s3cret"},
+		},
+		groups: map[string]objects.UserGroup{
+			"g1": {ID: "g1", Name: "devs"},
+			"g2": {ID: "g2", Name: "old"},
+		},
+	}
+	server := httptest.NewServer(d)
+	defer server.Close()
+
+	c := &Client{url: server.URL, secret: "s3cret", OrgID: "org"}
+
+	users := []objects.User{
+		{EmailAddress: "ALICE@example.com", FirstName: "Alice", LastName: "Smith", Active: true, Group: "devs"},
+		{EmailAddress: "bob@example.com", Active: true, Group: "ops"},
+	}
+	groups := []objects.UserGroup{
+		{Name: "devs", UserPermissions: map[string]string{"apis": "write"}},
+		{Name: "ops", UserPermissions: map[string]string{"apis": "read"}},
+	}
+
+	plan := &objects.SyncPlan{Version: objects.PlanVersion}
+	if err := c.PlanUsers(plan, users, groups); err != nil {
+		t.Fatal(err)
+	}
+
+	actions := map[string]objects.ChangeAction{}
+	for _, change := range plan.UserGroups {
+		actions["group "+change.UserGroup().Name] = change.Action
+	}
+	for _, change := range plan.Users {
+		actions["user "+strings.ToLower(change.User().EmailAddress)] = change.Action
+	}
+	// The user the sync runs as is kept even though it isn't in the repo
+	assert.Equal(t, map[string]objects.ChangeAction{
+		"group devs":             objects.ActionUpdate,
+		"group ops":              objects.ActionCreate,
+		"group old":              objects.ActionDelete,
+		"user alice@example.com": objects.ActionUpdate,
+		"user bob@example.com":   objects.ActionCreate,
+		"user carol@example.com": objects.ActionDelete,
+	}, actions)
+	assert.NotEmpty(t, plan.UsersFingerprint)
+
+	// Secrets never end up in the plan
+	for _, change := range plan.Users {
+		if change.Remote != nil {
+			assert.Empty(t, change.Remote.AccessKey)
+		}
+	}
+
+	if err := c.applyUserChanges(plan, &rollback.Journal{}); err != nil {
+		t.Fatal(err)
+	}
+
+	opsID := ""
+	groupNames := []string{}
+	for id, group := range d.groups {
+		groupNames = append(groupNames, group.Name)
+		if group.Name == "ops" {
+			opsID = id
+		}
+	}
+	assert.ElementsMatch(t, []string{"devs", "ops"}, groupNames)
+	assert.Equal(t, "write", d.groups["g1"].UserPermissions["apis"])
+
+	assert.Len(t, d.users, 3)
+	assert.Equal(t, "Smith", d.users["u1"].LastName)
+	assert.Equal(t, "g1", d.users["u1"].GroupID)
+	assert.Contains(t, d.users, "u3")
+	for _, user := range d.users {
+		if user.EmailAddress == "bob@example.com" {
+			assert.Equal(t, opsID, user.GroupID)
+		}
+	}
+
+	// Users can only be put in groups that will exist after the sync
+	plan = &objects.SyncPlan{Version: objects.PlanVersion}
+	err := c.PlanUsers(plan, []objects.User{{EmailAddress: "dan@example.com", Group: "old"}}, groups)
+	assert.Error(t, err)
+
+	// A sync with an owner never deletes the users and groups it doesn't list
+	c.Owner = "team-a"
+	plan = &objects.SyncPlan{Version: objects.PlanVersion}
+	if err := c.PlanUsers(plan, users[:1], groups[:1]); err != nil {
+		t.Fatal(err)
+	}
+	for _, change := range plan.UserGroups {
+		assert.NotEqual(t, objects.ActionDelete, change.Action, change.UserGroup().Name)
+	}
+	for _, change := range plan.Users {
+		assert.NotEqual(t, objects.ActionDelete, change.Action, change.User().EmailAddress)
+	}
+}
@@ -2,10 +2,10 @@ package gateway
 
 import (
 	"bytes"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/levigross/grequests"
 	"github.com/ongoingio/urljoin"
 	"io"
 	"io/ioutil"
@@ -31,14 +31,16 @@ func (c *Client) CreateCertificate(cert []byte) (string, error) {
 	}
 
 	req, err := http.NewRequest("POST", fullPath, body)
+	if err != nil {
+		return "", err
+	}
 	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("X-Tyk-Authorization", c.secret)
 
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify},
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return "", err
 	}
-	client := &http.Client{Transport: tr}
-	resp, err := client.Do(req)
+	defer resp.Body.Close()
 
 	rBody, _ := ioutil.ReadAll(resp.Body)
 	if resp.StatusCode != 200 {
@@ -56,3 +58,68 @@ func (c *Client) CreateCertificate(cert []byte) (string, error) {
 
 	return dbResp.Id, nil
 }
+
+// FetchCertificates returns the metadata of every certificate stored on the gateway.
+func (c *Client) FetchCertificates() ([]objects.CertificateMeta, error) {
+	fullPath := urljoin.Join(c.url, endpointCerts)
+
+	resp, err := grequests.Get(fullPath, &grequests.RequestOptions{
+		Params:     map[string]string{"mode": "detailed"},
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	certs := objects.CertificateList{}
+	if err := resp.JSON(&certs); err != nil {
+		return nil, err
+	}
+
+	return certs.Certs, nil
+}
+
+// FetchCertificate returns the metadata of the certificate with the given ID.
+func (c *Client) FetchCertificate(id string) (*objects.CertificateMeta, error) {
+	fullPath := urljoin.Join(c.url, endpointCerts, id)
+
+	resp, err := grequests.Get(fullPath, &grequests.RequestOptions{
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	cert := objects.CertificateMeta{}
+	if err := resp.JSON(&cert); err != nil {
+		return nil, err
+	}
+
+	return &cert, nil
+}
+
+// DeleteCertificate removes the certificate with the given ID from the gateway.
+func (c *Client) DeleteCertificate(id string) error {
+	fullPath := urljoin.Join(c.url, endpointCerts, id)
+
+	resp, err := grequests.Delete(fullPath, &grequests.RequestOptions{
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	return nil
+}
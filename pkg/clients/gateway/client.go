@@ -4,9 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/pool"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/rest"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/rollback"
 	"github.com/AaronFeledy/tyk-ops/pkg/output"
 
 	"encoding/json"
+	"net/http"
+	"sync"
 
 	"github.com/gofrs/uuid"
 	"github.com/levigross/grequests"
@@ -16,11 +21,27 @@ import (
 type Client struct {
 	url    string
 	secret string
-	// InsecureSkipVerify is a flag that specifies if we should validate the
-	// server's TLS certificate.
-	InsecureSkipVerify bool
+	// TLS sets how the TLS connections to the gateway are made.
+	rest.TLS
 	// Skip creating APIs if they already exist
 	SkipExisting bool
+	// Owner restricts syncs to the APIs stamped with this owner, and stamps the APIs they create or
+	// update. Syncs manage every API when it is empty.
+	Owner string
+	// Parallel is the number of APIs created, updated or deleted at once, they are handled one at a
+	// time when it is 1 or less.
+	Parallel int
+	// HTTP sets the timeouts, retries and rate limit of the requests made to the gateway.
+	HTTP rest.Options
+	// Proxy is the URL of the proxy the requests to the gateway are sent through, the proxy environment
+	// variables are used when it is empty.
+	Proxy string
+	// PoliciesFile is the policies file of a gateway that reads its policies from a file. Policies
+	// are written to it, rather than through the gateway's policy API, when it is set.
+	PoliciesFile string
+
+	httpClient     rest.Lazy
+	policiesFileMu sync.Mutex
 }
 
 const (
@@ -28,6 +49,10 @@ const (
 	endpointCerts    string = "/tyk/certs"
 	reloadAPIs       string = "/tyk/reload/group"
 	endpointPolicies string = "/tyk/policies"
+	endpointKeys     string = "/tyk/keys"
+
+	// authHeader is the header the gateway secret is sent in
+	authHeader = "X-Tyk-Authorization"
 )
 
 var (
@@ -64,10 +89,9 @@ func (c *Client) FetchAPIs() ([]objects.DBApiDefinition, error) {
 
 	ro := &grequests.RequestOptions{
 		Headers: map[string]string{
-			"x-tyk-authorization": c.secret,
-			"content-type":        "application/json",
+			"content-type": "application/json",
 		},
-		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient: c.client(),
 	}
 
 	resp, err := grequests.Get(fullPath, ro)
@@ -109,6 +133,9 @@ func getAPIsIdentifiers(apiDefs *[]objects.DBApiDefinition) (map[string]*objects
 	return apiids, ids, slugs, paths
 }
 
+// CreateAPIs creates APIs on the gateway, up to c.Parallel at once. Nothing is created if any of
+// the APIs already exists, unless SkipExisting is set. Every API is attempted even if some fail,
+// the failures are returned as a *pool.MultiError.
 func (c *Client) CreateAPIs(apiDefs *[]objects.DBApiDefinition) error {
 	existingAPIs, err := c.FetchAPIs()
 	if err != nil {
@@ -118,78 +145,71 @@ func (c *Client) CreateAPIs(apiDefs *[]objects.DBApiDefinition) error {
 	apiids, ids, slugs, paths := getAPIsIdentifiers(&existingAPIs)
 
 	var existsCount int64
+	createAPIs := make([]objects.DBApiDefinition, 0)
 	for i := range *apiDefs {
 		apiDef := (*apiDefs)[i]
-		fmt.Printf("Creating API %v: %v\n", i, apiDef.Name)
+		output.Printf("Creating API %v: %v\n", i, apiDef.Name)
 		var existsError error
 		if thisAPI, ok := apiids[apiDef.APIID]; ok && thisAPI != nil {
-			fmt.Println("Warning: API ID Exists")
+			output.Println("Warning: API ID Exists")
 			existsError = UseUpdateError
 		} else if thisAPI, ok := ids[apiDef.Id.Hex()]; ok && thisAPI != nil {
-			fmt.Println("Warning: Object ID Exists")
+			output.Println("Warning: Object ID Exists")
 			existsError = UseUpdateError
 		} else if thisAPI, ok := slugs[apiDef.Slug]; ok && thisAPI != nil {
-			fmt.Println("Warning: Slug Exists")
+			output.Println("Warning: Slug Exists")
 			existsError = UseUpdateError
 		} else if thisAPI, ok := paths[apiDef.Proxy.ListenPath+"-"+apiDef.Domain]; ok && thisAPI != nil {
-			fmt.Println("Warning: Listen Path Exists")
+			output.Println("Warning: Listen Path Exists")
 			existsError = UseUpdateError
 		}
 
 		if existsError != nil {
 			if c.SkipExisting {
 				existsCount++
+				event := objects.APIEvent(&apiDef, objects.ActionCreate, nil)
+				event.Result = output.ResultSkipped
+				output.Emit(event, "")
 				continue
 			}
+			output.Emit(objects.APIEvent(&apiDef, objects.ActionCreate, existsError), "")
 			return existsError
 		}
 
-		data, err := json.Marshal(apiDef.APIDefinition)
-		if err != nil {
-			return err
+		// Add the API to the existing API list, so that duplicates within apiDefs are caught too.
+		if apiDef.APIID != "" {
+			apiids[apiDef.APIID] = &apiDef
 		}
-
-		// Create
-		fullPath := urljoin.Join(c.url, endpointAPIs)
-		createResp, err := grequests.Post(fullPath, &grequests.RequestOptions{
-			JSON: data,
-			Headers: map[string]string{
-				"x-tyk-authorization": c.secret,
-				"content-type":        "application/json",
-			},
-			InsecureSkipVerify: c.InsecureSkipVerify,
-		})
-
-		if err != nil {
-			return err
+		if apiDef.Slug != "" {
+			slugs[apiDef.Slug] = &apiDef
 		}
+		paths[apiDef.Proxy.ListenPath+"-"+apiDef.Domain] = &apiDef
 
-		if createResp.StatusCode != 200 {
-			return fmt.Errorf("API Returned error: %v (code: %v)", createResp.String(), createResp.StatusCode)
-		}
+		createAPIs = append(createAPIs, apiDef)
+	}
 
-		var status APIMessage
-		if err := createResp.JSON(&status); err != nil {
-			return err
+	err = pool.Run(len(createAPIs), c.Parallel, func(i int) error {
+		apiDef := createAPIs[i]
+		if err := c.postAPI(&apiDef); err != nil {
+			output.Emit(objects.APIEvent(&apiDef, objects.ActionCreate, err), "")
+			return fmt.Errorf("creating API %v: %v", apiDef.Name, err)
 		}
 
-		if status.Status != "ok" {
-			return fmt.Errorf("API request completed, but with error: %v", status.Message)
-		}
+		output.Emit(objects.APIEvent(&apiDef, objects.ActionCreate, nil), "--> Status: OK, ID:%v\n", apiDef.APIID)
+		return nil
+	})
 
-		// initiate a reload
+	// initiate a reload
+	if len(createAPIs) > 0 {
 		go c.Reload()
+	}
 
-		// Add updated API to existing API list.
-		apiids[apiDef.APIID] = &apiDef
-		slugs[apiDef.Slug] = &apiDef
-		paths[apiDef.Proxy.ListenPath+"-"+apiDef.Domain] = &apiDef
-
-		fmt.Printf("--> Status: OK, ID:%v\n", apiDef.APIID)
+	if err != nil {
+		return err
 	}
 
 	if existsCount > 0 {
-		output.User.Println("%v APIs already exist and were skipped", existsCount)
+		output.User.Printf("%v APIs already exist and were skipped\n", existsCount)
 	}
 
 	return nil
@@ -197,13 +217,10 @@ func (c *Client) CreateAPIs(apiDefs *[]objects.DBApiDefinition) error {
 
 func (c *Client) Reload() error {
 	// Reload
-	fmt.Println("Reloading...")
+	output.Println("Reloading...")
 	fullPath := urljoin.Join(c.url, reloadAPIs)
 	reloadREsp, err := grequests.Get(fullPath, &grequests.RequestOptions{
-		Headers: map[string]string{
-			"x-tyk-authorization": c.secret,
-		},
-		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient: c.client(),
 	})
 
 	if err != nil {
@@ -220,12 +237,15 @@ func (c *Client) Reload() error {
 	}
 
 	if status.Status != "ok" {
-		fmt.Errorf("API request completed, but with error: %v", status.Message)
+		return fmt.Errorf("API request completed, but with error: %v", status.Message)
 	}
 
 	return nil
 }
 
+// UpdateAPIs updates the matching APIs on the gateway, up to c.Parallel at once. Nothing is
+// updated if any of the APIs can't be found. Every API is attempted even if some fail, the
+// failures are returned as a *pool.MultiError.
 func (c *Client) UpdateAPIs(apiDefs *[]objects.DBApiDefinition) error {
 	existingAPIs, err := c.FetchAPIs()
 	if err != nil {
@@ -234,9 +254,10 @@ func (c *Client) UpdateAPIs(apiDefs *[]objects.DBApiDefinition) error {
 
 	apiids, ids, slugs, paths := getAPIsIdentifiers(&existingAPIs)
 
+	updateAPIs := make([]objects.DBApiDefinition, 0, len(*apiDefs))
 	for i := range *apiDefs {
 		apiDef := (*apiDefs)[i]
-		fmt.Printf("Updating API %v: %v\n", i, apiDef.Name)
+		output.Printf("Updating API %v: %v\n", i, apiDef.Name)
 		if thisAPI, ok := apiids[apiDef.APIID]; ok && thisAPI != nil {
 			apiDef.Id = thisAPI.Id
 		} else if thisAPI, ok := ids[apiDef.Id.Hex()]; ok && thisAPI != nil {
@@ -258,67 +279,125 @@ func (c *Client) UpdateAPIs(apiDefs *[]objects.DBApiDefinition) error {
 				apiDef.Id = thisAPI.Id
 			}
 		} else {
+			output.Emit(objects.APIEvent(&apiDef, objects.ActionUpdate, UseCreateError), "")
 			return UseCreateError
 		}
 
-		// Update
 		if apiDef.APIID == "" {
 			return errors.New("API ID must be set")
 		}
 
-		data, err := json.Marshal(apiDef.APIDefinition)
-		if err != nil {
-			return err
-		}
+		// Add updated API to existing API list.
+		apiids[apiDef.APIID] = &apiDef
+		ids[apiDef.Id.Hex()] = &apiDef
+		slugs[apiDef.Slug] = &apiDef
+		paths[apiDef.Proxy.ListenPath+"-"+apiDef.Domain] = &apiDef
 
-		updatePath := urljoin.Join(c.url, endpointAPIs, apiDef.APIID)
-		uResp, err := grequests.Put(updatePath, &grequests.RequestOptions{
-			JSON: data,
-			Headers: map[string]string{
-				"x-tyk-authorization": c.secret,
-				"content-type":        "application/json",
-			},
-			Params: map[string]string{
-				"accept_additional_properties": "true",
-			},
-			InsecureSkipVerify: c.InsecureSkipVerify,
-		})
+		updateAPIs = append(updateAPIs, apiDef)
+	}
 
-		if err != nil {
-			return err
+	err = pool.Run(len(updateAPIs), c.Parallel, func(i int) error {
+		apiDef := updateAPIs[i]
+		if err := c.putAPI(&apiDef); err != nil {
+			output.Emit(objects.APIEvent(&apiDef, objects.ActionUpdate, err), "")
+			return fmt.Errorf("updating API %v: %v", apiDef.Name, err)
 		}
 
-		if uResp.StatusCode != 200 {
-			return fmt.Errorf("API updating returned error: %v (code: %v)", uResp.String(), uResp.StatusCode)
-		}
+		output.Emit(objects.APIEvent(&apiDef, objects.ActionUpdate, nil), "--> Status: OK, ID:%v\n", apiDef.APIID)
+		return nil
+	})
 
-		// initiate a reload
+	// initiate a reload
+	if len(updateAPIs) > 0 {
 		go c.Reload()
+	}
 
-		// Add updated API to existing API list.
-		apiids[apiDef.APIID] = &apiDef
-		ids[apiDef.Id.Hex()] = &apiDef
-		slugs[apiDef.Slug] = &apiDef
-		paths[apiDef.Proxy.ListenPath+"-"+apiDef.Domain] = &apiDef
+	return err
+}
 
-		fmt.Printf("--> Status: OK, ID:%v\n", apiDef.APIID)
+// postAPI creates an API on the gateway, keeping the API ID set in apiDef.
+func (c *Client) postAPI(apiDef *objects.DBApiDefinition) error {
+	data, err := json.Marshal(apiDef.APIDefinition)
+	if err != nil {
+		return err
+	}
+
+	fullPath := urljoin.Join(c.url, endpointAPIs)
+	createResp, err := grequests.Post(fullPath, &grequests.RequestOptions{
+		JSON: data,
+		Headers: map[string]string{
+			"content-type": "application/json",
+		},
+		HTTPClient: c.client(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if createResp.StatusCode != 200 {
+		return fmt.Errorf("API Returned error: %v (code: %v)", createResp.String(), createResp.StatusCode)
+	}
+
+	var status APIMessage
+	if err := createResp.JSON(&status); err != nil {
+		return err
+	}
+
+	if status.Status != "ok" {
+		return fmt.Errorf("API request completed, but with error: %v", status.Message)
 	}
 
 	return nil
 }
 
-func (c *Client) SyncAPIs(apiDefs []objects.DBApiDefinition) error {
-	deleteAPIs := []string{}
-	updateAPIs := []objects.DBApiDefinition{}
-	createAPIs := []objects.DBApiDefinition{}
+// putAPI replaces the gateway API with the same API ID as apiDef.
+func (c *Client) putAPI(apiDef *objects.DBApiDefinition) error {
+	data, err := json.Marshal(apiDef.APIDefinition)
+	if err != nil {
+		return err
+	}
+
+	updatePath := urljoin.Join(c.url, endpointAPIs, apiDef.APIID)
+	uResp, err := grequests.Put(updatePath, &grequests.RequestOptions{
+		JSON: data,
+		Headers: map[string]string{
+			"content-type": "application/json",
+		},
+		Params: map[string]string{
+			"accept_additional_properties": "true",
+		},
+		HTTPClient: c.client(),
+	})
 
-	apis, err := c.FetchAPIs()
 	if err != nil {
 		return err
 	}
 
+	if uResp.StatusCode != 200 {
+		return fmt.Errorf("API updating returned error: %v (code: %v)", uResp.String(), uResp.StatusCode)
+	}
+
+	return nil
+}
+
+// PlanAPIs works out which APIs a sync would delete, update and create on the gateway without
+// making any changes. APIs are matched on their API ID.
+func (c *Client) PlanAPIs(apiDefs []objects.DBApiDefinition) ([]objects.APIChange, error) {
+	apis, err := c.FetchAPIs()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.planAPIs(apis, apiDefs)
+}
+
+func (c *Client) planAPIs(apis []objects.DBApiDefinition, apiDefs []objects.DBApiDefinition) ([]objects.APIChange, error) {
+	changes := []objects.APIChange{}
+
 	GWIDMap := map[string]int{}
 	GitIDMap := map[string]int{}
+	gitKeys := make([]string, len(apiDefs))
 
 	// Build the gw ID map
 	for i, api := range apis {
@@ -329,71 +408,139 @@ func (c *Client) SyncAPIs(apiDefs []objects.DBApiDefinition) error {
 	// Build the Git ID Map
 	for i, def := range apiDefs {
 		if def.APIID != "" {
-			GitIDMap[def.APIID] = i
+			gitKeys[i] = def.APIID
 		} else {
 			uid, err := uuid.NewV4()
 			if err != nil {
-				fmt.Println("error generating UUID", err)
-				return err
+				output.Println("error generating UUID", err)
+				return nil, err
 			}
-			created := fmt.Sprintf("temp-%v", uid.String())
-			GitIDMap[created] = i
+			gitKeys[i] = fmt.Sprintf("temp-%v", uid.String())
 		}
+		GitIDMap[gitKeys[i]] = i
 	}
 
-	// Updates are when we find items in git that are also in dash
-	for key, index := range GitIDMap {
-		_, ok := GWIDMap[key]
-		if ok {
-			updateAPIs = append(updateAPIs, apiDefs[index])
+	// Deletes are when we find items in the gateway that are not in git
+	for i, api := range apis {
+		if GWIDMap[api.APIID] != i {
+			continue
+		}
+		if _, ok := GitIDMap[api.APIID]; !ok {
+			remote := apis[i]
+			changes = append(changes, objects.APIChange{Action: objects.ActionDelete, Remote: &remote})
 		}
 	}
 
-	// Deletes are when we find items in the dash that are not in git
-	for key, _ := range GWIDMap {
-		_, ok := GitIDMap[key]
+	// Updates are when we find items in git that are also in the gateway, creates are when we find
+	// things in git that are not in the gateway
+	for i, key := range gitKeys {
+		if GitIDMap[key] != i {
+			continue
+		}
+
+		local := apiDefs[i]
+		gwIndex, ok := GWIDMap[key]
 		if !ok {
-			deleteAPIs = append(deleteAPIs, key)
+			changes = append(changes, objects.APIChange{Action: objects.ActionCreate, Local: &local})
+			continue
 		}
+
+		remote := apis[gwIndex]
+		changes = append(changes, objects.APIChange{Action: objects.ActionUpdate, Local: &local, Remote: &remote})
 	}
 
-	// Create operations are when we find things in Git that are not in the dashboard
-	for key, index := range GitIDMap {
-		_, ok := GWIDMap[key]
-		if !ok {
-			createAPIs = append(createAPIs, apiDefs[index])
+	return objects.ClaimAPIChanges(changes, c.Owner)
+}
+
+// SyncAPIs makes the gateway's APIs match apiDefs. If any change fails, the changes already made
+// are rolled back and a *rollback.Error is returned.
+func (c *Client) SyncAPIs(apiDefs []objects.DBApiDefinition) error {
+	changes, err := c.PlanAPIs(apiDefs)
+	if err != nil {
+		return err
+	}
+
+	journal := &rollback.Journal{}
+	if err := c.applyAPIChanges(changes, journal); err != nil {
+		return journal.Rollback(err)
+	}
+
+	return nil
+}
+
+// applyAPIChanges makes the planned API changes, recording how to revert each one in the journal.
+// Deletes, updates and creates are made in that order, up to c.Parallel at once. Every change of a
+// kind is attempted even if some fail, but the next kind is only started if they all succeeded.
+func (c *Client) applyAPIChanges(changes []objects.APIChange, journal *rollback.Journal) error {
+	deleteAPIs := []objects.DBApiDefinition{}
+	updateAPIs := []objects.APIChange{}
+	createAPIs := []objects.DBApiDefinition{}
+
+	for _, change := range changes {
+		switch change.Action {
+		case objects.ActionDelete:
+			deleteAPIs = append(deleteAPIs, *change.Remote)
+		case objects.ActionUpdate:
+			updateAPIs = append(updateAPIs, change)
+		case objects.ActionCreate:
+			createAPIs = append(createAPIs, *change.Local)
 		}
 	}
 
-	fmt.Printf("Deleting: %v\n", len(deleteAPIs))
-	fmt.Printf("Updating: %v\n", len(updateAPIs))
-	fmt.Printf("Creating: %v\n", len(createAPIs))
+	output.Printf("Deleting: %v\n", len(deleteAPIs))
+	output.Printf("Updating: %v\n", len(updateAPIs))
+	output.Printf("Creating: %v\n", len(createAPIs))
 
 	// Do the deletes
-	for _, dbId := range deleteAPIs {
-		fmt.Printf("SYNC Deleting: %v\n", dbId)
-		if err := c.deleteAPI(dbId); err != nil {
-			return err
+	err := pool.Run(len(deleteAPIs), c.Parallel, func(i int) error {
+		remote := deleteAPIs[i]
+		if err := c.deleteAPI(remote.APIID); err != nil {
+			output.Emit(objects.APIEvent(&remote, objects.ActionDelete, err), "")
+			return fmt.Errorf("deleting API %v: %v", remote.Name, err)
 		}
+		journal.Record(fmt.Sprintf("Restored deleted API: %v", remote.Name), func() error {
+			return c.postAPI(&remote)
+		})
+		output.Emit(objects.APIEvent(&remote, objects.ActionDelete, nil), "SYNC Deleted: %v\n", remote.APIID)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	// Do the updates
-	if err := c.UpdateAPIs(&updateAPIs); err != nil {
+	err = pool.Run(len(updateAPIs), c.Parallel, func(i int) error {
+		local, remote := *updateAPIs[i].Local, *updateAPIs[i].Remote
+		if local.APIID == "" {
+			return errors.New("API ID must be set")
+		}
+		if err := c.putAPI(&local); err != nil {
+			output.Emit(objects.APIEvent(&local, objects.ActionUpdate, err), "")
+			return fmt.Errorf("updating API %v: %v", local.Name, err)
+		}
+		journal.Record(fmt.Sprintf("Reverted updated API: %v", remote.Name), func() error {
+			return c.putAPI(&remote)
+		})
+		output.Emit(objects.APIEvent(&local, objects.ActionUpdate, nil), "SYNC Updated: %v\n", local.APIID)
+		return nil
+	})
+	if err != nil {
 		return err
 	}
-	for _, apiDef := range updateAPIs {
-		fmt.Printf("SYNC Updated: %v\n", apiDef.APIID)
-	}
 
 	// Do the creates
-	if err := c.CreateAPIs(&createAPIs); err != nil {
-		return err
-	}
-	for _, apiDef := range updateAPIs {
-		fmt.Printf("SYNC Created: %v\n", apiDef.Name)
-	}
-
-	return nil
+	return pool.Run(len(createAPIs), c.Parallel, func(i int) error {
+		local := createAPIs[i]
+		if err := c.postAPI(&local); err != nil {
+			output.Emit(objects.APIEvent(&local, objects.ActionCreate, err), "")
+			return fmt.Errorf("creating API %v: %v", local.Name, err)
+		}
+		journal.Record(fmt.Sprintf("Removed created API: %v", local.Name), func() error {
+			return c.deleteAPI(local.APIID)
+		})
+		output.Emit(objects.APIEvent(&local, objects.ActionCreate, nil), "SYNC Created: %v\n", local.Name)
+		return nil
+	})
 }
 
 func (c *Client) DeleteAPI(id string) error {
@@ -406,10 +553,9 @@ func (c *Client) deleteAPI(id string) error {
 
 	delResp, err := grequests.Delete(delPath, &grequests.RequestOptions{
 		Headers: map[string]string{
-			"x-tyk-authorization": c.secret,
-			"content-type":        "application/json",
+			"content-type": "application/json",
 		},
-		InsecureSkipVerify: c.InsecureSkipVerify,
+		HTTPClient: c.client(),
 	})
 
 	if err != nil {
@@ -425,3 +571,10 @@ func (c *Client) deleteAPI(id string) error {
 
 	return nil
 }
+
+// client returns the HTTP client that requests to the gateway are made with, see rest.Lazy.
+func (c *Client) client() *http.Client {
+	return c.httpClient.Client(func() rest.Config {
+		return rest.Config{AuthHeader: authHeader, Secret: c.secret, TLS: c.TLS, Proxy: c.Proxy, HTTP: c.HTTP}
+	})
+}
@@ -0,0 +1,144 @@
+package gateway
+
+import (
+	"fmt"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/levigross/grequests"
+	"github.com/ongoingio/urljoin"
+)
+
+// CreateKey creates a key with the given session, returning the key. The gateway generates the key
+// unless one is given.
+func (c *Client) CreateKey(key string, session *objects.Session) (string, error) {
+	fullPath := urljoin.Join(c.url, endpointKeys, "create")
+	if key != "" {
+		fullPath = urljoin.Join(c.url, endpointKeys, key)
+	}
+
+	resp, err := grequests.Post(fullPath, &grequests.RequestOptions{
+		JSON:       session,
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	status, err := keyResponse(resp)
+	if err != nil {
+		return "", err
+	}
+
+	return status.Key, nil
+}
+
+// FetchKey returns the session of a key.
+func (c *Client) FetchKey(key string) (*objects.Session, error) {
+	return c.fetchKey(key, false)
+}
+
+// FetchKeyHash returns the session of the key with the given hash, as listed by gateways that hash
+// keys.
+func (c *Client) FetchKeyHash(hash string) (*objects.Session, error) {
+	return c.fetchKey(hash, true)
+}
+
+func (c *Client) fetchKey(key string, hashed bool) (*objects.Session, error) {
+	fullPath := urljoin.Join(c.url, endpointKeys, key)
+
+	ro := &grequests.RequestOptions{
+		HTTPClient: c.client(),
+	}
+	if hashed {
+		ro.Params = map[string]string{"hashed": "true"}
+	}
+
+	resp, err := grequests.Get(fullPath, ro)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	session := objects.Session{}
+	if err := resp.JSON(&session); err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// UpdateKey replaces the session of a key.
+func (c *Client) UpdateKey(key string, session *objects.Session) error {
+	fullPath := urljoin.Join(c.url, endpointKeys, key)
+
+	resp, err := grequests.Put(fullPath, &grequests.RequestOptions{
+		JSON:       session,
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = keyResponse(resp)
+	return err
+}
+
+// DeleteKey removes a key from the gateway.
+func (c *Client) DeleteKey(key string) error {
+	fullPath := urljoin.Join(c.url, endpointKeys, key)
+
+	resp, err := grequests.Delete(fullPath, &grequests.RequestOptions{
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = keyResponse(resp)
+	return err
+}
+
+// FetchKeys returns the keys on the gateway. Gateways that hash keys only list them when
+// enable_hashed_keys_listing is set, and then list their hashes.
+func (c *Client) FetchKeys() ([]string, error) {
+	fullPath := urljoin.Join(c.url, endpointKeys)
+
+	resp, err := grequests.Get(fullPath, &grequests.RequestOptions{
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	keys := objects.KeyList{}
+	if err := resp.JSON(&keys); err != nil {
+		return nil, err
+	}
+
+	return keys.Keys, nil
+}
+
+// keyResponse checks the response of a change to a key.
+func keyResponse(resp *grequests.Response) (*APIMessage, error) {
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v (code: %v)", resp.String(), resp.StatusCode)
+	}
+
+	var status APIMessage
+	if err := resp.JSON(&status); err != nil {
+		return nil, err
+	}
+
+	if status.Status != "ok" {
+		return nil, fmt.Errorf("API request completed, but with error: %v", status.Message)
+	}
+
+	return &status, nil
+}
@@ -0,0 +1,462 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/pool"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/rollback"
+	"github.com/AaronFeledy/tyk-ops/pkg/output"
+
+	"github.com/levigross/grequests"
+	"github.com/ongoingio/urljoin"
+)
+
+// PolicyAPIUnsupportedError is returned when the gateway has no policy API, which is the case for
+// gateways older than 5.0.
+var PolicyAPIUnsupportedError = errors.New("the gateway doesn't support the policy API, set a policies file to write the policies to instead")
+
+// FetchPolicies returns the gateway's policies, read from PoliciesFile when it is set.
+func (c *Client) FetchPolicies() ([]objects.Policy, error) {
+	if c.PoliciesFile != "" {
+		filePols, err := c.readPoliciesFile()
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]string, 0, len(filePols))
+		for id := range filePols {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		pols := make([]objects.Policy, len(ids))
+		for i, id := range ids {
+			pols[i] = filePols[id]
+		}
+		return pols, nil
+	}
+
+	fullPath := urljoin.Join(c.url, endpointPolicies)
+	resp, err := grequests.Get(fullPath, &grequests.RequestOptions{
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, PolicyAPIUnsupportedError
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("API Returned error: %v", resp.String())
+	}
+
+	pols := []objects.Policy{}
+	if err := resp.JSON(&pols); err != nil {
+		return nil, err
+	}
+
+	return pols, nil
+}
+
+// policyID returns the ID the gateway stores a policy under.
+func policyID(pol objects.Policy) string {
+	if pol.ID != "" {
+		return pol.ID
+	}
+	return pol.MID.Hex()
+}
+
+func getPoliciesIdentifiers(pols []objects.Policy) map[string]*objects.Policy {
+	ids := make(map[string]*objects.Policy)
+	for i := range pols {
+		pol := pols[i]
+		ids[policyID(pol)] = &pol
+	}
+
+	return ids
+}
+
+// CreatePolicies creates policies on the gateway, up to c.Parallel at once, in the same way as
+// CreateAPIs. Policies are matched on their ID, or on their DB ID when they have none.
+func (c *Client) CreatePolicies(pols *[]objects.Policy) error {
+	existingPols, err := c.FetchPolicies()
+	if err != nil {
+		return err
+	}
+
+	ids := getPoliciesIdentifiers(existingPols)
+
+	var existsCount int64
+	createPols := make([]objects.Policy, 0)
+	for i := range *pols {
+		pol := (*pols)[i]
+		output.Printf("Creating Policy %v: %v\n", i, pol.Name)
+		if policyID(pol) == "" {
+			return errors.New("Policies must have an ID to be created on a gateway")
+		}
+
+		if thisPol, ok := ids[policyID(pol)]; ok && thisPol != nil {
+			output.Println("Warning: Policy ID Exists")
+			if c.SkipExisting {
+				existsCount++
+				event := objects.PolicyEvent(&pol, objects.ActionCreate, nil)
+				event.Result = output.ResultSkipped
+				output.Emit(event, "")
+				continue
+			}
+			output.Emit(objects.PolicyEvent(&pol, objects.ActionCreate, UseUpdateError), "")
+			return UseUpdateError
+		}
+
+		// Add the policy to the existing policies, so that duplicates within pols are caught too.
+		ids[policyID(pol)] = &pol
+		createPols = append(createPols, pol)
+	}
+
+	err = pool.Run(len(createPols), c.Parallel, func(i int) error {
+		pol := createPols[i]
+		if err := c.postPolicy(&pol); err != nil {
+			output.Emit(objects.PolicyEvent(&pol, objects.ActionCreate, err), "")
+			return fmt.Errorf("creating policy %v: %v", pol.Name, err)
+		}
+
+		output.Emit(objects.PolicyEvent(&pol, objects.ActionCreate, nil), "--> Status: OK, ID:%v\n", policyID(pol))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if existsCount > 0 {
+		output.User.Printf("%v policies already exist and were skipped\n", existsCount)
+	}
+
+	return nil
+}
+
+// UpdatePolicies updates the matching policies on the gateway, up to c.Parallel at once, in the
+// same way as UpdateAPIs.
+func (c *Client) UpdatePolicies(pols *[]objects.Policy) error {
+	existingPols, err := c.FetchPolicies()
+	if err != nil {
+		return err
+	}
+
+	ids := getPoliciesIdentifiers(existingPols)
+
+	updatePols := make([]objects.Policy, 0, len(*pols))
+	for i := range *pols {
+		pol := (*pols)[i]
+		output.Printf("Updating Policy %v: %v\n", i, pol.Name)
+		if policyID(pol) == "" {
+			return errors.New("--> Can't update policy without an ID or explicit (legacy) ID")
+		}
+
+		if thisPol, ok := ids[policyID(pol)]; !ok || thisPol == nil {
+			output.Emit(objects.PolicyEvent(&pol, objects.ActionUpdate, UseCreateError), "")
+			return UseCreateError
+		}
+
+		updatePols = append(updatePols, pol)
+	}
+
+	return pool.Run(len(updatePols), c.Parallel, func(i int) error {
+		pol := updatePols[i]
+		if err := c.putPolicy(&pol); err != nil {
+			output.Emit(objects.PolicyEvent(&pol, objects.ActionUpdate, err), "")
+			return fmt.Errorf("updating policy %v: %v", pol.Name, err)
+		}
+
+		output.Emit(objects.PolicyEvent(&pol, objects.ActionUpdate, nil), "--> Status: OK, ID:%v\n", policyID(pol))
+		return nil
+	})
+}
+
+// PlanPolicies works out which policies a sync would delete, update and create on the gateway
+// without making any changes. Policies are matched on their ID.
+func (c *Client) PlanPolicies(pols []objects.Policy) ([]objects.PolicyChange, error) {
+	existingPols, err := c.FetchPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.planPolicies(existingPols, pols)
+}
+
+func (c *Client) planPolicies(existingPols []objects.Policy, pols []objects.Policy) ([]objects.PolicyChange, error) {
+	changes := []objects.PolicyChange{}
+
+	GWIDMap := map[string]int{}
+	GitIDMap := map[string]int{}
+
+	for i, pol := range existingPols {
+		GWIDMap[policyID(pol)] = i
+	}
+
+	for i, pol := range pols {
+		if policyID(pol) == "" {
+			return nil, fmt.Errorf("policy %v has no ID, which the gateway needs to store it", pol.Name)
+		}
+		GitIDMap[policyID(pol)] = i
+	}
+
+	// Deletes are when we find items in the gateway that are not in git
+	for i, pol := range existingPols {
+		if GWIDMap[policyID(pol)] != i {
+			continue
+		}
+		if _, ok := GitIDMap[policyID(pol)]; !ok {
+			remote := existingPols[i]
+			changes = append(changes, objects.PolicyChange{Action: objects.ActionDelete, Remote: &remote})
+		}
+	}
+
+	// Updates are when we find items in git that are also in the gateway, creates are when we find
+	// things in git that are not in the gateway
+	for i, pol := range pols {
+		if GitIDMap[policyID(pol)] != i {
+			continue
+		}
+
+		local := pols[i]
+		gwIndex, ok := GWIDMap[policyID(pol)]
+		if !ok {
+			changes = append(changes, objects.PolicyChange{Action: objects.ActionCreate, Local: &local})
+			continue
+		}
+
+		remote := existingPols[gwIndex]
+		changes = append(changes, objects.PolicyChange{Action: objects.ActionUpdate, Local: &local, Remote: &remote})
+	}
+
+	return objects.ClaimPolicyChanges(changes, c.Owner)
+}
+
+// SyncPolicies makes the gateway's policies match pols. If any change fails, the changes already
+// made are rolled back and a *rollback.Error is returned.
+func (c *Client) SyncPolicies(pols []objects.Policy) error {
+	changes, err := c.PlanPolicies(pols)
+	if err != nil {
+		return err
+	}
+
+	journal := &rollback.Journal{}
+	if err := c.applyPolicyChanges(changes, journal); err != nil {
+		return journal.Rollback(err)
+	}
+
+	return nil
+}
+
+// applyPolicyChanges makes the planned policy changes, recording how to revert each one in the
+// journal. The changes are made up to c.Parallel at once, in the same way as applyAPIChanges.
+func (c *Client) applyPolicyChanges(changes []objects.PolicyChange, journal *rollback.Journal) error {
+	deletePols := []objects.Policy{}
+	updatePols := []objects.PolicyChange{}
+	createPols := []objects.Policy{}
+
+	for _, change := range changes {
+		switch change.Action {
+		case objects.ActionDelete:
+			deletePols = append(deletePols, *change.Remote)
+		case objects.ActionUpdate:
+			updatePols = append(updatePols, change)
+		case objects.ActionCreate:
+			createPols = append(createPols, *change.Local)
+		}
+	}
+
+	output.Printf("Deleting policies: %v\n", len(deletePols))
+	output.Printf("Updating policies: %v\n", len(updatePols))
+	output.Printf("Creating policies: %v\n", len(createPols))
+
+	// Do the deletes
+	err := pool.Run(len(deletePols), c.Parallel, func(i int) error {
+		remote := deletePols[i]
+		if err := c.deletePolicy(policyID(remote)); err != nil {
+			output.Emit(objects.PolicyEvent(&remote, objects.ActionDelete, err), "")
+			return fmt.Errorf("deleting policy %v: %v", remote.Name, err)
+		}
+		journal.Record(fmt.Sprintf("Restored deleted policy: %v", remote.Name), func() error {
+			return c.postPolicy(&remote)
+		})
+		output.Emit(objects.PolicyEvent(&remote, objects.ActionDelete, nil), "SYNC Deleted Policy: %v\n", policyID(remote))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Do the updates
+	err = pool.Run(len(updatePols), c.Parallel, func(i int) error {
+		local, remote := *updatePols[i].Local, *updatePols[i].Remote
+		if err := c.putPolicy(&local); err != nil {
+			output.Emit(objects.PolicyEvent(&local, objects.ActionUpdate, err), "")
+			return fmt.Errorf("updating policy %v: %v", local.Name, err)
+		}
+		journal.Record(fmt.Sprintf("Reverted updated policy: %v", remote.Name), func() error {
+			return c.putPolicy(&remote)
+		})
+		output.Emit(objects.PolicyEvent(&local, objects.ActionUpdate, nil), "SYNC Updated Policy: %v\n", local.Name)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Do the creates
+	return pool.Run(len(createPols), c.Parallel, func(i int) error {
+		local := createPols[i]
+		if err := c.postPolicy(&local); err != nil {
+			output.Emit(objects.PolicyEvent(&local, objects.ActionCreate, err), "")
+			return fmt.Errorf("creating policy %v: %v", local.Name, err)
+		}
+		journal.Record(fmt.Sprintf("Removed created policy: %v", local.Name), func() error {
+			return c.deletePolicy(policyID(local))
+		})
+		output.Emit(objects.PolicyEvent(&local, objects.ActionCreate, nil), "SYNC Created Policy: %v\n", local.Name)
+		return nil
+	})
+}
+
+// postPolicy creates a policy on the gateway, or adds it to PoliciesFile when it is set.
+func (c *Client) postPolicy(pol *objects.Policy) error {
+	if c.PoliciesFile != "" {
+		return c.updatePoliciesFile(func(pols map[string]objects.Policy) {
+			pols[policyID(*pol)] = *pol
+		})
+	}
+
+	fullPath := urljoin.Join(c.url, endpointPolicies)
+	resp, err := grequests.Post(fullPath, &grequests.RequestOptions{
+		JSON:       pol,
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return policyResponse(resp)
+}
+
+// putPolicy replaces the gateway policy with the same ID as pol, or the one in PoliciesFile when
+// it is set.
+func (c *Client) putPolicy(pol *objects.Policy) error {
+	if c.PoliciesFile != "" {
+		return c.updatePoliciesFile(func(pols map[string]objects.Policy) {
+			pols[policyID(*pol)] = *pol
+		})
+	}
+
+	fullPath := urljoin.Join(c.url, endpointPolicies, policyID(*pol))
+	resp, err := grequests.Put(fullPath, &grequests.RequestOptions{
+		JSON:       pol,
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return policyResponse(resp)
+}
+
+// deletePolicy deletes a policy from the gateway, or from PoliciesFile when it is set.
+func (c *Client) deletePolicy(id string) error {
+	if c.PoliciesFile != "" {
+		return c.updatePoliciesFile(func(pols map[string]objects.Policy) {
+			delete(pols, id)
+		})
+	}
+
+	fullPath := urljoin.Join(c.url, endpointPolicies, id)
+	resp, err := grequests.Delete(fullPath, &grequests.RequestOptions{
+		HTTPClient: c.client(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return policyResponse(resp)
+}
+
+func (c *Client) DeletePolicy(id string) error {
+	return c.deletePolicy(id)
+}
+
+// policyResponse checks the response of a change to a policy.
+func policyResponse(resp *grequests.Response) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return PolicyAPIUnsupportedError
+	}
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("API Returned error: %v (code: %v)", resp.String(), resp.StatusCode)
+	}
+
+	var status APIMessage
+	if err := resp.JSON(&status); err != nil {
+		return err
+	}
+
+	if status.Status != "ok" {
+		return fmt.Errorf("API request completed, but with error: %v", status.Message)
+	}
+
+	return nil
+}
+
+// readPoliciesFile reads the policies in PoliciesFile, which holds them by ID in the format of the
+// gateway's file-based policies. A missing file holds no policies.
+func (c *Client) readPoliciesFile() (map[string]objects.Policy, error) {
+	pols := map[string]objects.Policy{}
+	data, err := ioutil.ReadFile(c.PoliciesFile)
+	if os.IsNotExist(err) {
+		return pols, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &pols); err != nil {
+		return nil, fmt.Errorf("reading %v: %v", c.PoliciesFile, err)
+	}
+
+	for id, pol := range pols {
+		if pol.ID == "" {
+			pol.ID = id
+			pols[id] = pol
+		}
+	}
+
+	return pols, nil
+}
+
+// updatePoliciesFile changes the policies in PoliciesFile. Changes made at once are applied one at
+// a time, so that none are lost.
+func (c *Client) updatePoliciesFile(change func(pols map[string]objects.Policy)) error {
+	c.policiesFileMu.Lock()
+	defer c.policiesFileMu.Unlock()
+
+	pols, err := c.readPoliciesFile()
+	if err != nil {
+		return err
+	}
+
+	change(pols)
+
+	data, err := json.MarshalIndent(pols, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(c.PoliciesFile, data, 0644)
+}
@@ -0,0 +1,153 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_SyncPolicies(t *testing.T) {
+	var mu sync.Mutex
+	stored := map[string]objects.Policy{
+		"gone": {ID: "gone", Name: "Gone"},
+		"gold": {ID: "gold", Name: "Gold", Rate: 1},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, endpointPolicies), "/")
+		switch r.Method {
+		case http.MethodGet:
+			pols := []objects.Policy{}
+			for _, pol := range stored {
+				pols = append(pols, pol)
+			}
+			_ = json.NewEncoder(w).Encode(pols)
+			return
+		case http.MethodPost, http.MethodPut:
+			pol := objects.Policy{}
+			if err := json.NewDecoder(r.Body).Decode(&pol); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			stored[pol.ID] = pol
+		case http.MethodDelete:
+			delete(stored, id)
+		}
+		_, _ = w.Write([]byte(`{"key":"` + id + `","status":"ok"}`))
+	}))
+	defer server.Close()
+
+	c, err := NewGatewayClient(server.URL, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.SyncPolicies([]objects.Policy{{ID: "gold", Name: "Gold", Rate: 10}, {ID: "silver", Name: "Silver"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, stored, 2)
+	assert.Equal(t, float64(10), stored["gold"].Rate)
+	assert.Equal(t, "Silver", stored["silver"].Name)
+
+	err = c.SyncPolicies([]objects.Policy{{Name: "No ID"}})
+	assert.Error(t, err)
+}
+
+func TestClient_SyncPolicies_Owner(t *testing.T) {
+	stored := map[string]objects.Policy{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode([]objects.Policy{})
+			return
+		case http.MethodPost, http.MethodPut:
+			pol := objects.Policy{}
+			_ = json.NewDecoder(r.Body).Decode(&pol)
+			stored[pol.ID] = pol
+		}
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+
+	c, err := NewGatewayClient(server.URL, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Owner = "team-a"
+
+	local := objects.Policy{ID: "gold", Name: "Gold", Tags: []string{"paid"}, MetaData: map[string]interface{}{"tier": "gold"}}
+	if err := c.SyncPolicies([]objects.Policy{local}); err != nil {
+		t.Fatal(err)
+	}
+
+	pol := stored["gold"]
+	assert.Equal(t, "team-a", pol.Owner())
+	assert.Equal(t, "gold", pol.MetaData["tier"])
+	assert.Equal(t, []string{"paid"}, pol.Tags, "the owner shouldn't be copied onto keys through the tags")
+	assert.Empty(t, local.Owner(), "stamping shouldn't change the repo's policy")
+}
+
+func TestClient_PoliciesUnsupported(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	c, err := NewGatewayClient(server.URL, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.FetchPolicies()
+	assert.Equal(t, PolicyAPIUnsupportedError, err)
+}
+
+func TestClient_PoliciesFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policies.json")
+	if err := ioutil.WriteFile(file, []byte(`{"gone": {"name": "Gone"}, "gold": {"id": "gold", "rate": 1}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The gateway isn't contacted for policies
+	c, err := NewGatewayClient("http://127.0.0.1:0", "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.PoliciesFile = file
+	c.Parallel = 4
+
+	pols, err := c.FetchPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"gold", "gone"}, []string{pols[0].ID, pols[1].ID})
+
+	want := []objects.Policy{{ID: "gold", Rate: 10}, {ID: "silver"}, {ID: "bronze"}}
+	if err := c.SyncPolicies(want); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	written := map[string]objects.Policy{}
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Len(t, written, 3)
+	assert.Equal(t, float64(10), written["gold"].Rate)
+	assert.Contains(t, written, "silver")
+	assert.Contains(t, written, "bronze")
+}
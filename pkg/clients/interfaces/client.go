@@ -13,6 +13,19 @@ type APIManagementClient interface {
 
 type CertificateManagementClient interface {
 	CreateCertificate(cert []byte) (string, error)
+	FetchCertificates() ([]objects.CertificateMeta, error)
+	FetchCertificate(id string) (*objects.CertificateMeta, error)
+	DeleteCertificate(id string) error
+}
+
+type KeyManagementClient interface {
+	CreateKey(key string, session *objects.Session) (string, error)
+	FetchKey(key string) (*objects.Session, error)
+	FetchKeyHash(hash string) (*objects.Session, error)
+	UpdateKey(key string, session *objects.Session) error
+	DeleteKey(key string) error
+	FetchKeys() ([]string, error)
+	FetchPolicies() ([]objects.Policy, error)
 }
 
 type UniversalClient interface {
@@ -4,22 +4,33 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/rest"
 	"github.com/levigross/grequests"
 	"github.com/ongoingio/urljoin"
 	"io"
 	"mime/multipart"
+	"net/http"
 	"net/textproto"
 )
 
+// authHeader is the header the mserv secret is sent in
+const authHeader = "X-Api-Key"
+
 // Client manages the connection to mserv
 type Client struct {
 	// url is the mserv endpoint URL
 	url string
 	// secret is the mserv auth token
 	secret string
-	// InsecureSkipVerify is a flag that specifies if we should validate the
-	// server's TLS certificate.
-	InsecureSkipVerify bool
+	// TLS sets how the TLS connections to mserv are made.
+	rest.TLS
+	// HTTP sets the timeouts, retries and rate limit of the requests made to mserv.
+	HTTP rest.Options
+	// Proxy is the URL of the proxy the requests to mserv are sent through, the proxy environment
+	// variables are used when it is empty.
+	Proxy string
+
+	httpClient rest.Lazy
 }
 
 // BundlePushParams is used to pass request parameters to the BundlePush() method
@@ -94,12 +105,11 @@ func (c *Client) BundlePush(params *BundlePushParams) (*BundleData, error) {
 	// Do the request
 	pushResp, err := grequests.Post(endpoint, &grequests.RequestOptions{
 		Headers: map[string]string{
-			"X-Api-Key":    c.secret,
 			"Content-Type": multipartWriter.FormDataContentType(),
 		},
-		InsecureSkipVerify: c.InsecureSkipVerify,
-		Params:             reqQueryParams,
-		RequestBody:        reqBodyReader,
+		HTTPClient:  c.client(),
+		Params:      reqQueryParams,
+		RequestBody: reqBodyReader,
 	})
 	if err != nil {
 		return nil, err
@@ -135,3 +145,10 @@ func (c *Client) BundlePush(params *BundlePushParams) (*BundleData, error) {
 
 	return &BundleData{Id: bundleID}, nil
 }
+
+// client returns the HTTP client that requests to mserv are made with, see rest.Lazy.
+func (c *Client) client() *http.Client {
+	return c.httpClient.Client(func() rest.Config {
+		return rest.Config{AuthHeader: authHeader, Secret: c.secret, TLS: c.TLS, Proxy: c.Proxy, HTTP: c.HTTP}
+	})
+}
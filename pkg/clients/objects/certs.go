@@ -1,7 +1,75 @@
 package objects
 
+import (
+	"crypto/x509/pkix"
+	"sort"
+	"strings"
+	"time"
+)
+
 type CertResponse struct {
 	Id      string `json:"id"`
 	Message string `json:"message"`
 	Status  string `json:"status"`
 }
+
+// CertificateMeta describes a certificate stored on a gateway or dashboard. Their APIs only return
+// this metadata, never the certificate's PEM data or its private key.
+type CertificateMeta struct {
+	ID          string    `json:"id"`
+	Fingerprint string    `json:"fingerprint"`
+	HasPrivate  bool      `json:"has_private"`
+	Issuer      pkix.Name `json:"issuer"`
+	Subject     pkix.Name `json:"subject"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+	DNSNames    []string  `json:"dns_names"`
+	IsCA        bool      `json:"is_ca"`
+}
+
+// CertificateList is the response to a detailed listing of certificates.
+type CertificateList struct {
+	Certs []CertificateMeta `json:"certs"`
+	Pages int               `json:"pages,omitempty"`
+}
+
+// CertificateIDs returns the IDs of every certificate the API refers to, in the order they are
+// first referenced. Pinned public keys may list several IDs separated by commas.
+func (a *APIDefinition) CertificateIDs() []string {
+	ids := []string{}
+	seen := map[string]bool{}
+	add := func(id string) {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			return
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+
+	for _, id := range a.Certificates {
+		add(id)
+	}
+	for _, id := range a.ClientCertificates {
+		add(id)
+	}
+	for _, domain := range sortedKeys(a.UpstreamCertificates) {
+		add(a.UpstreamCertificates[domain])
+	}
+	for _, domain := range sortedKeys(a.PinnedPublicKeys) {
+		for _, id := range strings.Split(a.PinnedPublicKeys[domain], ",") {
+			add(id)
+		}
+	}
+
+	return ids
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
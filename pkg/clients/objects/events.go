@@ -0,0 +1,46 @@
+package objects
+
+import "github.com/AaronFeledy/tyk-ops/pkg/output"
+
+// APIEvent describes an action taken on an API for structured output, err is the error the action
+// failed with, if any.
+func APIEvent(api *DBApiDefinition, action ChangeAction, err error) output.Event {
+	e := output.Event{Object: output.ObjectAPI, Action: string(action)}
+	if api.APIDefinition != nil {
+		e.ID, e.Name = api.APIID, api.Name
+	}
+	return withResult(e, err)
+}
+
+// PolicyEvent describes an action taken on a policy for structured output, in the same way as
+// APIEvent.
+func PolicyEvent(pol *Policy, action ChangeAction, err error) output.Event {
+	e := output.Event{Object: output.ObjectPolicy, ID: pol.ID, Name: pol.Name, Action: string(action)}
+	if e.ID == "" && pol.MID.Valid() {
+		e.ID = pol.MID.Hex()
+	}
+	return withResult(e, err)
+}
+
+// UserEvent describes an action taken on a dashboard user for structured output, in the same way as
+// APIEvent.
+func UserEvent(user *User, action ChangeAction, err error) output.Event {
+	e := output.Event{Object: output.ObjectUser, ID: user.ID, Name: user.EmailAddress, Action: string(action)}
+	return withResult(e, err)
+}
+
+// UserGroupEvent describes an action taken on a dashboard user group for structured output, in the
+// same way as APIEvent.
+func UserGroupEvent(group *UserGroup, action ChangeAction, err error) output.Event {
+	e := output.Event{Object: output.ObjectUserGroup, ID: group.ID, Name: group.Name, Action: string(action)}
+	return withResult(e, err)
+}
+
+func withResult(e output.Event, err error) output.Event {
+	e.Result = output.ResultOK
+	if err != nil {
+		e.Result = output.ResultFailed
+		e.Error = err.Error()
+	}
+	return e
+}
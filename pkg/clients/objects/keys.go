@@ -0,0 +1,53 @@
+package objects
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Session is the session of an API key, setting the APIs the key can access, the policies applied
+// to it and its limits.
+type Session struct {
+	LastCheck               int64                       `json:"last_check"`
+	Allowance               float64                     `json:"allowance"`
+	Rate                    float64                     `json:"rate"`
+	Per                     float64                     `json:"per"`
+	ThrottleInterval        float64                     `json:"throttle_interval"`
+	ThrottleRetryLimit      int                         `json:"throttle_retry_limit"`
+	MaxQueryDepth           int                         `json:"max_query_depth"`
+	DateCreated             time.Time                   `json:"date_created"`
+	Expires                 int64                       `json:"expires"`
+	QuotaMax                int64                       `json:"quota_max"`
+	QuotaRenews             int64                       `json:"quota_renews"`
+	QuotaRemaining          int64                       `json:"quota_remaining"`
+	QuotaRenewalRate        int64                       `json:"quota_renewal_rate"`
+	AccessRights            map[string]AccessDefinition `json:"access_rights"`
+	OrgID                   string                      `json:"org_id"`
+	HMACEnabled             bool                        `json:"hmac_enabled"`
+	HmacSecret              string                      `json:"hmac_string,omitempty"`
+	IsInactive              bool                        `json:"is_inactive"`
+	ApplyPolicies           []string                    `json:"apply_policies"`
+	MetaData                map[string]interface{}      `json:"meta_data"`
+	Tags                    []string                    `json:"tags"`
+	Alias                   string                      `json:"alias"`
+	Certificate             string                      `json:"certificate,omitempty"`
+	EnableDetailedRecording bool                        `json:"enable_detailed_recording"`
+	SessionLifetime         int64                       `json:"session_lifetime"`
+	// The settings of the other authentication methods are kept as they are, so that updating a
+	// key doesn't lose them
+	BasicAuthData json.RawMessage `json:"basic_auth_data,omitempty"`
+	JWTData       json.RawMessage `json:"jwt_data,omitempty"`
+	Monitor       json.RawMessage `json:"monitor,omitempty"`
+}
+
+// KeyResponse is the dashboard's response to a request for a key, or to its creation or update.
+type KeyResponse struct {
+	KeyID   string  `json:"key_id"`
+	KeyHash string  `json:"key_hash,omitempty"`
+	Data    Session `json:"data"`
+}
+
+// KeyList is the response to a listing of keys.
+type KeyList struct {
+	Keys []string `json:"keys"`
+}
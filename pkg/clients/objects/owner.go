@@ -0,0 +1,131 @@
+package objects
+
+import (
+	"fmt"
+)
+
+// OwnerConfigKey is the key that records which owner manages an object, in the config_data of an
+// API and the meta_data of a policy. Policy tags aren't used, as Tyk copies them onto every key
+// created from the policy.
+const OwnerConfigKey = "tykops_owner"
+
+// Owner returns the owner recorded on the API, or an empty string if it is unmanaged.
+func (a *DBApiDefinition) Owner() string {
+	if a.APIDefinition == nil {
+		return ""
+	}
+	owner, _ := a.ConfigData[OwnerConfigKey].(string)
+	return owner
+}
+
+// SetOwner records the owner of the API in its config data. The definition is copied first, so
+// other holders of the same definition are not affected. It does nothing without a definition.
+func (a *DBApiDefinition) SetOwner(owner string) {
+	if a.APIDefinition == nil {
+		return
+	}
+	def := *a.APIDefinition
+	def.ConfigData = make(map[string]interface{}, len(a.ConfigData)+1)
+	for k, v := range a.ConfigData {
+		def.ConfigData[k] = v
+	}
+	def.ConfigData[OwnerConfigKey] = owner
+	a.APIDefinition = &def
+}
+
+// Owner returns the owner recorded on the policy, or an empty string if it is unmanaged.
+func (p *Policy) Owner() string {
+	owner, _ := p.MetaData[OwnerConfigKey].(string)
+	return owner
+}
+
+// SetOwner records the owner of the policy in its meta data. The meta data is copied first, so
+// other copies of the policy are not affected.
+func (p *Policy) SetOwner(owner string) {
+	metaData := make(map[string]interface{}, len(p.MetaData)+1)
+	for k, v := range p.MetaData {
+		metaData[k] = v
+	}
+	metaData[OwnerConfigKey] = owner
+	p.MetaData = metaData
+}
+
+// NotOwnedError is returned when a sync would update an object that is not managed by its owner.
+type NotOwnedError struct {
+	Object string
+	Owner  string
+	// Current is the object's owner, empty when it is unmanaged.
+	Current string
+}
+
+func (e *NotOwnedError) Error() string {
+	if e.Current == "" {
+		return fmt.Sprintf("%v exists on the target but is not managed by %v, use adopt to take it over", e.Object, e.Owner)
+	}
+	return fmt.Sprintf("%v exists on the target but is managed by %v, not %v", e.Object, e.Current, e.Owner)
+}
+
+// ClaimAPIChanges restricts planned API changes to the objects managed by owner. Deletes of other
+// objects are dropped, updates of other objects fail with a *NotOwnedError, and every API that is
+// created or updated is stamped with the owner. Changes are returned as they are when owner is empty.
+func ClaimAPIChanges(changes []APIChange, owner string) ([]APIChange, error) {
+	if owner == "" {
+		return changes, nil
+	}
+
+	claimed := []APIChange{}
+	for _, change := range changes {
+		switch change.Action {
+		case ActionDelete:
+			if change.Remote.Owner() != owner {
+				continue
+			}
+		case ActionUpdate:
+			if current := change.Remote.Owner(); current != owner {
+				return nil, &NotOwnedError{Object: fmt.Sprintf("API %v", change.Remote.APIID), Owner: owner, Current: current}
+			}
+			fallthrough
+		case ActionCreate:
+			local := *change.Local
+			local.SetOwner(owner)
+			change.Local = &local
+		}
+		claimed = append(claimed, change)
+	}
+
+	return claimed, nil
+}
+
+// ClaimPolicyChanges restricts planned policy changes to the objects managed by owner, in the same
+// way as ClaimAPIChanges.
+func ClaimPolicyChanges(changes []PolicyChange, owner string) ([]PolicyChange, error) {
+	if owner == "" {
+		return changes, nil
+	}
+
+	claimed := []PolicyChange{}
+	for _, change := range changes {
+		switch change.Action {
+		case ActionDelete:
+			if change.Remote.Owner() != owner {
+				continue
+			}
+		case ActionUpdate:
+			if current := change.Remote.Owner(); current != owner {
+				id := change.Remote.ID
+				if id == "" {
+					id = change.Remote.MID.Hex()
+				}
+				return nil, &NotOwnedError{Object: fmt.Sprintf("policy %v", id), Owner: owner, Current: current}
+			}
+			fallthrough
+		case ActionCreate:
+			local := *change.Local
+			local.SetOwner(owner)
+			change.Local = &local
+		}
+		claimed = append(claimed, change)
+	}
+
+	return claimed, nil
+}
@@ -0,0 +1,140 @@
+package objects
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/diff"
+)
+
+type ChangeAction string
+
+const (
+	ActionCreate ChangeAction = "create"
+	ActionUpdate ChangeAction = "update"
+	ActionDelete ChangeAction = "delete"
+)
+
+// PlanVersion is the version of the plan format written by this version of tykops.
+const PlanVersion = 1
+
+var (
+	StalePlanError       error = errors.New("The target has changed since the plan was created, create a new plan")
+	UnsupportedPlanError error = errors.New("The plan was created by an incompatible version of tykops")
+)
+
+// ServerManagedFields are fields set by the Dashboard or Gateway which should not be treated as
+// differences between the repository and the target.
+var ServerManagedFields = []string{"_id", "last_updated", "date_created"}
+
+// APIChange is a single operation that a sync will perform on an API.
+type APIChange struct {
+	Action ChangeAction `json:"action"`
+	// Local is the definition from the repository, it is not set for deletes.
+	Local *DBApiDefinition `json:"local,omitempty"`
+	// Remote is the definition currently on the target, it is not set for creates.
+	Remote *DBApiDefinition `json:"remote,omitempty"`
+}
+
+// Definition returns the definition the change is about, preferring the repository copy.
+func (c APIChange) Definition() *DBApiDefinition {
+	if c.Local != nil {
+		return c.Local
+	}
+	return c.Remote
+}
+
+// Diff returns the field level changes an update will make to the remote API.
+func (c APIChange) Diff() ([]diff.Change, error) {
+	if c.Action != ActionUpdate {
+		return nil, nil
+	}
+	return diff.Compare(c.Remote, c.Local, ServerManagedFields...)
+}
+
+// PolicyChange is a single operation that a sync will perform on a policy.
+type PolicyChange struct {
+	Action ChangeAction `json:"action"`
+	// Local is the policy from the repository, it is not set for deletes.
+	Local *Policy `json:"local,omitempty"`
+	// Remote is the policy currently on the target, it is not set for creates.
+	Remote *Policy `json:"remote,omitempty"`
+}
+
+// Policy returns the policy the change is about, preferring the repository copy.
+func (c PolicyChange) Policy() *Policy {
+	if c.Local != nil {
+		return c.Local
+	}
+	return c.Remote
+}
+
+// Diff returns the field level changes an update will make to the remote policy.
+func (c PolicyChange) Diff() ([]diff.Change, error) {
+	if c.Action != ActionUpdate {
+		return nil, nil
+	}
+	return diff.Compare(c.Remote, c.Local, ServerManagedFields...)
+}
+
+// SyncPlan is the full set of changes a sync will make to a target. A plan can be saved and applied
+// later, the fingerprints record the state of the target it was created against so that it is only
+// applied if nothing has changed in the meantime.
+type SyncPlan struct {
+	Version int `json:"version"`
+	// APIsFingerprint is the FingerprintAPIs digest of the target's APIs.
+	APIsFingerprint string `json:"apis_fingerprint"`
+	// PoliciesFingerprint is the FingerprintPolicies digest of the target's policies, it is empty
+	// when the plan does not touch policies.
+	PoliciesFingerprint string `json:"policies_fingerprint,omitempty"`
+	// UsersFingerprint is the FingerprintUsers digest of the target's users and user groups, it is
+	// empty when the plan does not touch them.
+	UsersFingerprint string            `json:"users_fingerprint,omitempty"`
+	APIs             []APIChange       `json:"apis"`
+	Policies         []PolicyChange    `json:"policies"`
+	UserGroups       []UserGroupChange `json:"user_groups,omitempty"`
+	Users            []UserChange      `json:"users,omitempty"`
+}
+
+// FingerprintAPIs returns a digest of a set of remote APIs, used to detect whether the target has
+// changed since a plan was created. The order of the APIs does not affect the result.
+func FingerprintAPIs(apis []DBApiDefinition) (string, error) {
+	sorted := make([]DBApiDefinition, len(apis))
+	copy(sorted, apis)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Id.Hex() != sorted[j].Id.Hex() {
+			return sorted[i].Id.Hex() < sorted[j].Id.Hex()
+		}
+		return sorted[i].APIID < sorted[j].APIID
+	})
+
+	return fingerprint(sorted)
+}
+
+// FingerprintPolicies returns a digest of a set of remote policies, used to detect whether the
+// target has changed since a plan was created. The order of the policies does not affect the result.
+func FingerprintPolicies(pols []Policy) (string, error) {
+	sorted := make([]Policy, len(pols))
+	copy(sorted, pols)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].MID.Hex() != sorted[j].MID.Hex() {
+			return sorted[i].MID.Hex() < sorted[j].MID.Hex()
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	return fingerprint(sorted)
+}
+
+func fingerprint(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
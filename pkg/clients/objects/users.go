@@ -1,10 +1,158 @@
 package objects
 
+import (
+	"sort"
+	"strings"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/diff"
+)
+
 type UsersResponse struct {
 	Users []User `json:"users"`
+	Pages int    `json:"pages,omitempty"`
 }
 
+// User is a dashboard user. Its password and access key are secrets, they are never read from a
+// repo and are left out when the user is compared or written back.
 type User struct {
-	OrgID     string `json:"org_id"`
-	AccessKey string `json:"access_key"`
+	ID              string            `json:"id,omitempty"`
+	FirstName       string            `json:"first_name"`
+	LastName        string            `json:"last_name"`
+	EmailAddress    string            `json:"email_address"`
+	OrgID           string            `json:"org_id"`
+	Active          bool              `json:"active"`
+	UserPermissions map[string]string `json:"user_permissions,omitempty"`
+	GroupID         string            `json:"group_id,omitempty"`
+	// Group is the name of the user's group in a repo, it is resolved to the group's ID on the
+	// dashboard and never sent to it
+	Group     string `json:"group,omitempty"`
+	AccessKey string `json:"access_key,omitempty"`
+	Password  string `json:"password,omitempty"`
+}
+
+// WithoutSecrets returns a copy of the user without its password and access key.
+func (u User) WithoutSecrets() User {
+	u.AccessKey = ""
+	u.Password = ""
+	return u
+}
+
+// UserGroupsResponse is the dashboard's listing of user groups.
+type UserGroupsResponse struct {
+	Groups []UserGroup `json:"groups"`
+	Pages  int         `json:"pages,omitempty"`
+}
+
+// UserGroup is a dashboard user group, whose permissions apply to every user in it.
+type UserGroup struct {
+	ID              string            `json:"id,omitempty"`
+	OrgID           string            `json:"org_id"`
+	Name            string            `json:"name"`
+	Description     string            `json:"description"`
+	UserPermissions map[string]string `json:"user_permissions,omitempty"`
+}
+
+// userManagedFields are the fields of users and user groups that are not compared between the
+// repository and the target, as they are set by the dashboard or are secrets.
+var userManagedFields = append([]string{"id", "group", "access_key", "password"}, ServerManagedFields...)
+
+// UserChange is a single operation that a sync will perform on a dashboard user.
+type UserChange struct {
+	Action ChangeAction `json:"action"`
+	// Local is the user from the repository, it is not set for deletes.
+	Local *User `json:"local,omitempty"`
+	// Remote is the user currently on the target, without its secrets. It is not set for creates.
+	Remote *User `json:"remote,omitempty"`
+}
+
+// User returns the user the change is about, preferring the repository copy.
+func (c UserChange) User() *User {
+	if c.Local != nil {
+		return c.Local
+	}
+	return c.Remote
+}
+
+// Diff returns the field level changes an update will make to the remote user.
+func (c UserChange) Diff() ([]diff.Change, error) {
+	if c.Action != ActionUpdate {
+		return nil, nil
+	}
+	return diff.Compare(c.Remote, c.Local, userManagedFields...)
+}
+
+// UserGroupChange is a single operation that a sync will perform on a dashboard user group.
+type UserGroupChange struct {
+	Action ChangeAction `json:"action"`
+	// Local is the user group from the repository, it is not set for deletes.
+	Local *UserGroup `json:"local,omitempty"`
+	// Remote is the user group currently on the target, it is not set for creates.
+	Remote *UserGroup `json:"remote,omitempty"`
+}
+
+// UserGroup returns the user group the change is about, preferring the repository copy.
+func (c UserGroupChange) UserGroup() *UserGroup {
+	if c.Local != nil {
+		return c.Local
+	}
+	return c.Remote
+}
+
+// Diff returns the field level changes an update will make to the remote user group.
+func (c UserGroupChange) Diff() ([]diff.Change, error) {
+	if c.Action != ActionUpdate {
+		return nil, nil
+	}
+	return diff.Compare(c.Remote, c.Local, userManagedFields...)
+}
+
+// FingerprintUsers returns a digest of a target's users and user groups, used to detect whether the
+// target has changed since a plan was created. Secrets and order do not affect the result.
+func FingerprintUsers(users []User, groups []UserGroup) (string, error) {
+	sortedUsers := make([]User, len(users))
+	for i, user := range users {
+		sortedUsers[i] = user.WithoutSecrets()
+	}
+	sort.SliceStable(sortedUsers, func(i, j int) bool {
+		return sortedUsers[i].ID < sortedUsers[j].ID
+	})
+
+	sortedGroups := make([]UserGroup, len(groups))
+	copy(sortedGroups, groups)
+	sort.SliceStable(sortedGroups, func(i, j int) bool {
+		return sortedGroups[i].ID < sortedGroups[j].ID
+	})
+
+	return fingerprint(struct {
+		Users  []User
+		Groups []UserGroup
+	}{sortedUsers, sortedGroups})
+}
+
+// SameEmail reports whether two email addresses are the same, which the dashboard compares without
+// regard to case.
+func SameEmail(a, b string) bool {
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+// FindUser returns the user with the given ID or email address, or nil if there is none.
+func FindUser(users []User, ref string) *User {
+	for i := range users {
+		if (users[i].ID != "" && users[i].ID == ref) || SameEmail(users[i].EmailAddress, ref) {
+			user := users[i]
+			return &user
+		}
+	}
+	return nil
+}
+
+// FindUserGroup returns the user group with the given ID or name, or nil if there is none.
+func FindUserGroup(groups []UserGroup, ref string) *UserGroup {
+	for i := range groups {
+		if (groups[i].ID != "" && groups[i].ID == ref) || groups[i].Name == ref {
+			group := groups[i]
+			return &group
+		}
+	}
+	return nil
 }
@@ -0,0 +1,65 @@
+// Package pool runs the operations on a target concurrently, with a limit on how many are in
+// flight at once.
+package pool
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Run calls fn for every index from 0 to n-1, with at most parallel calls running at once. A
+// parallel of 1 or less runs the calls one after the other. Every call is made even if some fail,
+// the errors are returned together as a *MultiError in the order of their indexes.
+func Run(n, parallel int, fn func(i int) error) error {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	errs := make([]error, n)
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < parallel && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	multi := &MultiError{}
+	for _, err := range errs {
+		if err != nil {
+			multi.Errors = append(multi.Errors, err)
+		}
+	}
+	if len(multi.Errors) == 0 {
+		return nil
+	}
+	return multi
+}
+
+// MultiError holds the errors of all the operations that failed in a call to Run.
+type MultiError struct {
+	Errors []error
+}
+
+func (e *MultiError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%v operations failed: %v", len(e.Errors), strings.Join(msgs, "; "))
+}
@@ -0,0 +1,69 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	var running, peak int32
+	done := make([]bool, 20)
+
+	err := Run(len(done), 4, func(i int) error {
+		now := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if now <= p || atomic.CompareAndSwapInt32(&peak, p, now) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+		done[i] = true
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, int(peak), 4)
+	for i, d := range done {
+		assert.True(t, d, "operation %v was not run", i)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	var calls int32
+
+	err := Run(5, 3, func(i int) error {
+		atomic.AddInt32(&calls, 1)
+		if i%2 == 1 {
+			return fmt.Errorf("operation %v failed", i)
+		}
+		return nil
+	})
+
+	assert.Equal(t, int32(5), calls, "every operation should run despite the failures")
+	multi, ok := err.(*MultiError)
+	if !ok {
+		t.Fatalf("expected a *MultiError, got %T", err)
+	}
+	assert.Equal(t, []error{errors.New("operation 1 failed"), errors.New("operation 3 failed")}, multi.Errors)
+	assert.Equal(t, "2 operations failed: operation 1 failed; operation 3 failed", err.Error())
+}
+
+func TestRun_Sequential(t *testing.T) {
+	order := []int{}
+
+	err := Run(3, 0, func(i int) error {
+		order = append(order, i)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2}, order)
+}
@@ -0,0 +1,191 @@
+package rest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/output"
+	log "github.com/sirupsen/logrus"
+)
+
+// Config describes how a client connects to a server. Every client of a Tyk server makes its
+// requests with an HTTP client built from a Config by New.
+type Config struct {
+	// AuthHeader is the request header the Secret is sent in, e.g. "Authorization" for the dashboard
+	// or "X-Tyk-Authorization" for a gateway.
+	AuthHeader string
+	// Secret authenticates the requests. It is only sent when the request doesn't already carry the
+	// AuthHeader, and it is left out of the debug log.
+	Secret string
+	// UserAgent replaces the user agent of every request, it defaults to the package's UserAgent.
+	UserAgent string
+	// TLS sets how the server's certificate is validated and which certificate the client presents.
+	TLS TLS
+	// Proxy is the URL of the proxy requests are sent through. The HTTP_PROXY, HTTPS_PROXY and
+	// NO_PROXY environment variables are used when it is empty.
+	Proxy string
+	// HTTP sets the timeouts, retries and rate limit of the requests.
+	HTTP Options
+}
+
+// TLS configures the TLS connections to a server.
+type TLS struct {
+	// InsecureSkipVerify turns off the validation of the server's certificate.
+	InsecureSkipVerify bool
+	// CAFile is a PEM bundle of the certificate authorities that are trusted on top of the system's.
+	CAFile string
+	// CertFile and KeyFile are the PEM certificate and key the client presents for mutual TLS.
+	CertFile string
+	KeyFile  string
+}
+
+// SetTLS replaces the TLS settings. Clients embed TLS, so this sets how their connections are made.
+func (t *TLS) SetTLS(settings TLS) {
+	*t = settings
+}
+
+// Config returns the tls.Config for the connections, loading the files it refers to.
+func (t TLS) Config() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: t.InsecureSkipVerify}
+
+	if t.CAFile != "" {
+		pem, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA file: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in CA file %v", t.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		if t.CertFile == "" || t.KeyFile == "" {
+			return nil, errors.New("a client certificate needs both a certificate file and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// New returns an HTTP client configured by cfg. Its requests carry the user agent and the secret,
+// go through the proxy, are timed out, retried and rate limited as set by cfg.HTTP, and are logged
+// along with their responses when debug output is turned on.
+//
+// The client is always usable: when the TLS files or the proxy URL can't be loaded, every request
+// made with it fails with that error.
+func New(cfg Config) *http.Client {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+
+	tlsConfig, err := cfg.TLS.Config()
+	if err != nil {
+		return &http.Client{Transport: failingTransport{err: err}}
+	}
+	base.TLSClientConfig = tlsConfig
+
+	if cfg.Proxy != "" {
+		proxy, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return &http.Client{Transport: failingTransport{err: fmt.Errorf("invalid proxy URL: %v", err)}}
+		}
+		base.Proxy = http.ProxyURL(proxy)
+	}
+
+	return &http.Client{Transport: NewTransport(&clientTransport{base: base, cfg: cfg}, cfg.HTTP)}
+}
+
+// Lazy is an HTTP client that is built by New on first use. Clients hold one so that their HTTP
+// options and TLS settings can be set after they are created, as long as that is done before any
+// requests are made.
+type Lazy struct {
+	once   sync.Once
+	client *http.Client
+}
+
+// Client returns the HTTP client, building it from the Config returned by cfg on the first call.
+func (l *Lazy) Client(cfg func() Config) *http.Client {
+	l.once.Do(func() {
+		l.client = New(cfg())
+	})
+	return l.client
+}
+
+// clientTransport adds the user agent and the secret to each attempt at a request, and logs it.
+type clientTransport struct {
+	base http.RoundTripper
+	cfg  Config
+}
+
+func (t *clientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+
+	userAgent := t.cfg.UserAgent
+	if userAgent == "" {
+		userAgent = UserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	if t.cfg.AuthHeader != "" && t.cfg.Secret != "" && req.Header.Get(t.cfg.AuthHeader) == "" {
+		req.Header.Set(t.cfg.AuthHeader, t.cfg.Secret)
+	}
+
+	debug := output.User.IsLevelEnabled(log.DebugLevel)
+	if debug {
+		if dump, err := httputil.DumpRequestOut(req, true); err == nil {
+			output.User.Debugf("HTTP request:\n%v\n", t.redact(dump))
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if !debug {
+		return resp, err
+	}
+
+	if err != nil {
+		output.User.Debugf("HTTP %v %v failed after %v: %v\n", req.Method, req.URL, time.Since(start), err)
+		return resp, err
+	}
+	if dump, err := httputil.DumpResponse(resp, true); err == nil {
+		output.User.Debugf("HTTP response in %v:\n%v\n", time.Since(start), t.redact(dump))
+	}
+	return resp, nil
+}
+
+// redact removes the secret from a dumped request or response.
+func (t *clientTransport) redact(dump []byte) string {
+	if t.cfg.Secret == "" {
+		return string(dump)
+	}
+	return strings.ReplaceAll(string(dump), t.cfg.Secret, "[REDACTED]")
+}
+
+// failingTransport fails every request with the error that stopped a client from being configured.
+type failingTransport struct {
+	err error
+}
+
+func (t failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, t.err
+}
@@ -0,0 +1,156 @@
+package rest
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/output"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// writePEM writes the given PEM blocks to a file in dir, returning its path.
+func writePEM(t *testing.T, dir, name string, blocks ...*pem.Block) string {
+	buf := &bytes.Buffer{}
+	for _, block := range blocks {
+		if err := pem.Encode(buf, block); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNew_Headers(t *testing.T) {
+	var userAgent, secret string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent, secret = r.UserAgent(), r.Header.Get("X-Tyk-Authorization")
+	}))
+	defer server.Close()
+
+	client := New(Config{AuthHeader: "X-Tyk-Authorization", Secret: "s3cret"})
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("User-Agent", "library/1.0")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, UserAgent, userAgent)
+	assert.Equal(t, "s3cret", secret)
+
+	// A secret set on the request is kept
+	req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("X-Tyk-Authorization", "other")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assert.Equal(t, "other", secret)
+}
+
+func TestNew_CAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := New(Config{HTTP: Options{Retries: -1}}).Get(server.URL)
+	assert.Error(t, err, "the test server's certificate should not be trusted by default")
+
+	caFile := writePEM(t, t.TempDir(), "ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	resp, err := New(Config{TLS: TLS{CAFile: caFile}}).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestNew_ClientCertificate(t *testing.T) {
+	var presented int
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presented = len(r.TLS.PeerCertificates)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	// Present the server's own certificate, it is all the test needs
+	cert := server.TLS.Certificates[0]
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile := writePEM(t, dir, "cert.pem", &pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	keyFile := writePEM(t, dir, "key.pem", &pem.Block{Type: "PRIVATE KEY", Bytes: key})
+
+	resp, err := New(Config{TLS: TLS{InsecureSkipVerify: true, CertFile: certFile, KeyFile: keyFile}}).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, 1, presented)
+}
+
+func TestNew_InvalidTLS(t *testing.T) {
+	_, err := New(Config{TLS: TLS{CertFile: "cert.pem"}}).Get("https://127.0.0.1")
+	assert.EqualError(t, err, `Get "https://127.0.0.1": a client certificate needs both a certificate file and a key file`)
+
+	_, err = New(Config{TLS: TLS{CAFile: filepath.Join(t.TempDir(), "missing.pem")}}).Get("https://127.0.0.1")
+	assert.Error(t, err)
+}
+
+func TestNew_Proxy(t *testing.T) {
+	var host string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.URL.Host
+	}))
+	defer proxy.Close()
+
+	resp, err := New(Config{Proxy: proxy.URL}).Get("http://dashboard.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, "dashboard.example", host)
+}
+
+func TestNew_DebugLogRedactsSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"Status":"OK"}`))
+	}))
+	defer server.Close()
+
+	logs := &bytes.Buffer{}
+	out, level := output.User.Out, output.User.Level
+	output.User.Out = logs
+	output.User.SetLevel(log.DebugLevel)
+	defer func() {
+		output.User.Out = out
+		output.User.SetLevel(level)
+	}()
+
+	resp, err := New(Config{AuthHeader: "Authorization", Secret: "s3cret"}).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	assert.Contains(t, logs.String(), "Authorization: [REDACTED]")
+	assert.Contains(t, logs.String(), `{"Status":"OK"}`)
+	assert.NotContains(t, logs.String(), "s3cret")
+}
@@ -0,0 +1,225 @@
+package rest
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/output"
+)
+
+// Defaults for the Options that aren't set.
+const (
+	DefaultTimeout      = 30 * time.Second
+	DefaultRetries      = 3
+	DefaultRetryWait    = 500 * time.Millisecond
+	DefaultMaxRetryWait = 10 * time.Second
+)
+
+// Options tune how requests are made to a target. Fields left at zero use the defaults.
+type Options struct {
+	// Timeout is how long each attempt at a request may take, including reading the response.
+	Timeout time.Duration `mapstructure:"timeout" json:"timeout,omitempty"`
+	// Retries is how many times a request that failed with a transient error is retried, -1 turns
+	// retries off.
+	Retries int `mapstructure:"retries" json:"retries,omitempty"`
+	// RetryWait is the wait before the first retry, it doubles for each retry after that.
+	RetryWait time.Duration `mapstructure:"retry_wait" json:"retry_wait,omitempty"`
+	// MaxRetryWait is the longest wait between retries.
+	MaxRetryWait time.Duration `mapstructure:"max_retry_wait" json:"max_retry_wait,omitempty"`
+	// RateLimit is the most requests made per second, there is no limit when it is 0.
+	RateLimit float64 `mapstructure:"rate_limit" json:"rate_limit,omitempty"`
+}
+
+func (o Options) withDefaults() Options {
+	if o.Timeout == 0 {
+		o.Timeout = DefaultTimeout
+	}
+	if o.Retries == 0 {
+		o.Retries = DefaultRetries
+	} else if o.Retries < 0 {
+		o.Retries = 0
+	}
+	if o.RetryWait == 0 {
+		o.RetryWait = DefaultRetryWait
+	}
+	if o.MaxRetryWait == 0 {
+		o.MaxRetryWait = DefaultMaxRetryWait
+	}
+	return o
+}
+
+// Transport is an http.RoundTripper that limits the rate of requests, times out each attempt at a
+// request, and retries requests that failed with a transient error, waiting longer before each
+// retry.
+//
+// Requests are retried after a 429, as the target didn't act on them. Requests with an idempotent
+// method are also retried after a 5xx or a network error, but other requests aren't, as they may
+// have been acted on already.
+type Transport struct {
+	base    http.RoundTripper
+	opts    Options
+	limiter *limiter
+}
+
+// NewTransport returns a Transport that sends requests with base, which defaults to
+// http.DefaultTransport when nil.
+func NewTransport(base http.RoundTripper, opts Options) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	t := &Transport{base: base, opts: opts.withDefaults()}
+	if opts.RateLimit > 0 {
+		t.limiter = &limiter{interval: time.Duration(float64(time.Second) / opts.RateLimit)}
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := t.limiter.wait(req.Context()); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.try(req)
+		if attempt >= t.opts.Retries || !retryable(req, resp, err) {
+			return resp, err
+		}
+
+		wait := t.backoff(attempt, resp)
+		if err != nil {
+			output.User.Debugf("%v %v failed, retrying in %v: %v\n", req.Method, req.URL, wait, err)
+		} else {
+			output.User.Debugf("%v %v returned %v, retrying in %v\n", req.Method, req.URL, resp.StatusCode, wait)
+			// Read the body so that the connection can be reused
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// try makes a single attempt at a request, with its own timeout and a fresh copy of its body.
+func (t *Transport) try(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.opts.Timeout)
+	attempt := req.Clone(ctx)
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		attempt.Body = body
+	}
+
+	resp, err := t.base.RoundTrip(attempt)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The timeout also covers reading the body, so it is only released once the body is closed
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// backoff returns how long to wait before retrying after the given attempt. The wait doubles with
+// each attempt, with some jitter so that parallel requests don't retry in step, and follows a
+// Retry-After header when the target sends one.
+func (t *Transport) backoff(attempt int, resp *http.Response) time.Duration {
+	wait := t.opts.RetryWait << uint(attempt)
+	if wait <= 0 || wait > t.opts.MaxRetryWait {
+		wait = t.opts.MaxRetryWait
+	}
+	wait = wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			wait = time.Duration(seconds) * time.Second
+			if wait > t.opts.MaxRetryWait {
+				wait = t.opts.MaxRetryWait
+			}
+		}
+	}
+
+	return wait
+}
+
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	// The body can't be sent again
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+	if req.Context().Err() != nil {
+		return false
+	}
+
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+
+	if !idempotent(req.Method) {
+		return false
+	}
+	return err != nil || resp.StatusCode >= 500
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// limiter spaces requests evenly, so that no more than one is made per interval. It is safe for
+// concurrent use, a nil limiter doesn't limit anything.
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func (l *limiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	select {
+	case <-time.After(time.Until(at)):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,121 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testOptions = Options{RetryWait: time.Millisecond, MaxRetryWait: 5 * time.Millisecond}
+
+// failingServer answers the first failures requests with status, and the rest with 200.
+func failingServer(failures int32, status int) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	return server, &calls
+}
+
+func TestTransport_RetriesIdempotentRequests(t *testing.T) {
+	server, calls := failingServer(2, http.StatusBadGateway)
+	defer server.Close()
+
+	client := New(Config{HTTP: testOptions})
+	req, _ := http.NewRequest(http.MethodPut, server.URL, strings.NewReader("{}"))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), *calls)
+}
+
+func TestTransport_GivesUp(t *testing.T) {
+	server, calls := failingServer(10, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	opts := testOptions
+	opts.Retries = 2
+	resp, err := New(Config{HTTP: opts}).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(3), *calls)
+}
+
+func TestTransport_DoesNotRetryPosts(t *testing.T) {
+	server, calls := failingServer(1, http.StatusInternalServerError)
+	defer server.Close()
+
+	resp, err := New(Config{HTTP: testOptions}).Post(server.URL, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, int32(1), *calls, "a POST may have been acted on, so it must not be sent again")
+}
+
+func TestTransport_RetriesPostsWhenRateLimited(t *testing.T) {
+	server, calls := failingServer(1, http.StatusTooManyRequests)
+	defer server.Close()
+
+	resp, err := New(Config{HTTP: testOptions}).Post(server.URL, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), *calls)
+}
+
+func TestTransport_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	opts := testOptions
+	opts.Timeout = 10 * time.Millisecond
+	opts.Retries = -1
+	_, err := New(Config{HTTP: opts}).Get(server.URL)
+	assert.Error(t, err)
+}
+
+func TestTransport_RateLimit(t *testing.T) {
+	server, _ := failingServer(0, http.StatusOK)
+	defer server.Close()
+
+	opts := testOptions
+	opts.RateLimit = 50
+	client := New(Config{HTTP: opts})
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	// The first request goes straight away, the other four are 20ms apart
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(80*time.Millisecond))
+}
@@ -0,0 +1,84 @@
+// Package rollback keeps track of changes made to a target so they can be reverted if a later
+// change fails.
+package rollback
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/output"
+)
+
+type step struct {
+	description string
+	revert      func() error
+}
+
+// Journal records how to revert each change made to a target. It is safe for concurrent use.
+type Journal struct {
+	mu    sync.Mutex
+	steps []step
+}
+
+// Record adds a change that has been made, along with a description of what reverting it does and
+// the function that reverts it.
+func (j *Journal) Record(description string, revert func() error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.steps = append(j.steps, step{description: description, revert: revert})
+}
+
+// Rollback reverts every recorded change, most recent first. The cause is returned unchanged if
+// there was nothing to revert, otherwise an *Error describes what was rolled back.
+func (j *Journal) Rollback(cause error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if len(j.steps) == 0 {
+		return cause
+	}
+
+	rbErr := &Error{Cause: cause}
+	for i := len(j.steps) - 1; i >= 0; i-- {
+		s := j.steps[i]
+		if err := s.revert(); err != nil {
+			output.Printf("ROLLBACK Failed: %v: %v\n", s.description, err)
+			rbErr.Failed = append(rbErr.Failed, fmt.Errorf("%v: %v", s.description, err))
+			continue
+		}
+		output.Printf("ROLLBACK %v\n", s.description)
+		rbErr.RolledBack = append(rbErr.RolledBack, s.description)
+	}
+	j.steps = nil
+
+	return rbErr
+}
+
+// Error is returned when applying changes failed and the changes already made were reverted.
+type Error struct {
+	// Cause is the error that triggered the rollback.
+	Cause error
+	// RolledBack describes each change that was reverted.
+	RolledBack []string
+	// Failed holds the changes that could not be reverted and need to be fixed by hand.
+	Failed []error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%v (rolled back %v changes", e.Cause, len(e.RolledBack))
+	if len(e.Failed) == 0 {
+		return msg + ")"
+	}
+
+	failed := make([]string, len(e.Failed))
+	for i, err := range e.Failed {
+		failed[i] = err.Error()
+	}
+	return fmt.Sprintf("%v, %v could not be rolled back: %v)", msg, len(e.Failed), strings.Join(failed, "; "))
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
@@ -0,0 +1,162 @@
+// Package diff computes field-level differences between two JSON serialisable objects.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Change describes a single field that differs between two objects.
+type Change struct {
+	// Path is the dotted JSON path of the field, array elements are addressed as name[i].
+	Path string `json:"path"`
+	// Old is the value of the field in the original object, nil if it was not set.
+	Old interface{} `json:"old,omitempty"`
+	// New is the value of the field in the changed object, nil if it has been removed.
+	New interface{} `json:"new,omitempty"`
+}
+
+// String renders the change as a single line suitable for showing to the user.
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s => %s", c.Path, render(c.Old), render(c.New))
+}
+
+// Compare returns the changes needed to turn old into new. Both objects are compared through their
+// JSON representation so only serialised fields are taken into account. Any object key listed in
+// ignore is skipped wherever it appears. Unset fields are considered equal to empty values (null,
+// false, 0, "", [] and {}) as the Tyk APIs are not consistent about which of those they return.
+func Compare(old, new interface{}, ignore ...string) ([]Change, error) {
+	oldVal, err := normalise(old)
+	if err != nil {
+		return nil, err
+	}
+
+	newVal, err := normalise(new)
+	if err != nil {
+		return nil, err
+	}
+
+	ignored := make(map[string]bool, len(ignore))
+	for _, key := range ignore {
+		ignored[key] = true
+	}
+
+	changes := []Change{}
+	walk("", oldVal, newVal, ignored, &changes)
+	return changes, nil
+}
+
+func normalise(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func walk(path string, old, new interface{}, ignored map[string]bool, changes *[]Change) {
+	if isEmpty(old) && isEmpty(new) {
+		return
+	}
+
+	oldMap, oldIsMap := old.(map[string]interface{})
+	newMap, newIsMap := new.(map[string]interface{})
+	if (oldIsMap || old == nil) && (newIsMap || new == nil) {
+		keys := map[string]bool{}
+		for k := range oldMap {
+			keys[k] = true
+		}
+		for k := range newMap {
+			keys[k] = true
+		}
+
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			if !ignored[k] {
+				sorted = append(sorted, k)
+			}
+		}
+		sort.Strings(sorted)
+
+		for _, k := range sorted {
+			walk(join(path, k), oldMap[k], newMap[k], ignored, changes)
+		}
+		return
+	}
+
+	oldList, oldIsList := old.([]interface{})
+	newList, newIsList := new.([]interface{})
+	if (oldIsList || old == nil) && (newIsList || new == nil) {
+		size := len(oldList)
+		if len(newList) > size {
+			size = len(newList)
+		}
+
+		for i := 0; i < size; i++ {
+			var o, n interface{}
+			if i < len(oldList) {
+				o = oldList[i]
+			}
+			if i < len(newList) {
+				n = newList[i]
+			}
+			walk(fmt.Sprintf("%s[%d]", path, i), o, n, ignored, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(old, new) {
+		*changes = append(*changes, Change{Path: path, Old: old, New: new})
+	}
+}
+
+func isEmpty(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !val
+	case float64:
+		return val == 0
+	case string:
+		return val == ""
+	case []interface{}:
+		return len(val) == 0
+	case map[string]interface{}:
+		return len(val) == 0
+	}
+	return false
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func render(v interface{}) string {
+	if v == nil {
+		return "<unset>"
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+
+	return strings.TrimSpace(string(data))
+}
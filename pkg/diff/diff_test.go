@@ -0,0 +1,62 @@
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type proxy struct {
+	ListenPath string `json:"listen_path"`
+	TargetURL  string `json:"target_url"`
+}
+
+type def struct {
+	ID      string            `json:"_id,omitempty"`
+	Name    string            `json:"name"`
+	Active  bool              `json:"active"`
+	Tags    []string          `json:"tags"`
+	Proxy   proxy             `json:"proxy"`
+	Headers map[string]string `json:"headers"`
+}
+
+func TestCompare(t *testing.T) {
+	t.Run("identical objects have no changes", func(t *testing.T) {
+		a := def{Name: "a", Proxy: proxy{ListenPath: "/a/"}}
+		changes, err := Compare(a, a)
+		assert.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+	t.Run("nested fields are reported by path", func(t *testing.T) {
+		a := def{Name: "a", Proxy: proxy{TargetURL: "http://one"}}
+		b := def{Name: "a", Proxy: proxy{TargetURL: "http://two"}}
+		changes, err := Compare(a, b)
+		assert.NoError(t, err)
+		assert.Equal(t, []Change{{Path: "proxy.target_url", Old: "http://one", New: "http://two"}}, changes)
+		assert.Equal(t, `proxy.target_url: "http://one" => "http://two"`, changes[0].String())
+	})
+	t.Run("list elements are reported by index", func(t *testing.T) {
+		a := def{Tags: []string{"a", "b"}}
+		b := def{Tags: []string{"a", "c", "d"}}
+		changes, err := Compare(a, b)
+		assert.NoError(t, err)
+		assert.Equal(t, []Change{
+			{Path: "tags[1]", Old: "b", New: "c"},
+			{Path: "tags[2]", Old: nil, New: "d"},
+		}, changes)
+	})
+	t.Run("unset and empty values are equal", func(t *testing.T) {
+		a := map[string]interface{}{"name": "a"}
+		b := def{Name: "a", Tags: []string{}, Headers: map[string]string{}}
+		changes, err := Compare(a, b)
+		assert.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+	t.Run("ignored fields are skipped", func(t *testing.T) {
+		a := def{ID: "1", Name: "a"}
+		b := def{ID: "2", Name: "a"}
+		changes, err := Compare(a, b, "_id")
+		assert.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+}
@@ -1,8 +1,9 @@
 package ops
 
 import (
-	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/rest"
 	"github.com/go-resty/resty/v2"
 	"github.com/ongoingio/urljoin"
 )
@@ -10,6 +11,7 @@ import (
 const (
 	adminAuthHeader = "admin-auth"
 	ssoEndpoint     = "/admin/sso"
+	orgsEndpoint    = "/admin/organisations"
 )
 
 type loginResponse struct {
@@ -18,7 +20,24 @@ type loginResponse struct {
 
 type DashboardAdmin struct {
 	Server
+	// Client makes the requests to the dashboard's admin API, one is created when it is nil.
 	Client *resty.Client
+	// HTTP sets the timeouts, retries and rate limit of the requests.
+	HTTP rest.Options
+}
+
+// client returns the client requests are made with, creating it on first use.
+func (s *DashboardAdmin) client() *resty.Client {
+	if s.Client == nil {
+		s.Client = resty.NewWithClient(rest.New(rest.Config{
+			AuthHeader: adminAuthHeader,
+			Secret:     s.Secret,
+			TLS:        s.TLS(),
+			Proxy:      s.Proxy,
+			HTTP:       s.HTTP,
+		}))
+	}
+	return s.Client
 }
 
 // SSO allows you to generate a temporary authentication URL, valid for 60 seconds.
@@ -29,18 +48,10 @@ func (s *DashboardAdmin) SSO(section string, orgId string, email string, groupId
 	if section != "dashboard" && section != "portal" {
 		return "", fmt.Errorf("sso section must be 'dashboard' or 'portal' but got '%s'", section)
 	}
-	// Initialize the client if it hasn't been initialized yet
-	if s.Client == nil {
-		s.Client = resty.New()
-	}
-	if s.Server.AllowInsecure {
-		s.Client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
-	}
 
 	// Build the request. Resty will automatically encode the body as JSON when
 	// the Content-Type header is set to "application/json"
-	resp, err := s.Client.R().
-		SetHeader(adminAuthHeader, s.Secret).
+	resp, err := s.client().R().
 		SetHeader("Content-Type", "application/json").
 		SetBody(map[string]string{
 			"ForSection":   section,
@@ -71,16 +82,7 @@ func (s *DashboardAdmin) GetOrganizations() (*[]Organization, error) {
 		Pages         int            `json:"pages"`
 	})
 
-	// Initialize the client if it hasn't been initialized yet
-	if s.Client == nil {
-		s.Client = resty.New()
-	}
-	if s.Server.AllowInsecure {
-		s.Client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
-	}
-
-	resp, err := s.Client.R().
-		SetHeader("admin-auth", s.Secret).
+	resp, err := s.client().R().
 		SetHeader("Content-Type", "application/json").
 		SetResult(response).
 		Get(urljoin.Join(s.Url, endpoint))
@@ -97,6 +99,38 @@ func (s *DashboardAdmin) GetOrganizations() (*[]Organization, error) {
 	return &response.Organisations, nil
 }
 
+// GetOrganization will get an organization's metadata from the Tyk instance. The response is
+// returned as-is so that no fields are lost when it is later passed to UpdateOrganization.
+func (s *DashboardAdmin) GetOrganization(id string) (json.RawMessage, error) {
+	resp, err := s.client().R().
+		SetHeader("Content-Type", "application/json").
+		Get(urljoin.Join(s.Url, orgsEndpoint, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to send HTTP request: %v", err)
+	}
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("HTTP request failed with status code %v: %s", resp.StatusCode(), resp.String())
+	}
+
+	return json.RawMessage(resp.Body()), nil
+}
+
+// UpdateOrganization will replace an organization's metadata on the Tyk instance.
+func (s *DashboardAdmin) UpdateOrganization(id string, org json.RawMessage) error {
+	resp, err := s.client().R().
+		SetHeader("Content-Type", "application/json").
+		SetBody([]byte(org)).
+		Put(urljoin.Join(s.Url, orgsEndpoint, id))
+	if err != nil {
+		return fmt.Errorf("failed to send HTTP request: %v", err)
+	}
+	if resp.StatusCode() != 200 {
+		return fmt.Errorf("HTTP request failed with status code %v: %s", resp.StatusCode(), resp.String())
+	}
+
+	return nil
+}
+
 type Organization struct {
 	Id             string        `json:"id"`
 	OwnerName      string        `json:"owner_name"`
@@ -1,6 +1,8 @@
 // Package ops provides a set of tools for managing, developing, and testing Tyk APIs and Gateways.
 package ops
 
+import "github.com/AaronFeledy/tyk-ops/pkg/clients/rest"
+
 var (
 	Environments map[string]*Environment
 )
@@ -14,6 +16,36 @@ type Server struct {
 	Secret string `mapstructure:"secret" json:"-"`
 	// AllowInsecure is a flag that indicates whether or not to allow insecure connections.
 	AllowInsecure bool `mapstructure:"insecure" json:"insecure,omitempty"`
+	// CAFile is a PEM bundle of the certificate authorities that sign the server's certificate, they
+	// are trusted on top of the system's.
+	CAFile string `mapstructure:"ca_file" json:"ca_file,omitempty"`
+	// CertFile and KeyFile are the PEM client certificate and key presented to the server for mutual TLS.
+	CertFile string `mapstructure:"cert_file" json:"cert_file,omitempty"`
+	KeyFile  string `mapstructure:"key_file" json:"key_file,omitempty"`
+	// Proxy is the URL of the proxy that requests to the server are sent through. The HTTP_PROXY,
+	// HTTPS_PROXY and NO_PROXY environment variables are used when it is empty.
+	Proxy string `mapstructure:"proxy" json:"proxy,omitempty"`
+	// PoliciesFile is the policies file of a gateway that reads its policies from a file, policies are
+	// written to it instead of through the gateway's policy API.
+	PoliciesFile string `mapstructure:"policies_file" json:"policies_file,omitempty"`
+}
+
+// TLS returns the settings for the TLS connections to the server.
+func (s Server) TLS() rest.TLS {
+	return rest.TLS{
+		InsecureSkipVerify: s.AllowInsecure,
+		CAFile:             s.CAFile,
+		CertFile:           s.CertFile,
+		KeyFile:            s.KeyFile,
+	}
+}
+
+// SetTLS replaces the settings for the TLS connections to the server.
+func (s *Server) SetTLS(tls rest.TLS) {
+	s.AllowInsecure = tls.InsecureSkipVerify
+	s.CAFile = tls.CAFile
+	s.CertFile = tls.CertFile
+	s.KeyFile = tls.KeyFile
 }
 
 // Environment is the configuration for a Tyk environment.
@@ -23,4 +55,27 @@ type Environment struct {
 	Dashboard Server `mapstructure:"dashboard" json:"dashboard"`
 	Gateway   Server `mapstructure:"gateway" json:"gateway"`
 	Mserv     Server `mapstructure:"mserv" json:"mserv"`
+	// Vars are values for ${NAME} placeholders in definition files deployed to this environment.
+	Vars map[string]string `mapstructure:"vars" json:"vars,omitempty"`
+	// HTTP sets the timeouts, retries and rate limit of requests to the environment's servers.
+	HTTP rest.Options `mapstructure:"http" json:"http,omitempty"`
+}
+
+// Prune limits the objects a sync may delete from a target.
+type Prune struct {
+	// MaxDeletes is the largest number of APIs, and separately of policies, a sync may delete. There
+	// is no limit when it is 0.
+	MaxDeletes int `mapstructure:"max_deletes" json:"max_deletes,omitempty"`
+	// MaxDeletePercent is the largest share, in percent, of the APIs or policies managed by a sync
+	// that it may delete. It defaults to 50 when it is 0.
+	MaxDeletePercent float64 `mapstructure:"max_delete_percent" json:"max_delete_percent,omitempty"`
+	// Protected lists the objects that a sync never deletes.
+	Protected Protected `mapstructure:"protected" json:"protected,omitempty"`
+}
+
+// Protected selects objects by API or policy ID, by tag, or by a name pattern as used by path.Match.
+type Protected struct {
+	IDs   []string `mapstructure:"ids" json:"ids,omitempty"`
+	Tags  []string `mapstructure:"tags" json:"tags,omitempty"`
+	Names []string `mapstructure:"names" json:"names,omitempty"`
 }
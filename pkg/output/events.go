@@ -0,0 +1,148 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Format is the way a command reports what it did.
+type Format string
+
+const (
+	// FormatText prints human readable progress, it is the default.
+	FormatText Format = "text"
+	// FormatJSON prints a single result document listing every event once the command is done.
+	FormatJSON Format = "json"
+	// FormatNDJSON prints each event as a line of JSON as it happens, followed by the result.
+	FormatNDJSON Format = "ndjson"
+)
+
+// Event results
+const (
+	ResultOK      = "ok"
+	ResultFailed  = "failed"
+	ResultSkipped = "skipped"
+	// ResultPlanned is used for changes that were planned but not applied.
+	ResultPlanned = "planned"
+)
+
+// Object types
+const (
+	ObjectAPI       = "api"
+	ObjectPolicy    = "policy"
+	ObjectUser      = "user"
+	ObjectUserGroup = "user_group"
+)
+
+// Event is something a command did to an object, such as creating an API on the target.
+type Event struct {
+	Object string `json:"object"`
+	ID     string `json:"id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Action string `json:"action"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Result is the document printed when a command is done.
+type Result struct {
+	Command string `json:"command"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	// Events is left out of the last line of ndjson output, as the events were printed already.
+	Events []Event `json:"events,omitempty"`
+}
+
+var (
+	format = FormatText
+	events = []Event{}
+	// progressWriter is where Printf and Emit messages go, set by SetFormat
+	progressWriter io.Writer = os.Stdout
+	mu             sync.Mutex
+)
+
+// SetFormat sets the output format for the rest of the command. For the JSON formats, progress
+// printed with Printf and Emit goes to stderr instead of stdout, so that stdout only carries the JSON.
+func SetFormat(f string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	switch Format(f) {
+	case FormatText:
+		progressWriter = outWriter
+	case FormatJSON, FormatNDJSON:
+		progressWriter = errWriter
+	default:
+		return fmt.Errorf("unknown output format %q, use text, json or ndjson", f)
+	}
+
+	format = Format(f)
+	events = []Event{}
+	return nil
+}
+
+// Printf prints progress for the user, in the way set by SetFormat.
+func Printf(message string, a ...interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	fmt.Fprintf(progressWriter, message, a...)
+}
+
+// Println prints a line of progress for the user, in the way set by SetFormat.
+func Println(a ...interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	fmt.Fprintln(progressWriter, a...)
+}
+
+// Emit reports an event. The message, formatted with its arguments, is printed for the user;
+// an empty message prints nothing.
+func Emit(e Event, message string, a ...interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if message != "" {
+		fmt.Fprintf(progressWriter, message, a...)
+	}
+
+	switch format {
+	case FormatJSON:
+		events = append(events, e)
+	case FormatNDJSON:
+		writeJSON(e)
+	}
+}
+
+// Finish prints the result of a command in the JSON formats, err is the error the command failed
+// with, if any. Nothing is printed in text mode.
+func Finish(command string, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if format == FormatText {
+		return
+	}
+
+	result := Result{Command: command, Success: err == nil}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if format == FormatJSON {
+		result.Events = events
+	}
+	writeJSON(result)
+}
+
+func writeJSON(v interface{}) {
+	j, err := json.Marshal(v)
+	if err != nil {
+		User.Errorf("JSON Encoding error: %v", err)
+		return
+	}
+	fmt.Fprintf(outWriter, "%s\n", j)
+}
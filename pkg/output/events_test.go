@@ -0,0 +1,90 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// captureEvents runs f with the given format, returning what was written to stdout and to stderr.
+func captureEvents(t *testing.T, f string, run func()) (string, string) {
+	stdout, stderr, progress := outWriter, errWriter, progressWriter
+	defer func() {
+		outWriter, errWriter, progressWriter = stdout, stderr, progress
+		format = FormatText
+	}()
+
+	var outBuf, errBuf bytes.Buffer
+	outWriter, errWriter = &outBuf, &errBuf
+	if err := SetFormat(f); err != nil {
+		t.Fatal(err)
+	}
+	run()
+
+	return outBuf.String(), errBuf.String()
+}
+
+func TestSetFormat_Unknown(t *testing.T) {
+	err := SetFormat("yaml")
+	assert.Error(t, err)
+	assert.Equal(t, FormatText, format)
+}
+
+func TestEmit_JSON(t *testing.T) {
+	data, _ := captureEvents(t, "json", func() {
+		Emit(Event{Object: ObjectAPI, ID: "1", Name: "Orders", Action: "update", Result: ResultOK}, "")
+		Emit(Event{Object: ObjectPolicy, ID: "2", Action: "delete", Result: ResultFailed, Error: "boom"}, "")
+		Finish("sync", errors.New("boom"))
+	})
+
+	var result Result
+	if err := json.Unmarshal([]byte(data), &result); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "sync", result.Command)
+	assert.False(t, result.Success)
+	assert.Equal(t, "boom", result.Error)
+	assert.Equal(t, []Event{
+		{Object: ObjectAPI, ID: "1", Name: "Orders", Action: "update", Result: ResultOK},
+		{Object: ObjectPolicy, ID: "2", Action: "delete", Result: ResultFailed, Error: "boom"},
+	}, result.Events)
+}
+
+func TestEmit_NDJSON(t *testing.T) {
+	data, _ := captureEvents(t, "ndjson", func() {
+		Emit(Event{Object: ObjectAPI, ID: "1", Action: "create", Result: ResultOK}, "")
+		Finish("publish", nil)
+	})
+
+	lines := strings.Split(strings.TrimSpace(data), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", data)
+	}
+	assert.Equal(t, `{"object":"api","id":"1","action":"create","result":"ok"}`, lines[0])
+	assert.Equal(t, `{"command":"publish","success":true}`, lines[1])
+}
+
+func TestEmit_Text(t *testing.T) {
+	data, progress := captureEvents(t, "text", func() {
+		Emit(Event{Object: ObjectAPI, ID: "1", Action: "create", Result: ResultOK}, "Created %v\n", "1")
+		Printf("Done\n")
+		Finish("sync", nil)
+	})
+
+	assert.Equal(t, "Created 1\nDone\n", data)
+	assert.Empty(t, progress)
+}
+
+func TestPrintf_JSON(t *testing.T) {
+	data, progress := captureEvents(t, "json", func() {
+		Emit(Event{Object: ObjectAPI, ID: "1", Action: "create", Result: ResultOK}, "Created %v\n", "1")
+		Println("Done")
+	})
+
+	assert.Empty(t, data)
+	assert.Equal(t, "Created 1\nDone\n", progress)
+}
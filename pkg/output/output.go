@@ -44,6 +44,7 @@ func init() {
 		logLevel = log.DebugLevel
 	}
 	log.SetLevel(logLevel)
+	User.SetLevel(logLevel)
 }
 
 // DataWithFlair Prints only the data to stdout so it can be piped to other commands without including
@@ -0,0 +1,239 @@
+// Package snapshot reads and writes archives holding every API and policy of a Dashboard
+// organisation, so that the organisation can be restored later.
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"time"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+)
+
+// FormatVersion is the version of the archive layout written by this package.
+const FormatVersion = 1
+
+const (
+	manifestFile = "manifest.json"
+	orgFile      = "org.json"
+	apisDir      = "apis"
+	policiesDir  = "policies"
+)
+
+var UnsupportedVersionError = errors.New("Snapshot was created by an incompatible version of tykops")
+
+// Manifest describes the contents of a snapshot archive.
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	// Source is the URL of the dashboard the snapshot was taken from.
+	Source string `json:"source"`
+	OrgID  string `json:"org_id"`
+	// APIs and Policies list the archive files holding each object, in the order they were fetched.
+	APIs     []string `json:"apis"`
+	Policies []string `json:"policies"`
+	// CertificateIDs lists the certificates referenced by the APIs. Certificates are not part of the
+	// snapshot and must already exist on the dashboard it is restored to.
+	CertificateIDs []string `json:"certificate_ids"`
+	// HasOrg is set when the organisation's metadata was captured.
+	HasOrg bool `json:"has_org"`
+}
+
+// Snapshot is the state of a Dashboard organisation at a point in time.
+type Snapshot struct {
+	Manifest Manifest
+	APIs     []objects.DBApiDefinition
+	Policies []objects.Policy
+	// Org is the organisation's metadata as returned by the admin API, it is empty if the snapshot
+	// was taken without an admin secret.
+	Org json.RawMessage
+}
+
+// New creates a snapshot of the given objects, taken now.
+func New(source, orgID string, apis []objects.DBApiDefinition, pols []objects.Policy, org json.RawMessage) *Snapshot {
+	s := &Snapshot{
+		Manifest: Manifest{
+			Version:        FormatVersion,
+			CreatedAt:      time.Now().UTC(),
+			Source:         source,
+			OrgID:          orgID,
+			CertificateIDs: []string{},
+			HasOrg:         len(org) > 0,
+		},
+		APIs:     apis,
+		Policies: pols,
+		Org:      org,
+	}
+
+	seen := map[string]bool{}
+	for _, api := range apis {
+		for _, id := range api.CertificateIDs() {
+			if !seen[id] {
+				seen[id] = true
+				s.Manifest.CertificateIDs = append(s.Manifest.CertificateIDs, id)
+			}
+		}
+	}
+
+	return s
+}
+
+// FileName is the default name for the snapshot's archive, made from its org ID and the time it
+// was taken.
+func (s *Snapshot) FileName() string {
+	name := "snapshot"
+	if s.Manifest.OrgID != "" {
+		name += "-" + s.Manifest.OrgID
+	}
+	return fmt.Sprintf("%v-%v.tar.gz", name, s.Manifest.CreatedAt.Format("20060102T150405Z"))
+}
+
+// Write writes the snapshot to w as a gzipped tar archive.
+func (s *Snapshot) Write(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	files := map[string]interface{}{}
+	order := []string{}
+	add := func(name string, v interface{}) {
+		files[name] = v
+		order = append(order, name)
+	}
+
+	// Objects without an ID, or sharing one, are named by their position instead so none are lost
+	used := map[string]bool{}
+	fileName := func(dir, prefix, id string, i int) string {
+		name := path.Join(dir, fmt.Sprintf("%v-%v.json", prefix, id))
+		if id == "" || used[name] {
+			name = path.Join(dir, fmt.Sprintf("%v-%v.json", prefix, i))
+		}
+		used[name] = true
+		return name
+	}
+
+	manifest := s.Manifest
+	manifest.APIs = make([]string, len(s.APIs))
+	for i, api := range s.APIs {
+		manifest.APIs[i] = fileName(apisDir, "api", api.APIID, i)
+	}
+	manifest.Policies = make([]string, len(s.Policies))
+	for i, pol := range s.Policies {
+		manifest.Policies[i] = fileName(policiesDir, "policy", pol.ID, i)
+	}
+	manifest.HasOrg = len(s.Org) > 0
+
+	add(manifestFile, manifest)
+	if manifest.HasOrg {
+		add(orgFile, s.Org)
+	}
+	for i, name := range manifest.APIs {
+		add(name, s.APIs[i])
+	}
+	for i, name := range manifest.Policies {
+		add(name, s.Policies[i])
+	}
+
+	for _, name := range order {
+		data, err := json.MarshalIndent(files[name], "", "  ")
+		if err != nil {
+			return err
+		}
+
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: manifest.CreatedAt,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Read reads a snapshot archive written by Write.
+func Read(r io.Reader) (*Snapshot, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[hdr.Name] = data
+	}
+
+	s := &Snapshot{}
+	if err := unmarshalFile(files, manifestFile, &s.Manifest); err != nil {
+		return nil, err
+	}
+
+	if s.Manifest.Version != FormatVersion {
+		return nil, UnsupportedVersionError
+	}
+
+	if s.Manifest.HasOrg {
+		s.Org = json.RawMessage(files[orgFile])
+		if len(s.Org) == 0 {
+			return nil, fmt.Errorf("snapshot is missing %v", orgFile)
+		}
+	}
+
+	s.APIs = make([]objects.DBApiDefinition, len(s.Manifest.APIs))
+	for i, name := range s.Manifest.APIs {
+		s.APIs[i] = objects.DBApiDefinition{APIDefinition: &objects.APIDefinition{}}
+		if err := unmarshalFile(files, name, &s.APIs[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	s.Policies = make([]objects.Policy, len(s.Manifest.Policies))
+	for i, name := range s.Manifest.Policies {
+		if err := unmarshalFile(files, name, &s.Policies[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func unmarshalFile(files map[string][]byte, name string, v interface{}) error {
+	data, ok := files[name]
+	if !ok {
+		return fmt.Errorf("snapshot is missing %v", name)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("couldn't read %v from snapshot: %v", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot_RoundTrip(t *testing.T) {
+	api := objects.DBApiDefinition{APIDefinition: &objects.APIDefinition{}}
+	api.APIID = "one"
+	api.Name = "One"
+	api.Certificates = []string{"cert-a"}
+	api.PinnedPublicKeys = map[string]string{"example.com": "cert-b, cert-a"}
+
+	pol := objects.Policy{ID: "pol-one", Name: "Policy One"}
+	org := json.RawMessage(`{"id":"org","owner_name":"Org"}`)
+
+	s := New("http://dashboard", "org", []objects.DBApiDefinition{api}, []objects.Policy{pol}, org)
+	assert.Equal(t, []string{"cert-a", "cert-b"}, s.Manifest.CertificateIDs)
+	assert.Regexp(t, `^snapshot-org-\d{8}T\d{6}Z\.tar\.gz$`, s.FileName())
+
+	buf := &bytes.Buffer{}
+	if err := s.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, FormatVersion, read.Manifest.Version)
+	assert.Equal(t, "org", read.Manifest.OrgID)
+	assert.Equal(t, []string{"apis/api-one.json"}, read.Manifest.APIs)
+	assert.True(t, read.Manifest.CreatedAt.Equal(s.Manifest.CreatedAt))
+	if assert.Len(t, read.APIs, 1) {
+		assert.Equal(t, "One", read.APIs[0].Name)
+		assert.Equal(t, []string{"cert-a"}, read.APIs[0].Certificates)
+	}
+	if assert.Len(t, read.Policies, 1) {
+		assert.Equal(t, "Policy One", read.Policies[0].Name)
+	}
+	assert.JSONEq(t, string(org), string(read.Org))
+}
+
+func TestRead_UnsupportedVersion(t *testing.T) {
+	s := New("http://dashboard", "org", nil, nil, nil)
+	s.Manifest.Version = FormatVersion + 1
+
+	buf := &bytes.Buffer{}
+	if err := s.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Read(buf)
+	assert.Equal(t, UnsupportedVersionError, err)
+}
@@ -0,0 +1,229 @@
+package tyk_vcs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/output"
+	"gopkg.in/src-d/go-billy.v4"
+)
+
+// fingerprintLength is the length of a hex SHA256, which Tyk ends certificate IDs with.
+const fingerprintLength = 64
+
+// Certificate is a PEM certificate, or public key, listed in a spec.
+type Certificate struct {
+	// File is the file the certificate was read from.
+	File string
+	// Fingerprint is the hex SHA256 of the certificate, or of the public key when the file has no
+	// certificate. Tyk prefixes it with an organisation ID to make the certificate's ID.
+	Fingerprint string
+	// PEM is the contents of the file, including the private key when it has one.
+	PEM []byte
+}
+
+// CertificateStore is a target that certificates are uploaded to.
+type CertificateStore interface {
+	FetchCertificates() ([]objects.CertificateMeta, error)
+	CreateCertificate(cert []byte) (string, error)
+}
+
+// CertificateFingerprint returns the fingerprint Tyk gives to the certificate in PEM data, or to its
+// public key when it has no certificate.
+func CertificateFingerprint(data []byte) (string, error) {
+	var publicKey []byte
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			sum := sha256.Sum256(block.Bytes)
+			return hex.EncodeToString(sum[:]), nil
+		case "PUBLIC KEY":
+			if publicKey == nil {
+				publicKey = block.Bytes
+			}
+		}
+	}
+
+	if publicKey == nil {
+		return "", errors.New("no PEM certificate or public key found")
+	}
+	sum := sha256.Sum256(publicKey)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// referenceFingerprint returns the fingerprint a certificate ID ends with, or an empty string when
+// it doesn't look like a Tyk certificate ID.
+func referenceFingerprint(id string) string {
+	if len(id) < fingerprintLength {
+		return ""
+	}
+	fingerprint := strings.ToLower(id[len(id)-fingerprintLength:])
+	if _, err := hex.DecodeString(fingerprint); err != nil {
+		return ""
+	}
+	return fingerprint
+}
+
+func fetchCertificates(fs billy.Filesystem, spec *TykSourceSpec, subdirectoryPath string) ([]Certificate, error) {
+	files := []string{}
+	for _, info := range spec.Certificates {
+		if !isGlob(info.File) {
+			files = append(files, info.File)
+			continue
+		}
+
+		all, err := listFiles(fs, subdirectoryPath)
+		if err != nil {
+			return nil, err
+		}
+		matches, err := matchFiles(all, info.File)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+
+	certs := []Certificate{}
+	seen := map[string]bool{}
+	for _, file := range files {
+		data, err := readSource(fs, file, subdirectoryPath)
+		if err != nil {
+			return nil, err
+		}
+
+		fingerprint, err := CertificateFingerprint(data)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", file, err)
+		}
+		if seen[fingerprint] {
+			continue
+		}
+		seen[fingerprint] = true
+
+		certs = append(certs, Certificate{File: file, Fingerprint: fingerprint, PEM: data})
+	}
+
+	if len(certs) > 0 {
+		output.Printf("Fetched %v certificates\n", len(certs))
+	}
+
+	return certs, nil
+}
+
+// ResolveCertificates uploads the certificates listed in a spec that are missing from the target,
+// and rewrites the certificate IDs the APIs refer to into the IDs of the same certificates on the
+// target. Certificates are matched by their fingerprint, as the rest of their ID is specific to the
+// organisation they were added to.
+//
+// Nothing is uploaded when dryRun is set, the certificates that would be uploaded are returned
+// instead and references to them are left as they are. A warning is returned for every reference
+// to a certificate that is neither on the target nor listed in the spec.
+func ResolveCertificates(defs []objects.DBApiDefinition, certs []Certificate, store CertificateStore, dryRun bool) ([]string, []Certificate, error) {
+	referenced := false
+	for _, def := range defs {
+		if len(def.CertificateIDs()) > 0 {
+			referenced = true
+			break
+		}
+	}
+	if !referenced && len(certs) == 0 {
+		return nil, nil, nil
+	}
+
+	existing, err := store.FetchCertificates()
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching certificates: %v", err)
+	}
+
+	// Target IDs by fingerprint
+	ids := map[string]string{}
+	onTarget := map[string]bool{}
+	for _, cert := range existing {
+		onTarget[cert.ID] = true
+		fingerprint := strings.ToLower(cert.Fingerprint)
+		if fingerprint == "" {
+			fingerprint = referenceFingerprint(cert.ID)
+		}
+		if fingerprint != "" {
+			ids[fingerprint] = cert.ID
+		}
+	}
+
+	pending := []Certificate{}
+	for _, cert := range certs {
+		if _, ok := ids[cert.Fingerprint]; ok {
+			continue
+		}
+		if dryRun {
+			output.Printf("Certificate %v would be uploaded\n", cert.File)
+			pending = append(pending, cert)
+			continue
+		}
+
+		id, err := store.CreateCertificate(cert.PEM)
+		if err != nil {
+			return nil, nil, fmt.Errorf("uploading certificate %v: %v", cert.File, err)
+		}
+		output.Printf("Uploaded certificate %v: %v\n", cert.File, id)
+		ids[cert.Fingerprint] = id
+		onTarget[id] = true
+	}
+
+	listed := map[string]bool{}
+	for _, cert := range certs {
+		listed[cert.Fingerprint] = true
+	}
+
+	warnings := []string{}
+	warned := map[string]bool{}
+	resolve := func(def *objects.DBApiDefinition, id string) string {
+		id = strings.TrimSpace(id)
+		if id == "" || onTarget[id] {
+			return id
+		}
+
+		fingerprint := referenceFingerprint(id)
+		if targetID, ok := ids[fingerprint]; ok && fingerprint != "" {
+			return targetID
+		}
+
+		// Certificates that would be uploaded aren't missing
+		if !listed[fingerprint] && !warned[def.APIID+id] {
+			warned[def.APIID+id] = true
+			warnings = append(warnings, fmt.Sprintf("API %v refers to certificate %v, which is neither on the target nor listed in the spec", def.Name, id))
+		}
+		return id
+	}
+
+	for i := range defs {
+		def := &defs[i]
+		for j, id := range def.Certificates {
+			def.Certificates[j] = resolve(def, id)
+		}
+		for j, id := range def.ClientCertificates {
+			def.ClientCertificates[j] = resolve(def, id)
+		}
+		for domain, id := range def.UpstreamCertificates {
+			def.UpstreamCertificates[domain] = resolve(def, id)
+		}
+		for domain, keys := range def.PinnedPublicKeys {
+			resolved := []string{}
+			for _, id := range strings.Split(keys, ",") {
+				resolved = append(resolved, resolve(def, id))
+			}
+			def.PinnedPublicKeys[domain] = strings.Join(resolved, ",")
+		}
+	}
+
+	return warnings, pending, nil
+}
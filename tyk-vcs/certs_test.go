@@ -0,0 +1,184 @@
+package tyk_vcs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/util"
+)
+
+// testCertificate returns a self-signed PEM certificate and the hex SHA256 of its DER.
+func testCertificate(t *testing.T, name string) ([]byte, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256(der)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), hex.EncodeToString(sum[:])
+}
+
+func TestCertificateFingerprint(t *testing.T) {
+	cert, fingerprint := testCertificate(t, "orders")
+
+	got, err := CertificateFingerprint(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, fingerprint, got)
+
+	// The certificate is used even when the private key comes first
+	withKey := append(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("key")}), cert...)
+	got, err = CertificateFingerprint(withKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, fingerprint, got)
+
+	publicKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("public")})
+	got, err = CertificateFingerprint(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("public"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), got)
+
+	_, err = CertificateFingerprint([]byte("not a certificate"))
+	assert.Error(t, err)
+}
+
+func TestFSGetter_FetchCertificates(t *testing.T) {
+	orders, ordersFingerprint := testCertificate(t, "orders")
+	billing, billingFingerprint := testCertificate(t, "billing")
+
+	fs := memfs.New()
+	files := map[string][]byte{
+		"repo/certs/orders.pem":       orders,
+		"repo/certs/team/billing.pem": billing,
+		"repo/orders-copy.pem":        orders,
+	}
+	for name, content := range files {
+		if err := util.WriteFile(fs, name, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gg := &FSGetter{fs: fs, subdirectoryPath: "repo"}
+	spec := &TykSourceSpec{Certificates: []CertificateInfo{{File: "certs/**/*.pem"}, {File: "orders-copy.pem"}}}
+	certs, err := gg.FetchCertificates(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The copy is left out as it is the same certificate
+	fingerprints := map[string]bool{}
+	for _, cert := range certs {
+		fingerprints[cert.Fingerprint] = true
+	}
+	assert.Len(t, certs, 2)
+	assert.Equal(t, map[string]bool{ordersFingerprint: true, billingFingerprint: true}, fingerprints)
+
+	spec.Certificates = []CertificateInfo{{File: "missing.pem"}}
+	_, err = gg.FetchCertificates(spec)
+	assert.Error(t, err)
+}
+
+type fakeCertificateStore struct {
+	certs    []objects.CertificateMeta
+	uploaded [][]byte
+}
+
+func (s *fakeCertificateStore) FetchCertificates() ([]objects.CertificateMeta, error) {
+	return s.certs, nil
+}
+
+func (s *fakeCertificateStore) CreateCertificate(cert []byte) (string, error) {
+	fingerprint, err := CertificateFingerprint(cert)
+	if err != nil {
+		return "", err
+	}
+	s.uploaded = append(s.uploaded, cert)
+	return "target" + fingerprint, nil
+}
+
+func TestResolveCertificates(t *testing.T) {
+	orders, ordersFingerprint := testCertificate(t, "orders")
+	billing, billingFingerprint := testCertificate(t, "billing")
+	missing := "source" + hex.EncodeToString(make([]byte, 32))
+
+	api := func() objects.DBApiDefinition {
+		def := objects.DBApiDefinition{APIDefinition: &objects.APIDefinition{}}
+		def.APIID = "one"
+		def.Name = "One"
+		def.Certificates = []string{"source" + ordersFingerprint}
+		def.ClientCertificates = []string{"source" + billingFingerprint, missing}
+		def.UpstreamCertificates = map[string]string{"orders.internal": "source" + ordersFingerprint}
+		def.PinnedPublicKeys = map[string]string{"*": "source" + ordersFingerprint + ", source" + billingFingerprint}
+		return def
+	}
+	certs := []Certificate{
+		{File: "orders.pem", Fingerprint: ordersFingerprint, PEM: orders},
+		{File: "billing.pem", Fingerprint: billingFingerprint, PEM: billing},
+	}
+	store := func() *fakeCertificateStore {
+		return &fakeCertificateStore{certs: []objects.CertificateMeta{{ID: "target" + ordersFingerprint, Fingerprint: ordersFingerprint}}}
+	}
+
+	s := store()
+	defs := []objects.DBApiDefinition{api()}
+	warnings, pending, err := ResolveCertificates(defs, certs, s, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, [][]byte{billing}, s.uploaded)
+	assert.Empty(t, pending)
+	assert.Equal(t, []string{"target" + ordersFingerprint}, defs[0].Certificates)
+	assert.Equal(t, []string{"target" + billingFingerprint, missing}, defs[0].ClientCertificates)
+	assert.Equal(t, map[string]string{"orders.internal": "target" + ordersFingerprint}, defs[0].UpstreamCertificates)
+	assert.Equal(t, map[string]string{"*": "target" + ordersFingerprint + ",target" + billingFingerprint}, defs[0].PinnedPublicKeys)
+	assert.Equal(t, []string{"API One refers to certificate " + missing + ", which is neither on the target nor listed in the spec"}, warnings)
+
+	// A dry run uploads nothing, and leaves references to the certificates it would upload
+	s = store()
+	defs = []objects.DBApiDefinition{api()}
+	warnings, pending, err = ResolveCertificates(defs, certs, s, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Empty(t, s.uploaded)
+	assert.Equal(t, []Certificate{certs[1]}, pending)
+	assert.Equal(t, []string{"target" + ordersFingerprint}, defs[0].Certificates)
+	assert.Equal(t, []string{"source" + billingFingerprint, missing}, defs[0].ClientCertificates)
+	assert.Len(t, warnings, 1)
+
+	// The target isn't contacted when there is nothing to resolve
+	plain := objects.DBApiDefinition{APIDefinition: &objects.APIDefinition{}}
+	warnings, pending, err = ResolveCertificates([]objects.DBApiDefinition{plain}, nil, nil, false)
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.Empty(t, pending)
+}
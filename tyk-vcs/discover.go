@@ -0,0 +1,271 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/output"
+	"gopkg.in/src-d/go-billy.v4"
+)
+
+type fileKind int
+
+const (
+	kindUnknown fileKind = iota
+	kindAPI
+	kindOAS
+	kindPolicy
+)
+
+// expandSpec replaces the glob patterns in a spec's file lists with the files they match, and adds
+// the files found in its discovery directories. A file is never listed twice, and files that are
+// listed explicitly keep their overrides.
+func expandSpec(fs billy.Filesystem, ts *TykSourceSpec, subdirectoryPath string) error {
+	hasPatterns := false
+	listed := map[string]bool{}
+	for _, info := range ts.Files {
+		if isGlob(info.File) {
+			hasPatterns = true
+		} else {
+			listed[cleanPath(info.File)] = true
+		}
+	}
+	for _, info := range ts.Policies {
+		if isGlob(info.File) {
+			hasPatterns = true
+		} else {
+			listed[cleanPath(info.File)] = true
+		}
+	}
+
+	if !hasPatterns && len(ts.Discover) == 0 {
+		return nil
+	}
+
+	all, err := listFiles(fs, subdirectoryPath)
+	if err != nil {
+		return err
+	}
+
+	files := []APIInfo{}
+	for _, info := range ts.Files {
+		if !isGlob(info.File) {
+			files = append(files, info)
+			continue
+		}
+
+		matches, err := matchFiles(all, info.File)
+		if err != nil {
+			return err
+		}
+		if len(matches) > 1 && (info.APIID != "" || info.DBID != "") {
+			return fmt.Errorf("%v matches %v files, api_id and db_id can only be set for a single file", info.File, len(matches))
+		}
+
+		for _, match := range matches {
+			if listed[match] {
+				continue
+			}
+			listed[match] = true
+			expanded := info
+			expanded.File = match
+			files = append(files, expanded)
+		}
+	}
+
+	pols := []PolicyInfo{}
+	for _, info := range ts.Policies {
+		if !isGlob(info.File) {
+			pols = append(pols, info)
+			continue
+		}
+
+		matches, err := matchFiles(all, info.File)
+		if err != nil {
+			return err
+		}
+		if len(matches) > 1 && info.ID != "" {
+			return fmt.Errorf("%v matches %v files, id can only be set for a single file", info.File, len(matches))
+		}
+
+		for _, match := range matches {
+			if listed[match] {
+				continue
+			}
+			listed[match] = true
+			pols = append(pols, PolicyInfo{File: match, ID: info.ID})
+		}
+	}
+
+	var discoveredAPIs, discoveredPolicies int
+	for _, dir := range ts.Discover {
+		dir = cleanPath(dir)
+		for _, file := range all {
+			if dir != "." && !strings.HasPrefix(file, dir+"/") {
+				continue
+			}
+			if listed[file] || !isDefinitionFile(file) {
+				continue
+			}
+			listed[file] = true
+
+			raw, err := readFile(fs, file, subdirectoryPath)
+			if err != nil {
+				return err
+			}
+
+			switch classify(raw) {
+			case kindAPI:
+				files = append(files, APIInfo{File: file, Type: TYPE_APIDEF})
+				discoveredAPIs++
+			case kindOAS:
+				files = append(files, APIInfo{File: file, Type: TYPE_OAI})
+				discoveredAPIs++
+			case kindPolicy:
+				pols = append(pols, PolicyInfo{File: file})
+				discoveredPolicies++
+			}
+		}
+	}
+
+	if len(ts.Discover) > 0 {
+		output.Printf("Discovered %v API definitions and %v policies\n", discoveredAPIs, discoveredPolicies)
+	}
+
+	ts.Files = files
+	ts.Policies = pols
+	return nil
+}
+
+// classify works out what a file holds from its JSON contents.
+func classify(raw []byte) fileKind {
+	doc := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return kindUnknown
+	}
+
+	has := func(key string) bool {
+		_, ok := doc[key]
+		return ok
+	}
+
+	switch {
+	case has("api_definition"):
+		return kindAPI
+	case has("swagger"):
+		// Only Swagger 2.0 documents can be imported, OpenAPI 3 documents are left alone
+		return kindOAS
+	case has("access_rights"):
+		return kindPolicy
+	case has("proxy") && (has("api_id") || has("version_data")):
+		return kindAPI
+	default:
+		return kindUnknown
+	}
+}
+
+// listFiles returns the path of every file in the repo, relative to the subdirectory. Hidden files
+// and directories, such as the spec itself, are skipped.
+func listFiles(fs billy.Filesystem, subdirectoryPath string) ([]string, error) {
+	files := []string{}
+
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		entries, err := fs.ReadDir(getFilepath(dir, subdirectoryPath))
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+
+			name := path.Join(dir, entry.Name())
+			if entry.IsDir() {
+				if err := walk(name); err != nil {
+					return err
+				}
+				continue
+			}
+			files = append(files, name)
+		}
+
+		return nil
+	}
+
+	if err := walk(""); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// matchFiles returns the files that match a glob pattern.
+func matchFiles(files []string, pattern string) ([]string, error) {
+	patternSegments := strings.Split(cleanPath(pattern), "/")
+
+	matches := []string{}
+	for _, file := range files {
+		ok, err := matchSegments(patternSegments, strings.Split(file, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %v: %v", pattern, err)
+		}
+		if ok {
+			matches = append(matches, file)
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%v does not match any files", pattern)
+	}
+
+	return matches, nil
+}
+
+// matchSegments matches a path against a pattern one path segment at a time, where a ** segment
+// matches any number of segments.
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				ok, err := matchSegments(pattern[1:], name[i:])
+				if ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+
+		if len(name) == 0 {
+			return false, nil
+		}
+
+		ok, err := path.Match(pattern[0], name[0])
+		if !ok || err != nil {
+			return false, err
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0, nil
+}
+
+func isGlob(file string) bool {
+	return strings.ContainsAny(file, "*?[")
+}
+
+// isDefinitionFile reports whether a file could hold a definition, based on its extension.
+func isDefinitionFile(file string) bool {
+	return isYAML(file) || strings.ToLower(filepath.Ext(file)) == ".json"
+}
+
+func cleanPath(file string) string {
+	return path.Clean(filepath.ToSlash(file))
+}
@@ -0,0 +1,148 @@
+package tyk_vcs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/util"
+)
+
+func TestMatchSegments(t *testing.T) {
+	tests := []struct {
+		pattern, file string
+		want          bool
+	}{
+		{"apis/*.json", "apis/one.json", true},
+		{"apis/*.json", "apis/team/one.json", false},
+		{"apis/**/*.json", "apis/one.json", true},
+		{"apis/**/*.json", "apis/team/sub/one.json", true},
+		{"apis/**", "apis/team/one.yaml", true},
+		{"**/policy-*.json", "teams/a/policy-one.json", true},
+		{"**/policy-*.json", "teams/a/api-one.json", false},
+	}
+
+	for _, tt := range tests {
+		got, err := matchFiles([]string{tt.file}, tt.pattern)
+		if tt.want {
+			assert.NoError(t, err, tt.pattern)
+			assert.Equal(t, []string{tt.file}, got, tt.pattern)
+		} else {
+			assert.Error(t, err, tt.pattern)
+		}
+	}
+}
+
+func TestExpandSpec(t *testing.T) {
+	fs := memfs.New()
+	files := map[string]string{
+		"repo/.tyk.json":                `{}`,
+		"repo/apis/a.json":              `{"api_definition": {"api_id": "a"}}`,
+		"repo/apis/team/b.yaml":         "api_id: b\nproxy:\n  listen_path: /b/\n",
+		"repo/apis/team/readme.json":    `{"title": "not a definition"}`,
+		"repo/oas/petstore.json":        `{"swagger": "2.0"}`,
+		"repo/policies/p.json":          `{"id": "p", "access_rights": {}}`,
+		"repo/policies/nested/q.yml":    "id: q\naccess_rights: {}\n",
+		"repo/policies/.hidden/r.json":  `{"id": "r", "access_rights": {}}`,
+		"repo/elsewhere/unrelated.json": `{"api_definition": {}}`,
+		"repo/discovered/policy.json":   `{"id": "d", "access_rights": {}}`,
+		"repo/discovered/api.json":      `{"api_definition": {"api_id": "d"}}`,
+		"repo/discovered/swagger.yaml":  "swagger: \"2.0\"\n",
+		"repo/discovered/openapi3.yaml": "openapi: 3.0.0\n",
+		"repo/discovered/notes.txt":     "not json",
+		"repo/discovered/apis/a.json":   `{"api_definition": {"api_id": "da"}}`,
+	}
+	for name, content := range files {
+		if err := util.WriteFile(fs, name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("globs", func(t *testing.T) {
+		ts := &TykSourceSpec{
+			Type: TYPE_APIDEF,
+			Files: []APIInfo{
+				{File: "apis/team/b.yaml", APIID: "explicit"},
+				{File: "apis/**/*.*"},
+				{File: "oas/*.json", Type: TYPE_OAI},
+			},
+			Policies: []PolicyInfo{{File: "policies/**/*"}},
+		}
+		if err := expandSpec(fs, ts, "repo"); err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, []APIInfo{
+			{File: "apis/team/b.yaml", APIID: "explicit"},
+			{File: "apis/a.json"},
+			{File: "apis/team/readme.json"},
+			{File: "oas/petstore.json", Type: TYPE_OAI},
+		}, ts.Files)
+		assert.Equal(t, []PolicyInfo{{File: "policies/nested/q.yml"}, {File: "policies/p.json"}}, ts.Policies)
+	})
+
+	t.Run("patterns that match nothing", func(t *testing.T) {
+		ts := &TykSourceSpec{Files: []APIInfo{{File: "missing/*.json"}}}
+		assert.Error(t, expandSpec(fs, ts, "repo"))
+	})
+
+	t.Run("overrides on patterns matching several files", func(t *testing.T) {
+		ts := &TykSourceSpec{Files: []APIInfo{{File: "apis/**/*", APIID: "one"}}}
+		assert.Error(t, expandSpec(fs, ts, "repo"))
+	})
+
+	t.Run("discovery", func(t *testing.T) {
+		ts := &TykSourceSpec{
+			Files:    []APIInfo{{File: "discovered/api.json", APIID: "explicit"}},
+			Discover: []string{"discovered"},
+		}
+		if err := expandSpec(fs, ts, "repo"); err != nil {
+			t.Fatal(err)
+		}
+
+		types := map[string]SpecType{}
+		for _, f := range ts.Files {
+			types[f.File] = f.Type
+		}
+		assert.Equal(t, "explicit", ts.Files[0].APIID, "explicitly listed files should keep their overrides")
+		assert.Equal(t, SpecType(""), types["discovered/api.json"])
+		assert.Equal(t, TYPE_APIDEF, types["discovered/apis/a.json"])
+		assert.Equal(t, TYPE_OAI, types["discovered/swagger.yaml"])
+		assert.NotContains(t, types, "discovered/openapi3.yaml")
+		assert.NotContains(t, types, "discovered/notes.txt")
+		assert.NotContains(t, types, "apis/a.json", "only the discovery directories should be searched")
+		assert.Len(t, ts.Files, 3)
+		assert.Equal(t, []PolicyInfo{{File: "discovered/policy.json"}}, ts.Policies)
+	})
+}
+
+func TestFSGetter_Discover(t *testing.T) {
+	g := writeRepo(t, map[string]string{
+		".tyk.yaml":          "discover: [.]\n",
+		"team-a/api.yaml":    "api_definition:\n  api_id: a\n  name: A\n",
+		"team-b/api.json":    `{"api_definition": {"api_id": "b", "name": "B"}}`,
+		"team-b/policy.json": `{"id": "p", "org_id": "org", "access_rights": {}}`,
+	})
+
+	ts, err := g.FetchTykSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defs, err := g.FetchAPIDef(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, defs, 2) {
+		assert.Equal(t, "a", defs[0].APIID)
+		assert.Equal(t, "b", defs[1].APIID)
+	}
+
+	pols, err := g.FetchPolicies(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, pols, 1) {
+		assert.Equal(t, "p", pols[0].ID)
+	}
+}
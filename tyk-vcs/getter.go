@@ -5,10 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/output"
 	tyk_swagger "github.com/AaronFeledy/tyk-ops/tyk-swagger"
 	"github.com/TykTechnologies/storage/persistent/model"
 	"io/ioutil"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/src-d/go-billy.v4"
 	"gopkg.in/src-d/go-billy.v4/memfs"
@@ -23,7 +25,11 @@ type Getter interface {
 	FetchRepo() error
 	FetchAPIDef(spec *TykSourceSpec) ([]objects.DBApiDefinition, error)
 	FetchPolicies(spec *TykSourceSpec) ([]objects.Policy, error)
+	FetchCertificates(spec *TykSourceSpec) ([]Certificate, error)
+	FetchUsers(spec *TykSourceSpec) ([]objects.User, error)
+	FetchUserGroups(spec *TykSourceSpec) ([]objects.UserGroup, error)
 	FetchTykSpec() (*TykSourceSpec, error)
+	Validate(spec *TykSourceSpec) ([]ValidationError, error)
 }
 
 type BaseGetter struct {
@@ -80,7 +86,7 @@ func (gg *GitGetter) FetchRepo() error {
 	if len(gg.key) != 0 {
 		publicKey, keyError := ssh.NewPublicKeys("git", gg.key, "")
 		if keyError != nil {
-			fmt.Println("Error getting key for git authentication:", keyError)
+			output.Println("Error getting key for git authentication:", keyError)
 		}
 		cloneOptions.Auth = publicKey
 	}
@@ -100,13 +106,22 @@ func (gg *FSGetter) FetchRepo() error {
 }
 
 func fetchSpec(fs billy.Filesystem, subdirectoryPath string) (*TykSourceSpec, error) {
-	specFile, err := fs.Open(getFilepath(".tyk.json", subdirectoryPath))
-	if err != nil {
-		fmt.Println(".tyk.json")
-		return nil, err
+	specName := ""
+	for _, name := range specFileNames {
+		if _, err := fs.Stat(getFilepath(name, subdirectoryPath)); err != nil {
+			continue
+		}
+		if specName != "" {
+			return nil, fmt.Errorf("found both %v and %v, only one spec file may be used", specName, name)
+		}
+		specName = name
 	}
 
-	rawSpec, err := ioutil.ReadAll(specFile)
+	if specName == "" {
+		return nil, fmt.Errorf("no spec file found, expected one of %v", strings.Join(specFileNames, ", "))
+	}
+
+	rawSpec, err := readFile(fs, specName, subdirectoryPath)
 	if err != nil {
 		return nil, err
 	}
@@ -117,9 +132,34 @@ func fetchSpec(fs billy.Filesystem, subdirectoryPath string) (*TykSourceSpec, er
 		return nil, err
 	}
 
+	if err := expandSpec(fs, &ts, subdirectoryPath); err != nil {
+		return nil, err
+	}
+
 	return &ts, nil
 }
 
+// readFile reads a file from the repo, converting it to JSON if it is a YAML file.
+func readFile(fs billy.Filesystem, file string, subdirectoryPath string) ([]byte, error) {
+	raw, err := readSource(fs, file, subdirectoryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return toJSON(file, raw)
+}
+
+// readSource reads a file from the repo as it was written.
+func readSource(fs billy.Filesystem, file string, subdirectoryPath string) ([]byte, error) {
+	f, err := fs.Open(getFilepath(file, subdirectoryPath))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ioutil.ReadAll(f)
+}
+
 func (gg *GitGetter) FetchTykSpec() (*TykSourceSpec, error) {
 	if gg.r == nil {
 		return nil, errors.New("no repository in memory, fetch repo first")
@@ -143,112 +183,111 @@ func (gg *GitGetter) FetchAPIDef(spec *TykSourceSpec) ([]objects.DBApiDefinition
 }
 
 func fetchAPIDefinitions(fs billy.Filesystem, spec *TykSourceSpec, subdirectoryPath string) ([]objects.DBApiDefinition, error) {
-	switch spec.Type {
-	case TYPE_APIDEF:
-		return fetchAPIDefinitionsDirect(fs, spec, subdirectoryPath)
-	case TYPE_OAI:
-		return fetchAPIDefinitionsFromOAI(fs, spec, subdirectoryPath)
-	default:
-		return nil, fmt.Errorf("Type must be '%v or '%v'", TYPE_APIDEF, TYPE_OAI)
-	}
-}
-
-func fetchAPIDefinitionsDirect(fs billy.Filesystem, spec *TykSourceSpec, subdirectoryPath string) ([]objects.DBApiDefinition, error) {
-	defNames := spec.Files
-	defs := make([]objects.DBApiDefinition, len(defNames))
-	for i, defInfo := range defNames {
-		defFile, err := fs.Open(getFilepath(defInfo.File, subdirectoryPath))
-		if err != nil {
-			return nil, err
+	defs := make([]objects.DBApiDefinition, len(spec.Files))
+	for i, defInfo := range spec.Files {
+		var ad *objects.DBApiDefinition
+		var err error
+		switch spec.fileType(defInfo) {
+		case TYPE_APIDEF:
+			ad, err = fetchAPIDefinitionDirect(fs, spec, defInfo, subdirectoryPath)
+		case TYPE_OAI:
+			ad, err = fetchAPIDefinitionFromOAI(fs, spec, defInfo, subdirectoryPath)
+		default:
+			return nil, fmt.Errorf("Type must be '%v or '%v'", TYPE_APIDEF, TYPE_OAI)
 		}
-
-		rawDef, err := ioutil.ReadAll(defFile)
 		if err != nil {
 			return nil, err
 		}
 
-		ad := objects.DBApiDefinition{}
-		err = json.Unmarshal(rawDef, &ad)
-		if err != nil || (ad.APIDefinition == nil) {
-			def := objects.APIDefinition{}
-			errSecondUnmarshal := json.Unmarshal(rawDef, &def)
-			if errSecondUnmarshal != nil {
-				return nil, err
-			}
-			ad.APIDefinition = &def
-		}
+		defs[i] = *ad
+	}
 
-		if defInfo.APIID != "" {
-			ad.APIID = defInfo.APIID
-		}
+	output.Printf("Fetched %v definitions\n", len(defs))
+	return defs, nil
+}
 
-		if defInfo.DBID != "" {
-			ad.Id = model.ObjectIDHex(defInfo.DBID)
-		}
+func fetchAPIDefinitionDirect(fs billy.Filesystem, spec *TykSourceSpec, defInfo APIInfo, subdirectoryPath string) (*objects.DBApiDefinition, error) {
+	rawDef, err := readFile(fs, defInfo.File, subdirectoryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	rawDef, err = spec.render(defInfo.File, rawDef)
+	if err != nil {
+		return nil, err
+	}
 
-		if defInfo.ORGID != "" {
-			ad.OrgID = defInfo.ORGID
+	ad := objects.DBApiDefinition{}
+	err = json.Unmarshal(rawDef, &ad)
+	if err != nil || (ad.APIDefinition == nil) {
+		def := objects.APIDefinition{}
+		errSecondUnmarshal := json.Unmarshal(rawDef, &def)
+		if errSecondUnmarshal != nil {
+			return nil, err
 		}
+		ad.APIDefinition = &def
+	}
 
-		defs[i] = ad
+	if defInfo.APIID != "" {
+		ad.APIID = defInfo.APIID
 	}
 
-	fmt.Printf("Fetched %v definitions\n", len(defs))
-	return defs, nil
-}
+	if defInfo.DBID != "" {
+		ad.Id = model.ObjectIDHex(defInfo.DBID)
+	}
 
-func fetchAPIDefinitionsFromOAI(fs billy.Filesystem, spec *TykSourceSpec, subdirectoryPath string) ([]objects.DBApiDefinition, error) {
-	oaiNames := spec.Files
-	defs := make([]objects.DBApiDefinition, len(oaiNames))
+	if defInfo.ORGID != "" {
+		ad.OrgID = defInfo.ORGID
+	}
 
-	for i, oaiInfo := range oaiNames {
-		oaiFile, err := fs.Open(getFilepath(oaiInfo.File, subdirectoryPath))
-		if err != nil {
-			return nil, err
-		}
+	return &ad, nil
+}
 
-		rawData, err := ioutil.ReadAll(oaiFile)
-		if err != nil {
-			return nil, err
-		}
+func fetchAPIDefinitionFromOAI(fs billy.Filesystem, spec *TykSourceSpec, oaiInfo APIInfo, subdirectoryPath string) (*objects.DBApiDefinition, error) {
+	rawData, err := readFile(fs, oaiInfo.File, subdirectoryPath)
+	if err != nil {
+		return nil, err
+	}
 
-		oai := tyk_swagger.SwaggerAST{}
-		err = json.Unmarshal(rawData, &oai)
-		if err != nil {
-			return nil, err
-		}
+	rawData, err = spec.render(oaiInfo.File, rawData)
+	if err != nil {
+		return nil, err
+	}
 
-		ad, err := tyk_swagger.CreateDefinitionFromSwagger(&oai,
-			oaiInfo.ORGID,
-			oaiInfo.OAS.VersionName)
-		if err != nil {
-			return nil, err
-		}
+	oai := tyk_swagger.SwaggerAST{}
+	err = json.Unmarshal(rawData, &oai)
+	if err != nil {
+		return nil, err
+	}
 
-		if oaiInfo.APIID != "" {
-			ad.APIID = oaiInfo.APIID
-		}
+	ad, err := tyk_swagger.CreateDefinitionFromSwagger(&oai,
+		oaiInfo.ORGID,
+		oaiInfo.OAS.VersionName)
+	if err != nil {
+		return nil, err
+	}
 
-		if oaiInfo.DBID != "" {
-			ad.Id = model.ObjectIDHex(oaiInfo.DBID)
-		}
+	if oaiInfo.APIID != "" {
+		ad.APIID = oaiInfo.APIID
+	}
 
-		if oaiInfo.OAS.OverrideListenPath != "" {
-			ad.Proxy.ListenPath = oaiInfo.OAS.OverrideListenPath
-		}
+	if oaiInfo.DBID != "" {
+		ad.Id = model.ObjectIDHex(oaiInfo.DBID)
+	}
 
-		if oaiInfo.OAS.OverrideTarget != "" {
-			ad.Proxy.TargetURL = oaiInfo.OAS.OverrideTarget
-		}
+	if oaiInfo.OAS.OverrideListenPath != "" {
+		ad.Proxy.ListenPath = oaiInfo.OAS.OverrideListenPath
+	}
 
-		if oaiInfo.OAS.StripListenPath {
-			ad.Proxy.StripListenPath = true
-		}
+	if oaiInfo.OAS.OverrideTarget != "" {
+		ad.Proxy.TargetURL = oaiInfo.OAS.OverrideTarget
+	}
 
-		defs[i] = *ad
+	if oaiInfo.OAS.StripListenPath {
+		ad.Proxy.StripListenPath = true
 	}
 
-	return defs, nil
+	return ad, nil
 }
 
 func (gg *FSGetter) FetchPolicies(spec *TykSourceSpec) ([]objects.Policy, error) {
@@ -266,13 +305,13 @@ func fetchPolicies(fs billy.Filesystem, spec *TykSourceSpec, subdirectoryPath st
 	defNames := spec.Policies
 	defs := make([]objects.Policy, len(defNames))
 	for i, defInfo := range defNames {
-		defFile, err := fs.Open(getFilepath(defInfo.File, subdirectoryPath))
+		rawDef, err := readFile(fs, defInfo.File, subdirectoryPath)
 		if err != nil {
-			fmt.Println(defInfo.File)
+			output.Println(defInfo.File)
 			return nil, err
 		}
 
-		rawDef, err := ioutil.ReadAll(defFile)
+		rawDef, err = spec.render(defInfo.File, rawDef)
 		if err != nil {
 			return nil, err
 		}
@@ -294,11 +333,33 @@ func fetchPolicies(fs billy.Filesystem, spec *TykSourceSpec, subdirectoryPath st
 		defs[i] = pol
 	}
 
-	fmt.Printf("Fetched %v policies\n", len(defs))
+	output.Printf("Fetched %v policies\n", len(defs))
 
 	return defs, nil
 }
 
+func (gg *FSGetter) FetchCertificates(spec *TykSourceSpec) ([]Certificate, error) {
+	return fetchCertificates(gg.fs, spec, gg.subdirectoryPath)
+}
+
+func (gg *GitGetter) FetchCertificates(spec *TykSourceSpec) ([]Certificate, error) {
+	if gg.r == nil {
+		return nil, errors.New("No repository in memory, fetch repo first")
+	}
+	return fetchCertificates(gg.fs, spec, gg.subdirectoryPath)
+}
+
+func (gg *FSGetter) Validate(spec *TykSourceSpec) ([]ValidationError, error) {
+	return validateDefinitions(gg.fs, spec, gg.subdirectoryPath)
+}
+
+func (gg *GitGetter) Validate(spec *TykSourceSpec) ([]ValidationError, error) {
+	if gg.r == nil {
+		return nil, errors.New("no repository in memory, fetch repo first")
+	}
+	return validateDefinitions(gg.fs, spec, gg.subdirectoryPath)
+}
+
 func getFilepath(file string, pathSegments ...string) string {
 	if len(pathSegments) == 0 {
 		return file
@@ -1,6 +1,8 @@
 package tyk_vcs
 
 import (
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -147,3 +149,107 @@ func TestGetFilepath(t *testing.T) {
 		assert.Equal(t, filepath.Clean("examples/udg/simple/.tyk-json"), fullPath)
 	})
 }
+
+// writeRepo writes files to a temporary directory and returns a getter for it
+func writeRepo(t *testing.T, files map[string]string) *FSGetter {
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g, err := NewFSGetter(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestFSGetter_YAML(t *testing.T) {
+	g := writeRepo(t, map[string]string{
+		".tyk.yaml": `
+type: apidef
+files:
+  - file: apis/one.yaml
+    api_id: one
+policies:
+  - file: policies/one.yml
+`,
+		"apis/one.yaml": `
+api_definition:
+  name: One
+  org_id: org
+  proxy:
+    listen_path: /one/
+    target_url: http://one
+  version_data:
+    not_versioned: true
+    versions:
+      Default:
+        name: Default
+`,
+		"policies/one.yml": `
+id: pol-one
+name: Policy One
+org_id: org
+access_rights:
+  one:
+    api_id: one
+    versions: [Default]
+`,
+	})
+
+	ts, err := g.FetchTykSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, TYPE_APIDEF, ts.Type)
+
+	defs, err := g.FetchAPIDef(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, defs, 1) {
+		assert.Equal(t, "one", defs[0].APIID)
+		assert.Equal(t, "One", defs[0].Name)
+		assert.Equal(t, "http://one", defs[0].Proxy.TargetURL)
+		assert.True(t, defs[0].VersionData.NotVersioned)
+	}
+
+	pols, err := g.FetchPolicies(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assert.Len(t, pols, 1) {
+		assert.Equal(t, "pol-one", pols[0].ID)
+		assert.Equal(t, []string{"Default"}, pols[0].AccessRights["one"].Versions)
+	}
+}
+
+func TestFSGetter_FetchTykSpec_Ambiguous(t *testing.T) {
+	g := writeRepo(t, map[string]string{
+		".tyk.json": `{"type": "apidef"}`,
+		".tyk.yml":  `type: apidef`,
+	})
+
+	_, err := g.FetchTykSpec()
+	assert.Error(t, err)
+}
+
+func TestToJSON(t *testing.T) {
+	data, err := toJSON("oas.yaml", []byte("responses:\n  200:\n    description: OK\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t, `{"responses": {"200": {"description": "OK"}}}`, string(data))
+
+	raw := []byte(`{"a": 1}`)
+	data, err = toJSON("api.json", raw)
+	assert.NoError(t, err)
+	assert.Equal(t, raw, data)
+}
@@ -0,0 +1,126 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+)
+
+// IntegrityIssue is a reference between APIs and policies that won't hold once they are deployed.
+type IntegrityIssue struct {
+	// Object names the API or policy the issue was found in.
+	Object  string
+	Message string
+}
+
+func (i IntegrityIssue) String() string {
+	return fmt.Sprintf("%v %v", i.Object, i.Message)
+}
+
+// CheckIntegrity checks that the APIs and policies to be deployed only refer to each other. It
+// finds access rights to missing APIs or versions, OIDC client policies that are missing, and APIs
+// that share a listen path or slug.
+func CheckIntegrity(apis []objects.DBApiDefinition, pols []objects.Policy) []IntegrityIssue {
+	issues := []IntegrityIssue{}
+
+	apisByID := map[string]*objects.DBApiDefinition{}
+	for i := range apis {
+		if apis[i].APIDefinition != nil {
+			apisByID[apis[i].APIID] = &apis[i]
+		}
+	}
+
+	polIDs := map[string]bool{}
+	for _, pol := range pols {
+		if pol.ID != "" {
+			polIDs[pol.ID] = true
+		}
+		if pol.MID.Valid() {
+			polIDs[pol.MID.Hex()] = true
+		}
+	}
+
+	for _, pol := range pols {
+		object := fmt.Sprintf("policy %v", pol.ID)
+		if pol.ID == "" && pol.MID.Valid() {
+			object = fmt.Sprintf("policy %v", pol.MID.Hex())
+		} else if pol.ID == "" {
+			object = fmt.Sprintf("policy %q", pol.Name)
+		}
+
+		keys := make([]string, 0, len(pol.AccessRights))
+		for key := range pol.AccessRights {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			right := pol.AccessRights[key]
+			apiID := right.APIID
+			if apiID == "" {
+				apiID = key
+			}
+
+			api, ok := apisByID[apiID]
+			if !ok {
+				issues = append(issues, IntegrityIssue{object, fmt.Sprintf("grants access to API %v, which is not being deployed", apiID)})
+				continue
+			}
+
+			// APIs without any versions listed can't be checked
+			if len(api.VersionData.Versions) == 0 {
+				continue
+			}
+			for _, version := range right.Versions {
+				if _, ok := api.VersionData.Versions[version]; !ok {
+					issues = append(issues, IntegrityIssue{object, fmt.Sprintf("grants access to version %v of API %v, which doesn't exist", version, apiID)})
+				}
+			}
+		}
+	}
+
+	listenPaths := map[string]string{}
+	slugs := map[string]string{}
+	for _, api := range apis {
+		if api.APIDefinition == nil {
+			continue
+		}
+		object := fmt.Sprintf("api %v", api.APIID)
+
+		for _, provider := range api.OpenIDOptions.Providers {
+			clients := make([]string, 0, len(provider.ClientIDs))
+			for client := range provider.ClientIDs {
+				clients = append(clients, client)
+			}
+			sort.Strings(clients)
+
+			for _, client := range clients {
+				polID := provider.ClientIDs[client]
+				if !polIDs[polID] {
+					issues = append(issues, IntegrityIssue{object, fmt.Sprintf("uses policy %v for OIDC client %v of %v, which is not being deployed", polID, client, provider.Issuer)})
+				}
+			}
+		}
+
+		if api.Proxy.ListenPath != "" {
+			// Listen paths only clash on the same domain
+			path := api.Domain + api.Proxy.ListenPath
+			if other, ok := listenPaths[path]; ok {
+				issues = append(issues, IntegrityIssue{object, fmt.Sprintf("has the same listen path, %v, as API %v", path, other)})
+			} else {
+				listenPaths[path] = api.APIID
+			}
+		}
+
+		if api.Slug != "" {
+			if other, ok := slugs[api.Slug]; ok {
+				issues = append(issues, IntegrityIssue{object, fmt.Sprintf("has the same slug, %v, as API %v", api.Slug, other)})
+			} else {
+				slugs[api.Slug] = api.APIID
+			}
+		}
+	}
+
+	return issues
+}
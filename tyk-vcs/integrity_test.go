@@ -0,0 +1,54 @@
+package tyk_vcs
+
+import (
+	"testing"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	api := func(id, listenPath, slug string) objects.DBApiDefinition {
+		def := objects.DBApiDefinition{APIDefinition: &objects.APIDefinition{}}
+		def.APIID = id
+		def.Slug = slug
+		def.Proxy.ListenPath = listenPath
+		def.VersionData.Versions = map[string]apidef.VersionInfo{"Default": {Name: "Default"}}
+		return def
+	}
+
+	one := api("one", "/one/", "one")
+	one.OpenIDOptions.Providers = []apidef.OIDProviderConfig{{
+		Issuer:    "https://idp",
+		ClientIDs: map[string]string{"client-a": "gold", "client-b": "missing"},
+	}}
+	other := api("other", "/one/", "one")
+	otherDomain := api("other-domain", "/one/", "")
+	otherDomain.Domain = "example.com"
+
+	pols := []objects.Policy{{
+		ID: "gold",
+		AccessRights: map[string]objects.AccessDefinition{
+			"one":  {APIID: "one", Versions: []string{"Default", "v2"}},
+			"gone": {APIID: "gone", Versions: []string{"Default"}},
+		},
+	}}
+
+	got := []string{}
+	for _, issue := range CheckIntegrity([]objects.DBApiDefinition{one, other, otherDomain}, pols) {
+		got = append(got, issue.String())
+	}
+
+	assert.Equal(t, []string{
+		"policy gold grants access to API gone, which is not being deployed",
+		"policy gold grants access to version v2 of API one, which doesn't exist",
+		"api one uses policy missing for OIDC client client-b of https://idp, which is not being deployed",
+		"api other has the same listen path, /one/, as API one",
+		"api other has the same slug, one, as API one",
+	}, got)
+
+	one.OpenIDOptions.Providers[0].ClientIDs = map[string]string{"client-a": "gold"}
+	pols[0].AccessRights = map[string]objects.AccessDefinition{"one": {APIID: "one", Versions: []string{"Default"}}}
+	assert.Empty(t, CheckIntegrity([]objects.DBApiDefinition{one, otherDomain}, pols))
+}
@@ -0,0 +1,130 @@
+package tyk_vcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+)
+
+// overlay returns the merge patches to apply to each file for the spec's environment, keyed by the
+// cleaned file path. It fails if the overlay patches a file that the spec doesn't list, as that is
+// almost certainly a typo that would otherwise silently deploy the wrong configuration.
+func (ts *TykSourceSpec) overlay() (map[string]json.RawMessage, error) {
+	if ts.Environment == "" {
+		return nil, nil
+	}
+
+	patches, ok := ts.Overlays[ts.Environment]
+	if !ok {
+		return nil, nil
+	}
+
+	listed := map[string]bool{}
+	for _, f := range ts.Files {
+		listed[path.Clean(f.File)] = true
+	}
+	for _, p := range ts.Policies {
+		listed[path.Clean(p.File)] = true
+	}
+
+	files := make([]string, 0, len(patches))
+	for file := range patches {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	overlay := make(map[string]json.RawMessage, len(patches))
+	for _, file := range files {
+		clean := path.Clean(file)
+		if !listed[clean] {
+			return nil, fmt.Errorf("overlay %v patches %v, which is not listed in the spec", ts.Environment, file)
+		}
+		overlay[clean] = patches[file]
+	}
+
+	return overlay, nil
+}
+
+// applyOverlay applies the spec environment's merge patch for a file, if it has one, to the file's
+// JSON contents.
+func (ts *TykSourceSpec) applyOverlay(file string, raw []byte) ([]byte, error) {
+	overlay, err := ts.overlay()
+	if err != nil {
+		return nil, err
+	}
+
+	patch, ok := overlay[path.Clean(file)]
+	if !ok {
+		return raw, nil
+	}
+
+	patched, err := mergePatch(raw, patch)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't apply overlay %v to %v: %v", ts.Environment, file, err)
+	}
+
+	return patched, nil
+}
+
+// render prepares a file's JSON contents for use, applying the environment's overlay and then
+// resolving variables, so that overlays may also use them.
+func (ts *TykSourceSpec) render(file string, raw []byte) ([]byte, error) {
+	raw, err := ts.applyOverlay(file, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.interpolate(file, raw)
+}
+
+// mergePatch applies a JSON merge patch, as described in RFC 7386, to a JSON document.
+func mergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decodeJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decodeJSON(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(mergeValue(target, p))
+}
+
+// decodeJSON decodes a JSON document, keeping numbers as written so that large integers such as
+// quotas aren't rounded.
+func decodeJSON(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func mergeValue(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		// Anything other than an object replaces the target entirely
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+
+	for k, v := range patchObj {
+		if v == nil {
+			delete(targetObj, k)
+			continue
+		}
+		targetObj[k] = mergeValue(targetObj[k], v)
+	}
+
+	return targetObj
+}
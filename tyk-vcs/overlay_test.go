@@ -0,0 +1,104 @@
+package tyk_vcs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name, doc, patch, want string
+	}{
+		{"replaces values", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"adds values", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"removes null values", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"merges nested objects", `{"a":{"b":"c","d":"e"}}`, `{"a":{"b":"f"}}`, `{"a":{"b":"f","d":"e"}}`},
+		{"replaces arrays", `{"a":["b","c"]}`, `{"a":["d"]}`, `{"a":["d"]}`},
+		{"keeps large numbers", `{"quota_max":9007199254740993}`, `{"rate":10}`, `{"quota_max":9007199254740993,"rate":10}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergePatch([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestFSGetter_Overlays(t *testing.T) {
+	files := map[string]string{
+		".tyk.yml": `
+type: apidef
+files:
+  - file: apis/one.json
+policies:
+  - file: policies/one.json
+overlays:
+  prod:
+    apis/one.json:
+      api_definition:
+        proxy:
+          target_url: http://prod
+        domain: api.example.com
+    ./policies/one.json:
+      rate: 1000
+`,
+		"apis/one.json":     `{"api_definition": {"api_id": "one", "name": "One", "proxy": {"listen_path": "/one/", "target_url": "http://dev"}}}`,
+		"policies/one.json": `{"id": "one", "name": "One", "org_id": "org", "rate": 10}`,
+	}
+
+	fetch := func(t *testing.T, env string) (string, string, float64) {
+		g := writeRepo(t, files)
+		ts, err := g.FetchTykSpec()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts.Environment = env
+
+		defs, err := g.FetchAPIDef(ts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pols, err := g.FetchPolicies(ts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return defs[0].Proxy.TargetURL, defs[0].Domain, pols[0].Rate
+	}
+
+	t.Run("no environment", func(t *testing.T) {
+		target, domain, rate := fetch(t, "")
+		assert.Equal(t, "http://dev", target)
+		assert.Equal(t, "", domain)
+		assert.Equal(t, float64(10), rate)
+	})
+
+	t.Run("environment without an overlay", func(t *testing.T) {
+		target, _, _ := fetch(t, "staging")
+		assert.Equal(t, "http://dev", target)
+	})
+
+	t.Run("environment with an overlay", func(t *testing.T) {
+		target, domain, rate := fetch(t, "prod")
+		assert.Equal(t, "http://prod", target)
+		assert.Equal(t, "api.example.com", domain)
+		assert.Equal(t, float64(1000), rate)
+	})
+
+	t.Run("overlay for a file not in the spec", func(t *testing.T) {
+		g := writeRepo(t, files)
+		ts, err := g.FetchTykSpec()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts.Environment = "prod"
+		ts.Overlays["prod"]["apis/typo.json"] = []byte(`{}`)
+
+		_, err = g.FetchAPIDef(ts)
+		assert.Error(t, err)
+	})
+}
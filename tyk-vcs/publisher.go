@@ -12,5 +12,22 @@ type Publisher interface {
 	CreatePolicies(pols *[]objects.Policy) error
 	UpdatePolicies(pols *[]objects.Policy) error
 	SyncPolicies(pols []objects.Policy) error
+	// FetchAPIs returns the APIs currently on the target.
+	FetchAPIs() ([]objects.DBApiDefinition, error)
+	// FetchPolicies returns the policies currently on the target.
+	FetchPolicies() ([]objects.Policy, error)
+	// FetchCertificates returns the metadata of the certificates currently on the target.
+	FetchCertificates() ([]objects.CertificateMeta, error)
+	// CreateCertificate uploads a PEM certificate to the target, returning its ID.
+	CreateCertificate(cert []byte) (string, error)
+	// Plan works out the changes a sync of the given APIs and policies would make, without
+	// making any changes to the target. Policies are left alone when pols is empty.
+	Plan(apiDefs []objects.DBApiDefinition, pols []objects.Policy) (*objects.SyncPlan, error)
+	// PlanUsers adds the changes a sync of the given users and user groups would make to a plan.
+	// Users are left alone when users is empty, and user groups when groups is empty.
+	PlanUsers(plan *objects.SyncPlan, users []objects.User, groups []objects.UserGroup) error
+	// Apply makes the changes recorded in a plan, as long as the target has not changed since the
+	// plan was created.
+	Apply(plan *objects.SyncPlan) error
 	Reload() error
 }
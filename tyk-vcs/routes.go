@@ -0,0 +1,191 @@
+package tyk_vcs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+)
+
+// Route is an entry in the routing table of a target, the domain and listen path an API is served
+// on.
+type Route struct {
+	APIID      string
+	Domain     string
+	ListenPath string
+	// Remote is set for APIs that are on the target but not in the repo.
+	Remote bool
+}
+
+func (r Route) String() string {
+	source := "repo"
+	if r.Remote {
+		source = "target"
+	}
+	return fmt.Sprintf("api %v (%v) at %v%v", r.APIID, source, r.Domain, r.ListenPath)
+}
+
+// key returns the route's domain and listen path in the form they are matched in, so that
+// /foo and /foo/ compare equal.
+func (r Route) key() (string, string) {
+	listenPath := "/" + strings.Trim(r.ListenPath, "/")
+	return strings.ToLower(r.Domain), strings.TrimSuffix(listenPath, "/")
+}
+
+// RouteConflict is a pair of APIs whose routes collide. When Shadowed is set, Route's listen path
+// is a prefix of Other's rather than the same, so Route may catch requests meant for Other.
+type RouteConflict struct {
+	Route    Route
+	Other    Route
+	Shadowed bool
+}
+
+func (c RouteConflict) String() string {
+	if c.Shadowed {
+		return fmt.Sprintf("%v shadows %v", c.Route, c.Other)
+	}
+	return fmt.Sprintf("%v has the same listen path as %v", c.Route, c.Other)
+}
+
+// PlanRoutingTable returns the routing table of a target once a sync plan has been applied: the
+// routes of the APIs the plan creates and updates, along with those of the remote APIs it neither
+// updates nor deletes. Remote APIs are matched to the repo's by the plan, so an API is never taken
+// for a collision with its own deployed copy, however the sync matched them.
+func PlanRoutingTable(plan *objects.SyncPlan, remote []objects.DBApiDefinition) []Route {
+	apis := []objects.DBApiDefinition{}
+	replaced := []objects.DBApiDefinition{}
+	for _, change := range plan.APIs {
+		switch change.Action {
+		case objects.ActionCreate:
+			apis = append(apis, *change.Local)
+		case objects.ActionUpdate:
+			apis = append(apis, *change.Local)
+			replaced = append(replaced, *change.Remote)
+		case objects.ActionDelete:
+			replaced = append(replaced, *change.Remote)
+		}
+	}
+
+	return routingTable(apis, remote, replaced)
+}
+
+// PublishRoutingTable returns the routing table of a target once the repo's APIs have been
+// published or updated on it. Remote APIs are matched to the repo's in the same way as publish and
+// update do, by API ID, DB ID, slug, and then listen path and domain.
+func PublishRoutingTable(apis []objects.DBApiDefinition, remote []objects.DBApiDefinition) []Route {
+	replaced := []objects.DBApiDefinition{}
+	for _, api := range apis {
+		if api.APIDefinition == nil {
+			continue
+		}
+		if match := matchRemoteAPI(api, remote); match != nil {
+			replaced = append(replaced, *match)
+		}
+	}
+
+	return routingTable(apis, remote, replaced)
+}
+
+// matchRemoteAPI returns the remote API that publishing or updating api would replace, if any.
+// Empty identifiers never match.
+func matchRemoteAPI(api objects.DBApiDefinition, remote []objects.DBApiDefinition) *objects.DBApiDefinition {
+	matches := []func(r objects.DBApiDefinition) bool{
+		func(r objects.DBApiDefinition) bool { return api.APIID != "" && r.APIID == api.APIID },
+		func(r objects.DBApiDefinition) bool { return api.Id.Hex() != "" && r.Id.Hex() == api.Id.Hex() },
+		func(r objects.DBApiDefinition) bool { return api.Slug != "" && r.Slug == api.Slug },
+		func(r objects.DBApiDefinition) bool {
+			return r.Proxy.ListenPath == api.Proxy.ListenPath && r.Domain == api.Domain
+		},
+	}
+
+	for _, match := range matches {
+		for i := range remote {
+			if remote[i].APIDefinition != nil && match(remote[i]) {
+				return &remote[i]
+			}
+		}
+	}
+	return nil
+}
+
+// routingTable combines the routes of the APIs in the repo with those of the remote APIs that
+// will still be served alongside them, which are all but the replaced ones.
+func routingTable(apis, remote, replaced []objects.DBApiDefinition) []Route {
+	routes := []Route{}
+	for _, api := range apis {
+		if api.APIDefinition == nil {
+			continue
+		}
+		routes = append(routes, Route{APIID: api.APIID, Domain: api.Domain, ListenPath: api.Proxy.ListenPath})
+	}
+
+	gone := map[string]bool{}
+	for _, api := range replaced {
+		if key := remoteKey(api); key != "" {
+			gone[key] = true
+		}
+	}
+
+	for _, api := range remote {
+		if api.APIDefinition == nil || gone[remoteKey(api)] {
+			continue
+		}
+		routes = append(routes, Route{APIID: api.APIID, Domain: api.Domain, ListenPath: api.Proxy.ListenPath, Remote: true})
+	}
+
+	return routes
+}
+
+// remoteKey identifies a remote API by its DB ID, or by its API ID on gateways, which have no DB
+// IDs. It is empty when the API has neither.
+func remoteKey(api objects.DBApiDefinition) string {
+	if api.APIDefinition == nil {
+		return ""
+	}
+	if id := api.Id.Hex(); id != "" {
+		return "id:" + id
+	}
+	if api.APIID != "" {
+		return "api:" + api.APIID
+	}
+	return ""
+}
+
+// CheckRoutes finds the APIs in a routing table that share a listen path on the same domain, or
+// whose listen path is a prefix of another's. Collisions between remote APIs are left out, as
+// deploying the repo doesn't cause them.
+func CheckRoutes(routes []Route) []RouteConflict {
+	sorted := make([]Route, len(routes))
+	copy(sorted, routes)
+	// Shorter listen paths first, so that a shadowing route always comes before the one it shadows
+	sort.SliceStable(sorted, func(i, j int) bool {
+		_, pi := sorted[i].key()
+		_, pj := sorted[j].key()
+		return len(pi) < len(pj)
+	})
+
+	conflicts := []RouteConflict{}
+	for i, route := range sorted {
+		domain, listenPath := route.key()
+		for _, other := range sorted[i+1:] {
+			if route.Remote && other.Remote {
+				continue
+			}
+
+			otherDomain, otherPath := other.key()
+			if domain != otherDomain {
+				continue
+			}
+
+			switch {
+			case listenPath == otherPath:
+				conflicts = append(conflicts, RouteConflict{Route: route, Other: other})
+			case strings.HasPrefix(otherPath, listenPath+"/"):
+				conflicts = append(conflicts, RouteConflict{Route: route, Other: other, Shadowed: true})
+			}
+		}
+	}
+
+	return conflicts
+}
@@ -0,0 +1,104 @@
+package tyk_vcs
+
+import (
+	"testing"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/TykTechnologies/storage/persistent/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func routeTestAPI(id, domain, listenPath string) objects.DBApiDefinition {
+	def := objects.DBApiDefinition{APIDefinition: &objects.APIDefinition{}}
+	def.APIID = id
+	def.Domain = domain
+	def.Proxy.ListenPath = listenPath
+	return def
+}
+
+func conflictStrings(routes []Route) []string {
+	got := []string{}
+	for _, conflict := range CheckRoutes(routes) {
+		got = append(got, conflict.String())
+	}
+	return got
+}
+
+func TestCheckRoutes(t *testing.T) {
+	api := routeTestAPI
+
+	repo := []objects.DBApiDefinition{
+		api("orders", "", "/orders/"),
+		api("shop", "", "/shop"),
+		api("hosted", "example.com", "/orders/"),
+	}
+	remote := []objects.DBApiDefinition{
+		// Replaced by the repo's copy, so it doesn't collide with it
+		api("orders", "", "/orders/"),
+		api("legacy-orders", "", "/orders"),
+		api("cart", "", "/shop/cart/"),
+		api("shopping", "", "/shopping/"),
+		// Collisions between remote APIs are not caused by the repo
+		api("other", "", "/other/"),
+		api("other-copy", "", "/other/"),
+	}
+
+	assert.Equal(t, []string{
+		"api shop (repo) at /shop shadows api cart (target) at /shop/cart/",
+		"api orders (repo) at /orders/ has the same listen path as api legacy-orders (target) at /orders",
+	}, conflictStrings(PublishRoutingTable(repo, remote)))
+}
+
+func TestPublishRoutingTable_Matching(t *testing.T) {
+	// A repo API without an API ID is matched to its deployed copy by slug or listen path, as
+	// update does, so it doesn't collide with it
+	bySlug := routeTestAPI("", "", "/orders/")
+	bySlug.Slug = "orders"
+	byPath := routeTestAPI("", "", "/billing/")
+
+	deployedOrders := routeTestAPI("a1", "", "/orders/")
+	deployedOrders.Id = model.NewObjectID()
+	deployedOrders.Slug = "orders"
+	deployedBilling := routeTestAPI("a2", "", "/billing/")
+	deployedBilling.Id = model.NewObjectID()
+
+	assert.Empty(t, conflictStrings(PublishRoutingTable(
+		[]objects.DBApiDefinition{bySlug, byPath},
+		[]objects.DBApiDefinition{deployedOrders, deployedBilling},
+	)))
+
+	// A repo API without an API ID doesn't hide remote APIs without one
+	repo := routeTestAPI("", "", "/shop/")
+	repo.Slug = "shop"
+	unmanaged := routeTestAPI("", "", "/shop/cart/")
+	unmanaged.Id = model.NewObjectID()
+	assert.Equal(t, []string{
+		"api  (repo) at /shop/ shadows api  (target) at /shop/cart/",
+	}, conflictStrings(PublishRoutingTable([]objects.DBApiDefinition{repo}, []objects.DBApiDefinition{unmanaged})))
+}
+
+func TestPlanRoutingTable(t *testing.T) {
+	// Cloud syncs match APIs by slug, so the repo copy may have a different API ID
+	remote := routeTestAPI("cloud-id", "", "/orders/")
+	remote.Id = model.NewObjectID()
+	remote.Slug = "orders"
+	local := routeTestAPI("", "", "/orders/")
+	local.Slug = "orders"
+
+	gone := routeTestAPI("old", "", "/old/")
+	gone.Id = model.NewObjectID()
+	replacement := routeTestAPI("new", "", "/old/")
+
+	unmanaged := routeTestAPI("", "", "/orders")
+	unmanaged.Id = model.NewObjectID()
+
+	plan := &objects.SyncPlan{APIs: []objects.APIChange{
+		{Action: objects.ActionUpdate, Local: &local, Remote: &remote},
+		{Action: objects.ActionDelete, Remote: &gone},
+		{Action: objects.ActionCreate, Local: &replacement},
+	}}
+
+	assert.Equal(t, []string{
+		"api  (repo) at /orders/ has the same listen path as api  (target) at /orders",
+	}, conflictStrings(PlanRoutingTable(plan, []objects.DBApiDefinition{remote, gone, unmanaged})))
+}
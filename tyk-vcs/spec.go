@@ -1,5 +1,7 @@
 package tyk_vcs
 
+import "encoding/json"
+
 type PublishAction string
 type SpecType string
 
@@ -13,10 +15,12 @@ const (
 )
 
 type APIInfo struct {
-	File  string `json:"file,omitempty"`
-	APIID string `json:"api_id,omitempty"`
-	DBID  string `json:"db_id,omitempty"`
-	ORGID string `json:"org_id,omitempty"`
+	File string `json:"file,omitempty"`
+	// Type overrides the spec's type for this file.
+	Type  SpecType `json:"type,omitempty"`
+	APIID string   `json:"api_id,omitempty"`
+	DBID  string   `json:"db_id,omitempty"`
+	ORGID string   `json:"org_id,omitempty"`
 	OAS   struct {
 		OverrideTarget     string `json:"override_target,omitempty"`
 		OverrideListenPath string `json:"override_listen_path,omitempty"`
@@ -30,8 +34,59 @@ type PolicyInfo struct {
 	ID   string `json:"id,omitempty"`
 }
 
+// CertificateInfo lists a PEM certificate, or public key, that the APIs in the spec refer to.
+type CertificateInfo struct {
+	File string `json:"file,omitempty"`
+}
+
+// UserInfo lists a file holding a dashboard user, which is matched on the dashboard by its email
+// address. User files must not hold a password or access key.
+type UserInfo struct {
+	File string `json:"file,omitempty"`
+}
+
+// UserGroupInfo lists a file holding a dashboard user group, which is matched on the dashboard by
+// its name.
+type UserGroupInfo struct {
+	File string `json:"file,omitempty"`
+}
+
+// TykSourceSpec describes the APIs, policies, certificates, users and user groups in a repo. Files
+// in Files, Policies and Certificates may be glob patterns, where ** matches any number of
+// directories.
 type TykSourceSpec struct {
 	Type     SpecType     `json:"type,omitempty"`
 	Files    []APIInfo    `json:"files,omitempty"`
 	Policies []PolicyInfo `json:"policies,omitempty"`
+	// Certificates are uploaded to the target when it doesn't have them, and the APIs' references to
+	// them are rewritten to the target's certificate IDs.
+	Certificates []CertificateInfo `json:"certificates,omitempty"`
+	// Users and UserGroups are synced to dashboards. The dashboard's users are left alone when the
+	// spec lists none, and so are its user groups.
+	Users      []UserInfo      `json:"users,omitempty"`
+	UserGroups []UserGroupInfo `json:"user_groups,omitempty"`
+	// Discover lists directories that are searched for API definitions, OAS documents and policies,
+	// which are told apart by their contents.
+	Discover []string `json:"discover,omitempty"`
+	// Overlays holds JSON merge patches (RFC 7386) for the listed files, keyed by environment name
+	// and then by file.
+	Overlays map[string]map[string]json.RawMessage `json:"overlays,omitempty"`
+
+	// Vars are the default values for ${NAME} placeholders in the listed files.
+	Vars map[string]string `json:"vars,omitempty"`
+
+	// Environment selects which overlay is applied when definitions are fetched. It is set by the
+	// caller rather than read from the spec.
+	Environment string `json:"-"`
+	// EnvironmentVars are the environment's values for placeholders, overriding Vars. They are set by
+	// the caller rather than read from the spec.
+	EnvironmentVars map[string]string `json:"-"`
+}
+
+// fileType returns the type of an API file, which defaults to the spec's type.
+func (ts *TykSourceSpec) fileType(info APIInfo) SpecType {
+	if info.Type != "" {
+		return info.Type
+	}
+	return ts.Type
 }
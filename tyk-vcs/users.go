@@ -0,0 +1,97 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/AaronFeledy/tyk-ops/pkg/clients/objects"
+	"github.com/AaronFeledy/tyk-ops/pkg/output"
+	"gopkg.in/src-d/go-billy.v4"
+)
+
+// SecretInRepoError is returned for user files that hold a password or access key, which must not be
+// kept in a repo.
+var SecretInRepoError = errors.New("users in the repo must not include a password or access key")
+
+func (gg *FSGetter) FetchUsers(spec *TykSourceSpec) ([]objects.User, error) {
+	return fetchUsers(gg.fs, spec, gg.subdirectoryPath)
+}
+
+func (gg *GitGetter) FetchUsers(spec *TykSourceSpec) ([]objects.User, error) {
+	if gg.r == nil {
+		return nil, errors.New("No repository in memory, fetch repo first")
+	}
+	return fetchUsers(gg.fs, spec, gg.subdirectoryPath)
+}
+
+func (gg *FSGetter) FetchUserGroups(spec *TykSourceSpec) ([]objects.UserGroup, error) {
+	return fetchUserGroups(gg.fs, spec, gg.subdirectoryPath)
+}
+
+func (gg *GitGetter) FetchUserGroups(spec *TykSourceSpec) ([]objects.UserGroup, error) {
+	if gg.r == nil {
+		return nil, errors.New("No repository in memory, fetch repo first")
+	}
+	return fetchUserGroups(gg.fs, spec, gg.subdirectoryPath)
+}
+
+func fetchUsers(fs billy.Filesystem, spec *TykSourceSpec, subdirectoryPath string) ([]objects.User, error) {
+	users := make([]objects.User, len(spec.Users))
+	for i, info := range spec.Users {
+		if err := readObject(fs, spec, info.File, subdirectoryPath, &users[i]); err != nil {
+			return nil, err
+		}
+
+		if users[i].EmailAddress == "" {
+			return nil, fmt.Errorf("%v: users must include an email address", info.File)
+		}
+		if users[i].Password != "" || users[i].AccessKey != "" {
+			return nil, fmt.Errorf("%v: %v", info.File, SecretInRepoError)
+		}
+	}
+
+	if len(users) > 0 {
+		output.Printf("Fetched %v users\n", len(users))
+	}
+
+	return users, nil
+}
+
+func fetchUserGroups(fs billy.Filesystem, spec *TykSourceSpec, subdirectoryPath string) ([]objects.UserGroup, error) {
+	groups := make([]objects.UserGroup, len(spec.UserGroups))
+	for i, info := range spec.UserGroups {
+		if err := readObject(fs, spec, info.File, subdirectoryPath, &groups[i]); err != nil {
+			return nil, err
+		}
+
+		if groups[i].Name == "" {
+			return nil, fmt.Errorf("%v: user groups must include a name", info.File)
+		}
+	}
+
+	if len(groups) > 0 {
+		output.Printf("Fetched %v user groups\n", len(groups))
+	}
+
+	return groups, nil
+}
+
+// readObject reads a JSON or YAML file from the repo into v, applying the spec's overlays and
+// variables.
+func readObject(fs billy.Filesystem, spec *TykSourceSpec, file, subdirectoryPath string, v interface{}) error {
+	raw, err := readFile(fs, file, subdirectoryPath)
+	if err != nil {
+		return err
+	}
+
+	raw, err = spec.render(file, raw)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("%v: %v", file, err)
+	}
+	return nil
+}
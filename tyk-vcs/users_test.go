@@ -0,0 +1,48 @@
+package tyk_vcs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/util"
+)
+
+func TestFSGetter_FetchUsers(t *testing.T) {
+	fs := memfs.New()
+	files := map[string]string{
+		"repo/users/alice.json":  `{"email_address": "alice@example.com", "group": "devs", "active": true}`,
+		"repo/users/secret.json": `{"email_address": "bob@example.com", "password": "hunter2"}`,
+		"repo/users/devs.yaml":   "name: devs\nuser_permissions:\n  apis: ${ACCESS}\n",
+	}
+	for name, content := range files {
+		if err := util.WriteFile(fs, name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gg := &FSGetter{fs: fs, subdirectoryPath: "repo"}
+	spec := &TykSourceSpec{
+		Users:      []UserInfo{{File: "users/alice.json"}},
+		UserGroups: []UserGroupInfo{{File: "users/devs.yaml"}},
+		Vars:       map[string]string{"ACCESS": "write"},
+	}
+
+	users, err := gg.FetchUsers(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, users, 1)
+	assert.Equal(t, "devs", users[0].Group)
+
+	groups, err := gg.FetchUserGroups(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "write", groups[0].UserPermissions["apis"])
+
+	// Secrets must not be kept in the repo
+	spec.Users = []UserInfo{{File: "users/secret.json"}}
+	_, err = gg.FetchUsers(spec)
+	assert.Error(t, err)
+}
@@ -0,0 +1,440 @@
+package tyk_vcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TykTechnologies/tyk/apidef"
+	"github.com/TykTechnologies/tyk/apidef/oas"
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError is a problem found in a definition file. Line is 0 when the problem can't be
+// tied to a line, and Field is the dotted path of the value at fault, if any.
+type ValidationError struct {
+	File    string
+	Line    int
+	Field   string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	location := e.File
+	if e.Line > 0 {
+		location = fmt.Sprintf("%v:%v", e.File, e.Line)
+	}
+	if e.Field != "" {
+		return fmt.Sprintf("%v: %v: %v", location, e.Field, e.Message)
+	}
+	return fmt.Sprintf("%v: %v", location, e.Message)
+}
+
+// sourceFile is a definition file being validated, along with the position of each of its values.
+type sourceFile struct {
+	name  string
+	lines map[string]int
+	errs  []ValidationError
+}
+
+// report records a problem with the value at a dotted path, using the line of its closest ancestor
+// when the value itself isn't in the file, for instance because it is missing.
+func (f *sourceFile) report(field, format string, args ...interface{}) {
+	line := 0
+	for p := field; ; p = parentPath(p) {
+		if l, ok := f.lines[p]; ok {
+			line = l
+			break
+		}
+		if p == "" {
+			break
+		}
+	}
+
+	f.errs = append(f.errs, ValidationError{
+		File:    f.name,
+		Line:    line,
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// sorted returns the problems found in the file in the order they appear in it.
+func (f *sourceFile) sorted() []ValidationError {
+	sort.SliceStable(f.errs, func(i, j int) bool {
+		return f.errs[i].Line < f.errs[j].Line
+	})
+	return f.errs
+}
+
+// validateDefinitions checks every API definition, OAS document and policy listed in a spec, after
+// applying the spec's overlay and variables. Problems with the files are returned as validation
+// errors, while the error is reserved for files that can't be read or rendered.
+func validateDefinitions(fs billy.Filesystem, spec *TykSourceSpec, subdirectoryPath string) ([]ValidationError, error) {
+	errs := []ValidationError{}
+
+	for _, info := range spec.Files {
+		f, doc, err := loadForValidation(fs, spec, info.File, subdirectoryPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if doc != nil {
+			switch spec.fileType(info) {
+			case TYPE_APIDEF:
+				validateAPIDefinition(f, doc)
+			case TYPE_OAI:
+				validateSwagger(f, doc)
+			default:
+				return nil, fmt.Errorf("Type must be '%v or '%v'", TYPE_APIDEF, TYPE_OAI)
+			}
+		}
+
+		errs = append(errs, f.sorted()...)
+	}
+
+	for _, info := range spec.Policies {
+		f, doc, err := loadForValidation(fs, spec, info.File, subdirectoryPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if doc != nil {
+			validatePolicy(f, doc)
+		}
+
+		errs = append(errs, f.sorted()...)
+	}
+
+	return errs, nil
+}
+
+// loadForValidation reads and renders a file. A nil document is returned when the file isn't
+// valid JSON or YAML, in which case the syntax error is recorded against the file.
+func loadForValidation(fs billy.Filesystem, spec *TykSourceSpec, file string, subdirectoryPath string) (*sourceFile, map[string]interface{}, error) {
+	f := &sourceFile{name: file, lines: map[string]int{}}
+
+	src, err := readSource(fs, file, subdirectoryPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if isYAML(file) {
+		err = yamlLines(src, f.lines)
+	} else {
+		err = jsonLines(src, f.lines)
+	}
+	if err != nil {
+		f.errs = append(f.errs, syntaxError(file, src, err))
+		return f, nil, nil
+	}
+
+	raw, err := toJSON(file, src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw, err = spec.render(file, raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		f.report("", "expected an object")
+		return f, nil, nil
+	}
+
+	return f, doc, nil
+}
+
+func syntaxError(file string, src []byte, err error) ValidationError {
+	e := ValidationError{File: file, Message: err.Error()}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		e.Line = lineAt(src, syntaxErr.Offset)
+	}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		e.Message = strings.Join(typeErr.Errors, ", ")
+	}
+
+	return e
+}
+
+// validateAPIDefinition checks an API definition, either bare or wrapped as the dashboard exports
+// it, against the Tyk API definition schema and rules, and any Tyk OAS document alongside it
+// against the Tyk OAS schema.
+func validateAPIDefinition(f *sourceFile, doc map[string]interface{}) {
+	def, prefix := doc, ""
+	if wrapped, ok := doc["api_definition"]; ok {
+		def, ok = wrapped.(map[string]interface{})
+		if !ok {
+			f.report("api_definition", "expected an object")
+			return
+		}
+		prefix = "api_definition"
+	}
+
+	if validateSchema(f, prefix, []byte(apidef.Schema), def) {
+		raw, _ := json.Marshal(def)
+		ad := apidef.APIDefinition{}
+		if err := json.Unmarshal(raw, &ad); err != nil {
+			f.report(prefix, "%v", err)
+		} else {
+			result := apidef.Validate(&ad, apidef.DefaultValidationRuleSet)
+			for _, err := range result.Errors {
+				f.report(prefix, "%v", err)
+			}
+		}
+	}
+
+	if doc["oas"] != nil {
+		validateOAS(f, "oas", doc["oas"])
+	}
+}
+
+// validateOAS checks a Tyk OAS document against the schema for its OpenAPI version.
+func validateOAS(f *sourceFile, prefix string, doc interface{}) {
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		f.report(prefix, "expected an object")
+		return
+	}
+
+	version, _ := obj["openapi"].(string)
+	if version == "" {
+		f.report(joinPath(prefix, "openapi"), "openapi is required")
+		return
+	}
+
+	schema, err := oas.GetOASSchema(version)
+	if err != nil {
+		f.report(joinPath(prefix, "openapi"), "%v", err)
+		return
+	}
+
+	validateSchema(f, prefix, schema, obj)
+}
+
+// validateSwagger checks the parts of a Swagger 2.0 document that are needed to import it. An
+// OpenAPI 3 document is checked against the Tyk OAS schema instead.
+func validateSwagger(f *sourceFile, doc map[string]interface{}) {
+	if _, ok := doc["openapi"]; ok {
+		validateOAS(f, "", doc)
+		return
+	}
+
+	if version, _ := doc["swagger"].(string); version != "2.0" {
+		f.report("swagger", "swagger must be \"2.0\"")
+	}
+
+	info, ok := doc["info"].(map[string]interface{})
+	if !ok {
+		f.report("info", "info is required")
+	} else if title, _ := info["title"].(string); title == "" {
+		f.report("info.title", "info.title is required, it is used as the API name")
+	}
+
+	if _, ok := doc["paths"].(map[string]interface{}); !ok {
+		f.report("paths", "paths is required")
+	}
+}
+
+// validatePolicy checks the structure of a policy, including that each access right refers to the
+// API it is keyed by.
+func validatePolicy(f *sourceFile, doc map[string]interface{}) {
+	if org, _ := doc["org_id"].(string); org == "" {
+		f.report("org_id", "org_id is required")
+	}
+	if name, _ := doc["name"].(string); name == "" {
+		f.report("name", "name is required")
+	}
+
+	if n, ok := doc["quota_max"].(float64); ok && n < -1 {
+		f.report("quota_max", "quota_max must be -1 for unlimited, or at least 0")
+	}
+
+	rights, ok := doc["access_rights"].(map[string]interface{})
+	if !ok {
+		if doc["access_rights"] != nil {
+			f.report("access_rights", "expected an object")
+		}
+		return
+	}
+
+	keys := make([]string, 0, len(rights))
+	for key := range rights {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		val := rights[key]
+		field := joinPath("access_rights", key)
+		right, ok := val.(map[string]interface{})
+		if !ok {
+			f.report(field, "expected an object")
+			continue
+		}
+
+		if id, _ := right["api_id"].(string); id == "" {
+			f.report(joinPath(field, "api_id"), "api_id is required")
+		} else if id != key {
+			f.report(joinPath(field, "api_id"), "api_id %v doesn't match the key it is listed under", id)
+		}
+
+		if versions, _ := right["versions"].([]interface{}); len(versions) == 0 {
+			f.report(joinPath(field, "versions"), "at least one version is required")
+		}
+	}
+}
+
+// validateSchema validates a document against a JSON schema, reporting each violation. It returns
+// true when the document is valid.
+func validateSchema(f *sourceFile, prefix string, schema []byte, doc interface{}) bool {
+	result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schema), gojsonschema.NewGoLoader(doc))
+	if err != nil {
+		f.report(prefix, "%v", err)
+		return false
+	}
+
+	for _, e := range result.Errors() {
+		field := e.Field()
+		if field == gojsonschema.STRING_ROOT_SCHEMA_PROPERTY {
+			field = ""
+		}
+		// Point at the offending property itself rather than the object holding it
+		if e.Type() == "additional_property_not_allowed" {
+			if property, ok := e.Details()["property"].(string); ok {
+				field = joinPath(field, property)
+			}
+		}
+		f.report(joinPath(prefix, field), "%v", e.Description())
+	}
+
+	return result.Valid()
+}
+
+// jsonLines records the line each value of a JSON document starts on, keyed by its dotted path.
+// Values in objects are recorded at the line of their key.
+func jsonLines(src []byte, lines map[string]int) error {
+	dec := json.NewDecoder(bytes.NewReader(src))
+
+	var walk func(path string) error
+	walk = func(path string) error {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if _, ok := lines[path]; !ok {
+			lines[path] = lineAt(src, dec.InputOffset())
+		}
+
+		switch tok {
+		case json.Delim('{'):
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				child := joinPath(path, fmt.Sprint(key))
+				lines[child] = lineAt(src, dec.InputOffset())
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				if err := walk(joinPath(path, strconv.Itoa(i))); err != nil {
+					return err
+				}
+			}
+		default:
+			return nil
+		}
+
+		// Consume the closing delimiter
+		_, err = dec.Token()
+		return err
+	}
+
+	if err := walk(""); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
+
+// yamlLines records the line each value of a YAML document starts on, keyed by its dotted path.
+func yamlLines(src []byte, lines map[string]int) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return err
+	}
+
+	var walk func(path string, node *yaml.Node)
+	walk = func(path string, node *yaml.Node) {
+		if _, ok := lines[path]; !ok {
+			lines[path] = node.Line
+		}
+
+		switch node.Kind {
+		case yaml.DocumentNode:
+			for _, n := range node.Content {
+				walk(path, n)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				child := joinPath(path, node.Content[i].Value)
+				lines[child] = node.Content[i].Line
+				walk(child, node.Content[i+1])
+			}
+		case yaml.SequenceNode:
+			for i, n := range node.Content {
+				walk(joinPath(path, strconv.Itoa(i)), n)
+			}
+		case yaml.AliasNode:
+			if node.Alias != nil {
+				walk(path, node.Alias)
+			}
+		}
+	}
+
+	walk("", &doc)
+	return nil
+}
+
+func lineAt(src []byte, offset int64) int {
+	if offset > int64(len(src)) {
+		offset = int64(len(src))
+	}
+	return bytes.Count(src[:offset], []byte("\n")) + 1
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	if key == "" {
+		return path
+	}
+	return path + "." + key
+}
+
+func parentPath(path string) string {
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
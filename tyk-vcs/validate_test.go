@@ -0,0 +1,81 @@
+package tyk_vcs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFSGetter_Validate(t *testing.T) {
+	g := writeRepo(t, map[string]string{
+		".tyk.json": `{
+			"type": "apidef",
+			"files": [{"file": "good.json"}, {"file": "bad.json"}, {"file": "broken.json"}, {"file": "petstore.json", "type": "oas"}],
+			"policies": [{"file": "policy.yaml"}]
+		}`,
+		"good.json": `{
+			"api_definition": {
+				"name": "Good",
+				"api_id": "good",
+				"org_id": "org",
+				"use_keyless": true,
+				"proxy": {"listen_path": "/good/", "target_url": "http://upstream"},
+				"version_data": {"not_versioned": true, "versions": {"Default": {"name": "Default"}}}
+			}
+		}`,
+		"bad.json": `{
+	"name": 5,
+	"api_id": "bad",
+	"use_keyless": true,
+	"proxy": {
+		"listen_path": "/bad/"
+	},
+	"version_data": {"not_versioned": true, "versions": {}},
+	"typo": true
+}`,
+		"broken.json":   "{\n  \"name\": \"broken\",\n  \"api_id\": \n}",
+		"petstore.json": `{"swagger": "2.0", "info": {"version": "1"}}`,
+		"policy.yaml": `
+name: Gold
+org_id: org
+quota_max: -5
+access_rights:
+  one:
+    api_id: two
+    versions: []
+`,
+	})
+
+	ts, err := g.FetchTykSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs, err := g.Validate(ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	broken := false
+	for _, e := range errs {
+		if e.File == "broken.json" {
+			// The wording of syntax errors depends on the Go version
+			assert.Equal(t, 4, e.Line)
+			broken = true
+			continue
+		}
+		got = append(got, e.Error())
+	}
+	assert.True(t, broken, "syntax error not reported")
+	assert.Equal(t, []string{
+		"bad.json:2: name: Invalid type. Expected: string, given: integer",
+		"bad.json:5: proxy: target_url is required",
+		"bad.json:9: typo: Additional property typo is not allowed",
+		"petstore.json:1: info.title: info.title is required, it is used as the API name",
+		"petstore.json:1: paths: paths is required",
+		"policy.yaml:4: quota_max: quota_max must be -1 for unlimited, or at least 0",
+		"policy.yaml:7: access_rights.one.api_id: api_id two doesn't match the key it is listed under",
+		"policy.yaml:8: access_rights.one.versions: at least one version is required",
+	}, got)
+}
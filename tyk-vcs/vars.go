@@ -0,0 +1,87 @@
+package tyk_vcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// placeholder matches ${NAME} references, and $${NAME} which is an escaped, literal ${NAME}.
+var placeholder = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// lookupVar resolves a variable. The environment's variables override the spec's, and environment
+// variables of the process override both.
+func (ts *TykSourceSpec) lookupVar(name string) (string, bool) {
+	if val, ok := os.LookupEnv(name); ok {
+		return val, true
+	}
+	if val, ok := ts.EnvironmentVars[name]; ok {
+		return val, true
+	}
+	// Names from the config file are lower cased when it is loaded
+	if val, ok := ts.EnvironmentVars[strings.ToLower(name)]; ok {
+		return val, true
+	}
+	val, ok := ts.Vars[name]
+	return val, ok
+}
+
+// interpolate replaces ${NAME} placeholders in the string values of a file's JSON contents. It
+// fails, listing every missing name, if any placeholder can't be resolved.
+func (ts *TykSourceSpec) interpolate(file string, raw []byte) ([]byte, error) {
+	if !bytes.Contains(raw, []byte("${")) {
+		return raw, nil
+	}
+
+	doc, err := decodeJSON(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := map[string]bool{}
+	doc = ts.interpolateValue(doc, missing)
+
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("%v: unresolved variables: %v", file, strings.Join(names, ", "))
+	}
+
+	return json.Marshal(doc)
+}
+
+func (ts *TykSourceSpec) interpolateValue(v interface{}, missing map[string]bool) interface{} {
+	switch v := v.(type) {
+	case string:
+		return placeholder.ReplaceAllStringFunc(v, func(match string) string {
+			if strings.HasPrefix(match, "$$") {
+				return match[1:]
+			}
+			name := placeholder.FindStringSubmatch(match)[1]
+			val, ok := ts.lookupVar(name)
+			if !ok {
+				missing[name] = true
+			}
+			return val
+		})
+	case map[string]interface{}:
+		for k, val := range v {
+			v[k] = ts.interpolateValue(val, missing)
+		}
+		return v
+	case []interface{}:
+		for i, val := range v {
+			v[i] = ts.interpolateValue(val, missing)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,59 @@
+package tyk_vcs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTykSourceSpec_Interpolate(t *testing.T) {
+	ts := &TykSourceSpec{
+		Vars: map[string]string{
+			"UPSTREAM":   "http://spec",
+			"AUTH_TOKEN": "from-spec",
+			"DOMAIN":     "spec.example.com",
+		},
+		EnvironmentVars: map[string]string{
+			"upstream":   "http://env",
+			"AUTH_TOKEN": "from-env",
+		},
+	}
+
+	os.Setenv("TYKOPS_TEST_AUTH_TOKEN", `se"cret`)
+	defer os.Unsetenv("TYKOPS_TEST_AUTH_TOKEN")
+
+	t.Run("resolves from all sources", func(t *testing.T) {
+		got, err := ts.interpolate("api.json", []byte(`{
+			"target": "${UPSTREAM}/v1",
+			"domain": "${DOMAIN}",
+			"headers": ["Bearer ${TYKOPS_TEST_AUTH_TOKEN}", "${AUTH_TOKEN}"],
+			"literal": "$${UPSTREAM}",
+			"rate": 10
+		}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.JSONEq(t, `{
+			"target": "http://env/v1",
+			"domain": "spec.example.com",
+			"headers": ["Bearer se\"cret", "from-env"],
+			"literal": "${UPSTREAM}",
+			"rate": 10
+		}`, string(got))
+	})
+
+	t.Run("fails on unresolved variables", func(t *testing.T) {
+		_, err := ts.interpolate("api.json", []byte(`{"a": "${MISSING_B}", "b": ["${MISSING_A}"]}`))
+		if assert.Error(t, err) {
+			assert.Equal(t, "api.json: unresolved variables: MISSING_A, MISSING_B", err.Error())
+		}
+	})
+
+	t.Run("files without placeholders are unchanged", func(t *testing.T) {
+		raw := []byte(`{"a": "$b"}`)
+		got, err := ts.interpolate("api.json", raw)
+		assert.NoError(t, err)
+		assert.Equal(t, raw, got)
+	})
+}
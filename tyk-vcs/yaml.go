@@ -0,0 +1,67 @@
+package tyk_vcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// specFileNames are the names a spec file may have, JSON and YAML specs are read the same way.
+var specFileNames = []string{".tyk.json", ".tyk.yaml", ".tyk.yml"}
+
+// isYAML reports whether a file should be read as YAML, based on its extension.
+func isYAML(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// toJSON converts the contents of a YAML file to JSON, so it can be decoded into the same structures
+// as JSON files. The contents of any other file are returned unchanged.
+func toJSON(file string, raw []byte) ([]byte, error) {
+	if !isYAML(file) {
+		return raw, nil
+	}
+
+	var doc interface{}
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("%v: %v", file, err)
+	}
+
+	data, err := json.Marshal(jsonCompatible(doc))
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", file, err)
+	}
+
+	return data, nil
+}
+
+// jsonCompatible converts YAML maps with non-string keys, such as OAS response codes, into maps
+// that can be marshalled to JSON.
+func jsonCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			v[k] = jsonCompatible(val)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = jsonCompatible(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range v {
+			v[i] = jsonCompatible(val)
+		}
+		return v
+	default:
+		return v
+	}
+}